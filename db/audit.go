@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// columns the repository maintains when the model has them
+const (
+	createdByColumn = "created_by"
+	createdAtColumn = "created_at"
+	updatedByColumn = "updated_by"
+	versionColumn   = "version"
+	deletedAtColumn = "deleted_at"
+)
+
+// ErrStaleEntity is returned by Update when the row was changed or deleted since the entity was read
+var ErrStaleEntity = errors.New("db: stale entity")
+
+type actorKey struct{}
+
+// ContextWithActor returns a context carrying the actor stored in created_by and updated_by, such as a user id
+func ContextWithActor(ctx context.Context, actor any) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx
+func ActorFromContext(ctx context.Context) (any, bool) {
+	actor := ctx.Value(actorKey{})
+	return actor, actor != nil
+}
+
+// field returns the schema field of a column, nil when the model has no such column
+func (r *GormRepository[M, E]) field(column string) *schema.Field {
+	sc, err := r.getSchema()
+	if err != nil {
+		return nil
+	}
+	return sc.FieldsByDBName[column]
+}
+
+// stamp sets the audit and version fields of a model before it is written
+// a new model starts at version 1, an updated one has its version incremented and the previous one returned
+func (r *GormRepository[M, E]) stamp(ctx context.Context, model *M, creating bool) (version int64, err error) {
+	value := reflect.ValueOf(model)
+	if actor, ok := ActorFromContext(ctx); ok {
+		if field := r.field(createdByColumn); field != nil && creating {
+			if err := field.Set(ctx, value, actor); err != nil {
+				return 0, fmt.Errorf("db: set %s: %w", createdByColumn, err)
+			}
+		}
+		if field := r.field(updatedByColumn); field != nil {
+			if err := field.Set(ctx, value, actor); err != nil {
+				return 0, fmt.Errorf("db: set %s: %w", updatedByColumn, err)
+			}
+		}
+	}
+	field := r.field(versionColumn)
+	if field == nil {
+		return 0, nil
+	}
+	current, _ := field.ValueOf(ctx, value)
+	switch v := reflect.ValueOf(current); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		version = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		version = int64(v.Uint())
+	default:
+		return 0, fmt.Errorf("db: %s must be an integer, got %T", versionColumn, current)
+	}
+	next := version + 1
+	if creating && version > 0 {
+		next = version
+	}
+	return version, field.Set(ctx, value, next)
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultBatchSize = 100
+
+// OnConflict tells UpsertBatch what to do with a row conflicting with an existing one
+type OnConflict struct {
+	// Columns are the fields of the unique constraint, the primary key when empty
+	Columns []string
+	// Update are the fields overwritten with the new row, every field when empty
+	// created_by, created_at and version are never overwritten, version is incremented instead
+	Update []string
+	// DoNothing keeps the existing row
+	DoNothing bool
+}
+
+// InsertBatch inserts the entities in chunks of batchSize rows in a single transaction, firing the insert events of each entity
+// The entities are updated with the values set by the database such as their primary key
+func (r *GormRepository[M, E]) InsertBatch(ctx context.Context, entities []E, batchSize int) error {
+	return r.writeBatch(ctx, entities, batchSize, nil)
+}
+
+// UpsertBatch inserts the entities in chunks of batchSize rows in a single transaction, resolving conflicts with onConflict
+// The entities keep the version they were inserted with, rows updated on conflict have to be read again for theirs
+func (r *GormRepository[M, E]) UpsertBatch(ctx context.Context, entities []E, batchSize int, onConflict OnConflict) error {
+	sc, err := r.getSchema()
+	if err != nil {
+		return err
+	}
+	b := &builder{schema: sc}
+	conflict := clause.OnConflict{DoNothing: onConflict.DoNothing}
+	for _, field := range onConflict.Columns {
+		column, err := b.dbName(field)
+		if err != nil {
+			return err
+		}
+		conflict.Columns = append(conflict.Columns, clause.Column{Name: column})
+	}
+	if onConflict.DoNothing {
+		return r.writeBatch(ctx, entities, batchSize, conflict)
+	}
+	var columns []string
+	for _, field := range onConflict.Update {
+		column, err := b.dbName(field)
+		if err != nil {
+			return err
+		}
+		columns = append(columns, column)
+	}
+	if len(onConflict.Update) == 0 {
+		// the fields gorm updates with UpdateAll, which would also overwrite the columns kept below
+		for _, field := range sc.Fields {
+			if field.DBName != "" && field.Creatable && !field.PrimaryKey && field.AutoCreateTime == 0 &&
+				(!field.HasDefaultValue || field.DefaultValueInterface != nil) {
+				columns = append(columns, field.DBName)
+			}
+		}
+	}
+	for _, column := range columns {
+		switch column {
+		case createdByColumn, createdAtColumn, versionColumn:
+		default:
+			conflict.DoUpdates = append(conflict.DoUpdates, clause.AssignmentColumns([]string{column})...)
+		}
+	}
+	if r.field(versionColumn) != nil {
+		current := clause.Column{Table: clause.CurrentTable, Name: versionColumn}
+		conflict.DoUpdates = append(conflict.DoUpdates, clause.Assignment{
+			Column: clause.Column{Name: versionColumn},
+			Value:  clause.Expr{SQL: "? + 1", Vars: []any{current}},
+		})
+	}
+	// a model with nothing left to update keeps its existing rows
+	conflict.DoNothing = len(conflict.DoUpdates) == 0
+	return r.writeBatch(ctx, entities, batchSize, conflict)
+}
+
+func (r *GormRepository[M, E]) writeBatch(ctx context.Context, entities []E, batchSize int, conflict clause.Expression) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	inserting := conflict == nil
+	if inserting {
+		for i := range entities {
+			if err := r.publish(ctx, Event[E]{Type: BeforeInsert, New: &entities[i]}); err != nil {
+				return err
+			}
+		}
+	}
+	var start M
+	models := make([]M, 0, len(entities))
+	for _, entity := range entities {
+		model := start.FromEntity(entity).(M)
+		if _, err := r.stamp(ctx, &model, true); err != nil {
+			return err
+		}
+		models = append(models, model)
+	}
+	err := r.WithTx(ctx, func(ctx context.Context, _ *GormRepository[M, E]) error {
+		tx := r.conn(ctx)
+		if conflict != nil {
+			tx = tx.Clauses(conflict)
+		}
+		for _, chunk := range ChunkSlice(models, batchSize) {
+			if err := tx.Create(&chunk).Error; err != nil {
+				return err
+			}
+		}
+		if !inserting {
+			return nil
+		}
+		events := make([]Event[E], 0, len(models))
+		for _, model := range models {
+			inserted := model.ToEntity()
+			events = append(events, Event[E]{Type: AfterInsert, New: &inserted})
+		}
+		return r.emit(ctx, events...)
+	})
+	if err != nil {
+		return err
+	}
+	for i, model := range models {
+		entities[i] = model.ToEntity()
+	}
+	return nil
+}
+
+// DeleteWhere deletes the rows matching the specifications and returns their number
+// At least one condition is required, OrderBy, Select and Preload specifications are ignored
+// When AfterDelete events are listened to the matching rows are loaded and deleted by primary key
+func (r *GormRepository[M, E]) DeleteWhere(ctx context.Context, specifications ...Specification) (deleted int64, err error) {
+	conditions := make([]Specification, 0, len(specifications))
+	for _, s := range specifications {
+		if _, ok := s.(queryOption); !ok {
+			conditions = append(conditions, s)
+		}
+	}
+	if len(conditions) == 0 {
+		return 0, gorm.ErrMissingWhereClause
+	}
+	if !r.listening(AfterDelete) {
+		result := r.applySpecifications(r.conn(ctx), conditions...).Delete(new(M))
+		return result.RowsAffected, result.Error
+	}
+	err = r.WithTx(ctx, func(ctx context.Context, _ *GormRepository[M, E]) error {
+		var models []M
+		if err := r.applySpecifications(r.conn(ctx), conditions...).Find(&models).Error; err != nil {
+			return err
+		}
+		if len(models) == 0 {
+			return nil
+		}
+		result := r.conn(ctx).Delete(&models)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		events := make([]Event[E], 0, len(models))
+		for _, model := range models {
+			entity := model.ToEntity()
+			events = append(events, Event[E]{Type: AfterDelete, Old: &entity})
+		}
+		return r.emit(ctx, events...)
+	})
+	return deleted, err
+}
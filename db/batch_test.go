@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUpsertBatchKeepsAuditColumns(t *testing.T) {
+	repo := newTestRepository(t)
+	item := testItem{Code: "a", Name: "first"}
+	if err := repo.Insert(ContextWithActor(context.Background(), "alice"), &item); err != nil {
+		t.Fatal(err)
+	}
+	item.Name = "second"
+	if err := repo.Update(ContextWithActor(context.Background(), "bob"), &item); err != nil {
+		t.Fatal(err)
+	}
+	created, err := repo.FindByID(context.Background(), item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		onConflict OnConflict
+		want       string
+	}{
+		{"every field", OnConflict{Columns: []string{"Code"}}, "upserted"},
+		{"listed fields", OnConflict{Columns: []string{"code"}, Update: []string{"Name", "version", "created_by"}}, "upserted"},
+	}
+	for i, test := range tests {
+		upserted := []testItem{{Code: "a", Name: test.want}}
+		if err := repo.UpsertBatch(ContextWithActor(context.Background(), "carol"), upserted, 0, test.onConflict); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		got, err := repo.FindByID(context.Background(), item.ID)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got.Name != test.want || got.UpdatedBy != "carol" {
+			t.Errorf("%s: name %q updated by %q, want %q updated by carol", test.name, got.Name, got.UpdatedBy, test.want)
+		}
+		if want := int64(3 + i); got.Version != want {
+			t.Errorf("%s: version %d, want %d", test.name, got.Version, want)
+		}
+		if got.CreatedBy != "alice" || !got.CreatedAt.Equal(created.CreatedAt) {
+			t.Errorf("%s: created by %q at %v, want alice at %v", test.name, got.CreatedBy, got.CreatedAt, created.CreatedAt)
+		}
+	}
+}
+
+func TestUpsertBatchDoNothing(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	items := []testItem{{Code: "a", Name: "first"}}
+	if err := repo.InsertBatch(ctx, items, 0); err != nil {
+		t.Fatal(err)
+	}
+	upserted := []testItem{{Code: "a", Name: "second"}, {Code: "b", Name: "new"}}
+	if err := repo.UpsertBatch(ctx, upserted, 1, OnConflict{Columns: []string{"code"}, DoNothing: true}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.Find(ctx, OrderBy("code", Ascending))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != "first" || got[0].Version != 1 || got[1].Name != "new" {
+		t.Fatalf("got %+v, want a kept as first and b inserted", got)
+	}
+}
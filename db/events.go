@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EventType string
+
+const (
+	// BeforeInsert handlers run before the row is written, they can change the new entity and abort the insert with an error
+	BeforeInsert EventType = "before_insert"
+	AfterInsert  EventType = "after_insert"
+	AfterUpdate  EventType = "after_update"
+	AfterDelete  EventType = "after_delete"
+)
+
+// Event is a change of an entity, Old is nil for an insert and New is nil for a delete
+type Event[E any] struct {
+	Type EventType `json:"type"`
+	Old  *E        `json:"old,omitempty"`
+	New  *E        `json:"new,omitempty"`
+}
+
+type EventHandler[E any] func(ctx context.Context, event Event[E]) error
+
+type subscription[E any] struct {
+	handler EventHandler[E]
+}
+
+// hooks are the event handlers of a repository
+type hooks[E any] struct {
+	mu          sync.RWMutex
+	subscribers map[EventType][]*subscription[E]
+	outbox      bool
+}
+
+// Subscribe registers a handler for the events of a type and returns a function removing it
+// After events are published once the write is committed, in the order of the writes
+// Insert, Update, Delete, DeleteByID, DeleteWhere and InsertBatch fire events, UpsertBatch and Restore do not
+func (r *GormRepository[M, E]) Subscribe(eventType EventType, handler EventHandler[E]) (unsubscribe func()) {
+	s := &subscription[E]{handler: handler}
+	r.hooks.mu.Lock()
+	defer r.hooks.mu.Unlock()
+	if r.hooks.subscribers == nil {
+		r.hooks.subscribers = make(map[EventType][]*subscription[E])
+	}
+	r.hooks.subscribers[eventType] = append(r.hooks.subscribers[eventType], s)
+	return func() {
+		r.hooks.mu.Lock()
+		defer r.hooks.mu.Unlock()
+		subscribers := r.hooks.subscribers[eventType]
+		for i, sub := range subscribers {
+			if sub == s {
+				r.hooks.subscribers[eventType] = append(subscribers[:i:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// EnableOutbox stores the After events in the OutboxEvent table in the transaction of the write instead of publishing them,
+// DeliverOutbox publishes them so no event is lost when the process stops after a commit
+func (r *GormRepository[M, E]) EnableOutbox() *GormRepository[M, E] {
+	r.hooks.mu.Lock()
+	r.hooks.outbox = true
+	r.hooks.mu.Unlock()
+	return r
+}
+
+func (r *GormRepository[M, E]) DisableOutbox() *GormRepository[M, E] {
+	r.hooks.mu.Lock()
+	r.hooks.outbox = false
+	r.hooks.mu.Unlock()
+	return r
+}
+
+func (r *GormRepository[M, E]) outboxEnabled() bool {
+	r.hooks.mu.RLock()
+	defer r.hooks.mu.RUnlock()
+	return r.hooks.outbox
+}
+
+// listening tells whether events of the type are stored or published, so the writes only load the old entities when needed
+func (r *GormRepository[M, E]) listening(eventType EventType) bool {
+	r.hooks.mu.RLock()
+	defer r.hooks.mu.RUnlock()
+	return r.hooks.outbox || len(r.hooks.subscribers[eventType]) > 0
+}
+
+// publish runs the handlers of the event, an error of a Before handler stops the others
+func (r *GormRepository[M, E]) publish(ctx context.Context, event Event[E]) error {
+	r.hooks.mu.RLock()
+	subscribers := r.hooks.subscribers[event.Type]
+	r.hooks.mu.RUnlock()
+	var errs []error
+	for _, s := range subscribers {
+		if err := s.handler(ctx, event); err != nil {
+			if event.Type == BeforeInsert {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// emit stores the After events in the outbox, defers them to the commit of the transaction of ctx or publishes them
+// it is called in the transaction of the write when the outbox is enabled, an error then rolls the write back
+func (r *GormRepository[M, E]) emit(ctx context.Context, events ...Event[E]) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if r.outboxEnabled() {
+		sc, err := r.getSchema()
+		if err != nil {
+			return err
+		}
+		rows := make([]OutboxEvent, 0, len(events))
+		for _, event := range events {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, OutboxEvent{Entity: sc.Table, Type: event.Type, Payload: string(payload)})
+		}
+		return r.conn(ctx).Create(&rows).Error
+	}
+	if state := txFromContext(ctx); state != nil && state.managed {
+		for _, event := range events {
+			event := event
+			state.afterCommit(func(ctx context.Context) error {
+				return r.publish(ctx, event)
+			})
+		}
+		return nil
+	}
+	var errs []error
+	for _, event := range events {
+		errs = append(errs, r.publish(ctx, event))
+	}
+	return errors.Join(errs...)
+}
+
+// writeTx runs a write in a transaction when the outbox is enabled so its events are stored with it
+func (r *GormRepository[M, E]) writeTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := TxFromContext(ctx); ok || !r.outboxEnabled() {
+		return fn(ctx)
+	}
+	return r.WithTx(ctx, func(ctx context.Context, _ *GormRepository[M, E]) error {
+		return fn(ctx)
+	})
+}
+
+// OutboxEvent is an event stored by a repository with the outbox enabled, the table is created with db.AutoMigrate(&OutboxEvent{})
+type OutboxEvent struct {
+	ID          uint64    `gorm:"primaryKey"`
+	Entity      string    `gorm:"size:191;index:idx_outbox_pending,priority:1"`
+	Type        EventType `gorm:"size:32"`
+	Payload     string    `gorm:"type:text"`
+	CreatedAt   time.Time
+	DeliveredAt *time.Time `gorm:"index:idx_outbox_pending,priority:2"`
+	Attempts    int
+	LastError   string `gorm:"type:text"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// DeliverOutbox publishes up to limit stored events of the repository entity in the order they were written and marks them delivered
+// It stops at the first failing handler, whose error is recorded on the event and returned, so the event is delivered again by the next call
+// Events are delivered at least once, concurrent calls skip the events locked by each other on postgres and mysql
+func (r *GormRepository[M, E]) DeliverOutbox(ctx context.Context, limit int) (delivered int, err error) {
+	sc, err := r.getSchema()
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 {
+		limit = defaultBatchSize
+	}
+	var handlerErr error
+	err = r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Where(clause.Eq{Column: "entity", Value: sc.Table}).
+			Where(clause.Eq{Column: "delivered_at", Value: nil}).
+			Order("id").Limit(limit)
+		switch tx.Dialector.Name() {
+		case "postgres", "mysql":
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		var rows []OutboxEvent
+		if err := query.Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			var event Event[E]
+			handlerErr = json.Unmarshal([]byte(row.Payload), &event)
+			if handlerErr == nil {
+				handlerErr = r.publish(ctx, event)
+			}
+			if handlerErr != nil {
+				return tx.Model(&row).Updates(map[string]any{"attempts": row.Attempts + 1, "last_error": handlerErr.Error()}).Error
+			}
+			if err := tx.Model(&row).Updates(map[string]any{"attempts": row.Attempts + 1, "delivered_at": time.Now()}).Error; err != nil {
+				return err
+			}
+			delivered++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return delivered, handlerErr
+}
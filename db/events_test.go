@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// eventRecorder collects the events a handler receives
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event[testItem]
+}
+
+func (r *eventRecorder) handle(_ context.Context, event Event[testItem]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// summary returns the type and the codes of the old and new entity of every recorded event
+func (r *eventRecorder) summary() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var summary []string
+	for _, event := range r.events {
+		s := string(event.Type)
+		if event.Old != nil {
+			s += " old=" + event.Old.Code
+		}
+		if event.New != nil {
+			s += " new=" + event.New.Code
+		}
+		summary = append(summary, s)
+	}
+	return summary
+}
+
+func subscribeAll(repo *GormRepository[testItem, testItem], recorder *eventRecorder) {
+	for _, eventType := range []EventType{AfterInsert, AfterUpdate, AfterDelete} {
+		repo.Subscribe(eventType, recorder.handle)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	recorder := &eventRecorder{}
+	subscribeAll(repo, recorder)
+	repo.Subscribe(BeforeInsert, func(_ context.Context, event Event[testItem]) error {
+		if event.New.Code == "rejected" {
+			return errors.New("rejected")
+		}
+		event.New.Name = "named by the handler"
+		return nil
+	})
+
+	item := testItem{Code: "a"}
+	if err := repo.Insert(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "named by the handler" {
+		t.Errorf("BeforeInsert handler did not change the entity: %+v", item)
+	}
+	item.Code = "b"
+	if err := repo.Update(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteByID(ctx, item.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Insert(ctx, &testItem{Code: "rejected"}); err == nil {
+		t.Error("Insert succeeded although a BeforeInsert handler failed")
+	}
+	want := []string{"after_insert new=a", "after_update old=a new=b", "after_delete old=b"}
+	if got := recorder.summary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if count, _ := repo.Count(ctx); count != 0 {
+		t.Errorf("counted %d rows, want the rejected insert not to be written", count)
+	}
+}
+
+func TestEventsUnsubscribe(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	recorder := &eventRecorder{}
+	unsubscribe := repo.Subscribe(AfterInsert, recorder.handle)
+	if err := repo.Insert(ctx, &testItem{Code: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	unsubscribe()
+	if err := repo.Insert(ctx, &testItem{Code: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := recorder.summary(); !reflect.DeepEqual(got, []string{"after_insert new=a"}) {
+		t.Errorf("events = %v, want only the insert before unsubscribing", got)
+	}
+}
+
+func TestEventsAfterCommit(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	recorder := &eventRecorder{}
+	subscribeAll(repo, recorder)
+
+	err := repo.WithTx(ctx, func(ctx context.Context, repo *GormRepository[testItem, testItem]) error {
+		if err := repo.Insert(ctx, &testItem{Code: "a"}); err != nil {
+			return err
+		}
+		// the savepoint is rolled back so its events are dropped while the outer ones are kept
+		_ = repo.WithTx(ctx, func(ctx context.Context, repo *GormRepository[testItem, testItem]) error {
+			if err := repo.Insert(ctx, &testItem{Code: "nested"}); err != nil {
+				return err
+			}
+			return errors.New("rolled back")
+		})
+		if err := repo.Insert(ctx, &testItem{Code: "b"}); err != nil {
+			return err
+		}
+		if got := recorder.summary(); len(got) != 0 {
+			t.Errorf("events %v were published before the commit", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := recorder.summary(), []string{"after_insert new=a", "after_insert new=b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events after commit = %v, want %v", got, want)
+	}
+
+	recorder.events = nil
+	failed := errors.New("failed")
+	err = repo.WithTx(ctx, func(ctx context.Context, repo *GormRepository[testItem, testItem]) error {
+		if err := repo.Insert(ctx, &testItem{Code: "c"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithTx error = %v, want %v", err, failed)
+	}
+	if got := recorder.summary(); len(got) != 0 {
+		t.Errorf("events %v were published for a rolled back transaction", got)
+	}
+
+	// handler errors are returned once the transaction is committed
+	handlerErr := errors.New("handler failed")
+	repo.Subscribe(AfterInsert, func(context.Context, Event[testItem]) error { return handlerErr })
+	err = repo.WithTx(ctx, func(ctx context.Context, repo *GormRepository[testItem, testItem]) error {
+		return repo.Insert(ctx, &testItem{Code: "d"})
+	})
+	if !errors.Is(err, handlerErr) {
+		t.Errorf("WithTx error = %v, want the handler error", err)
+	}
+	if count, err := repo.Count(ctx, Equal("code", "d")); err != nil || count != 1 {
+		t.Errorf("counted %d committed rows (%v), want 1", count, err)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	if err := repo.db.AutoMigrate(&OutboxEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := &eventRecorder{}
+	subscribeAll(repo, recorder)
+	repo.EnableOutbox()
+
+	item := testItem{Code: "a"}
+	if err := repo.Insert(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	item.Code = "b"
+	if err := repo.Update(ctx, &item); err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("failed")
+	err := repo.WithTx(ctx, func(ctx context.Context, repo *GormRepository[testItem, testItem]) error {
+		if err := repo.Insert(ctx, &testItem{Code: "rolled back"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithTx error = %v, want %v", err, failed)
+	}
+	if got := recorder.summary(); len(got) != 0 {
+		t.Fatalf("events %v were published instead of stored", got)
+	}
+	var stored int64
+	if err := repo.db.Model(&OutboxEvent{}).Count(&stored).Error; err != nil || stored != 2 {
+		t.Fatalf("stored %d events (%v), want 2 without the rolled back insert", stored, err)
+	}
+
+	// a failing handler stops the delivery, records the error and the event is delivered again
+	handlerErr := errors.New("handler failed")
+	fail := true
+	repo.Subscribe(AfterUpdate, func(context.Context, Event[testItem]) error {
+		if fail {
+			return handlerErr
+		}
+		return nil
+	})
+	delivered, err := repo.DeliverOutbox(ctx, 10)
+	if !errors.Is(err, handlerErr) || delivered != 1 {
+		t.Fatalf("DeliverOutbox = %d, %v, want 1 and the handler error", delivered, err)
+	}
+	var pending OutboxEvent
+	if err := repo.db.Where("delivered_at IS NULL").First(&pending).Error; err != nil {
+		t.Fatal(err)
+	}
+	if pending.Type != AfterUpdate || pending.Attempts != 1 || pending.LastError != handlerErr.Error() {
+		t.Errorf("pending event = %+v, want the update with one failed attempt", pending)
+	}
+
+	fail = false
+	if delivered, err := repo.DeliverOutbox(ctx, 10); err != nil || delivered != 1 {
+		t.Fatalf("DeliverOutbox = %d, %v, want 1", delivered, err)
+	}
+	if delivered, err := repo.DeliverOutbox(ctx, 10); err != nil || delivered != 0 {
+		t.Fatalf("DeliverOutbox = %d, %v, want nothing left to deliver", delivered, err)
+	}
+	want := []string{"after_insert new=a", "after_update old=a new=b", "after_update old=a new=b"}
+	if got := recorder.summary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered events = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,310 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidFilter   = errors.New("db: invalid filter")
+	ErrFieldNotAllowed = errors.New("db: field not allowed")
+	// ErrNotSerializable is returned by NewFilter for specifications with no filter form such as Exists or OrderBy
+	ErrNotSerializable = errors.New("db: specification is not serializable")
+)
+
+const maxFilterDepth = 32
+
+// Filter is a json filter expression such as {"and":[{"field":"age","op":"gt","value":18}]}
+// A node is either a condition with field, op and value or one of and, or and not
+// The operators are the ones of rule.Condition and "operator" is read as "op", so rules and queries share the format:
+// eq, neq, gt, gte, lt, lte, between, in, not_in, contains, not_contains, starts_with, ends_with, like, ilike, is_null and is_not_null
+type Filter struct {
+	And   []Filter `json:"and,omitempty"`
+	Or    []Filter `json:"or,omitempty"`
+	Not   *Filter  `json:"not,omitempty"`
+	Field string   `json:"field,omitempty"`
+	Op    string   `json:"op,omitempty"`
+	Value any      `json:"value,omitempty"`
+}
+
+// UnmarshalJSON reads integral numbers as int64 and other numbers as float64, unknown keys are rejected so a typo does not drop a condition
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	type filter Filter
+	var node struct {
+		filter
+		Operator string `json:"operator"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&node); err != nil {
+		return err
+	}
+	*f = Filter(node.filter)
+	if f.Op == "" {
+		f.Op = node.Operator
+	}
+	f.Value = filterValue(f.Value)
+	return nil
+}
+
+// MarshalJSON keeps an empty or, which matches no row, instead of omitting it and matching every row
+func (f Filter) MarshalJSON() ([]byte, error) {
+	type filter Filter
+	if f.Or != nil && len(f.Or) == 0 {
+		return []byte(`{"or":[]}`), nil
+	}
+	return json.Marshal(filter(f))
+}
+
+func filterValue(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case []any:
+		for i := range value {
+			value[i] = filterValue(value[i])
+		}
+	}
+	return value
+}
+
+// ParseFilter returns the specification of a json filter, fields outside allowed are rejected
+func ParseFilter(data []byte, allowed ...string) (Specification, error) {
+	var f Filter
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return f.Specification(allowed...)
+}
+
+// Specification returns the specification of the filter, fields outside allowed are rejected
+// An empty filter or and matches every row, an empty or matches none
+func (f Filter) Specification(allowed ...string) (Specification, error) {
+	fields := make(map[string]bool, len(allowed))
+	for _, field := range allowed {
+		fields[field] = true
+	}
+	return f.specification(fields, 0)
+}
+
+func (f Filter) specification(fields map[string]bool, depth int) (Specification, error) {
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf("%w: nested deeper than %d", ErrInvalidFilter, maxFilterDepth)
+	}
+	kinds := 0
+	for _, set := range []bool{f.And != nil, f.Or != nil, f.Not != nil, f.Field != "" || f.Op != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return nil, fmt.Errorf("%w: a node has one of and, or, not and field", ErrInvalidFilter)
+	}
+	switch {
+	case f.Not != nil:
+		spec, err := f.Not.specification(fields, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return Not(spec), nil
+	case f.Or != nil:
+		specs, err := filterSpecifications(f.Or, fields, depth)
+		if err != nil {
+			return nil, err
+		}
+		return Or(specs...), nil
+	case f.Field == "" && f.Op == "":
+		specs, err := filterSpecifications(f.And, fields, depth)
+		if err != nil {
+			return nil, err
+		}
+		return And(specs...), nil
+	}
+	if !fields[f.Field] {
+		return nil, fmt.Errorf("%w %q", ErrFieldNotAllowed, f.Field)
+	}
+	return f.condition()
+}
+
+func filterSpecifications(filters []Filter, fields map[string]bool, depth int) ([]Specification, error) {
+	specs := make([]Specification, 0, len(filters))
+	for _, filter := range filters {
+		spec, err := filter.specification(fields, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// condition returns the specification of a field condition after checking its value suits the operator
+func (f Filter) condition() (Specification, error) {
+	invalid := func(want string) error {
+		return fmt.Errorf("%w: %s on %q takes %s", ErrInvalidFilter, f.Op, f.Field, want)
+	}
+	switch f.Op {
+	case "is_null", "is_not_null":
+		if f.Value != nil {
+			return nil, invalid("no value")
+		}
+		if f.Op == "is_null" {
+			return IsNull(f.Field), nil
+		}
+		return IsNotNull(f.Field), nil
+	case "between", "in", "not_in":
+		values, ok := f.Value.([]any)
+		if !ok || !scalars(values...) || f.Op == "between" && len(values) != 2 {
+			return nil, invalid("a list of values")
+		}
+		switch f.Op {
+		case "between":
+			return Between(f.Field, values[0], values[1]), nil
+		case "in":
+			return In(f.Field, values), nil
+		}
+		return NotIn(f.Field, values), nil
+	case "contains", "not_contains", "starts_with", "ends_with", "like", "ilike":
+		text, ok := f.Value.(string)
+		if !ok {
+			return nil, invalid("a string")
+		}
+		switch f.Op {
+		case "contains":
+			return Contains(f.Field, text), nil
+		case "not_contains":
+			return NotContains(f.Field, text), nil
+		case "starts_with":
+			return StartsWith(f.Field, text), nil
+		case "ends_with":
+			return EndsWith(f.Field, text), nil
+		case "like":
+			return Like(f.Field, text), nil
+		}
+		return ILike(f.Field, text), nil
+	}
+	operator, ok := filterComparisons[f.Op]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrInvalidOperator, f.Op)
+	}
+	if f.Value == nil || !scalars(f.Value) {
+		return nil, invalid("a string, number or boolean")
+	}
+	return binaryOperatorSpecification[any]{field: f.Field, operator: operator, value: f.Value}, nil
+}
+
+var filterComparisons = map[string]string{
+	"eq":  "=",
+	"neq": "<>",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+func scalars(values ...any) bool {
+	for _, value := range values {
+		switch value.(type) {
+		case []any, map[string]any:
+			return false
+		}
+	}
+	return true
+}
+
+// filterable is implemented by the specifications having a filter form
+type filterable interface {
+	filter() (Filter, error)
+}
+
+// NewFilter returns the filter form of a specification built with And, Or, Not and the comparison specifications
+func NewFilter(spec Specification) (Filter, error) {
+	if s, ok := spec.(filterable); ok {
+		return s.filter()
+	}
+	return Filter{}, fmt.Errorf("%w: %T", ErrNotSerializable, spec)
+}
+
+// MarshalFilter returns the json filter of a specification
+func MarshalFilter(spec Specification) ([]byte, error) {
+	f, err := NewFilter(spec)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(f)
+}
+
+func (s joinSpecification) filter() (Filter, error) {
+	filters := make([]Filter, 0, len(s.specifications))
+	for _, spec := range s.specifications {
+		f, err := NewFilter(spec)
+		if err != nil {
+			return Filter{}, err
+		}
+		filters = append(filters, f)
+	}
+	if s.separator == "OR" {
+		return Filter{Or: filters}, nil
+	}
+	return Filter{And: filters}, nil
+}
+
+func (s notSpecification) filter() (Filter, error) {
+	f, err := NewFilter(s.Specification)
+	if err != nil {
+		return Filter{}, err
+	}
+	return Filter{Not: &f}, nil
+}
+
+func (s binaryOperatorSpecification[T]) filter() (Filter, error) {
+	switch s.operator {
+	case "IN":
+		return Filter{Field: s.field, Op: "in", Value: s.value}, nil
+	case "NOT IN":
+		return Filter{Field: s.field, Op: "not_in", Value: s.value}, nil
+	case "LIKE":
+		return Filter{Field: s.field, Op: "like", Value: s.value}, nil
+	}
+	for op, operator := range filterComparisons {
+		if operator == s.operator {
+			return Filter{Field: s.field, Op: op, Value: s.value}, nil
+		}
+	}
+	return Filter{}, fmt.Errorf("%w: operator %s", ErrNotSerializable, s.operator)
+}
+
+func (s insensitiveLikeSpecification) filter() (Filter, error) {
+	return Filter{Field: s.field, Op: "ilike", Value: s.pattern}, nil
+}
+
+func (s patternSpecification) filter() (Filter, error) {
+	op := "contains"
+	switch {
+	case s.not:
+		op = "not_contains"
+	case s.kind == prefixed:
+		op = "starts_with"
+	case s.kind == suffixed:
+		op = "ends_with"
+	}
+	return Filter{Field: s.field, Op: op, Value: s.text}, nil
+}
+
+func (s betweenSpecification[T]) filter() (Filter, error) {
+	return Filter{Field: s.field, Op: "between", Value: []any{s.from, s.to}}, nil
+}
+
+func (s nullSpecification) filter() (Filter, error) {
+	if s.not {
+		return Filter{Field: s.field, Op: "is_not_null"}, nil
+	}
+	return Filter{Field: s.field, Op: "is_null"}, nil
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		query  string
+		values []any
+	}{
+		{`{}`, "", []any{}},
+		{`{"field":"age","op":"gt","value":18}`, "age > ?", []any{int64(18)}},
+		{`{"field":"age","operator":"lte","value":1.5}`, "age <= ?", []any{1.5}},
+		{`{"and":[{"field":"age","op":"gte","value":18},{"field":"name","op":"starts_with","value":"a%"}]}`,
+			"(age >= ? AND name LIKE ? ESCAPE '!')", []any{int64(18), "a!%%"}},
+		{`{"or":[{"field":"age","op":"in","value":[1,2]},{"field":"name","op":"is_null"}]}`,
+			"(age IN ? OR name IS NULL)", []any{[]any{int64(1), int64(2)}}},
+		{`{"not":{"field":"age","op":"between","value":[1,9]}}`, " NOT (age BETWEEN ? AND ?)", []any{int64(1), int64(9)}},
+		{`{"or":[]}`, matchNone, []any{}},
+		{`{"not":{"and":[]}}`, matchNone, []any{}},
+		{`{"not":{}}`, matchNone, []any{}},
+		{`{"and":[{"or":[]},{"field":"age","op":"eq","value":1}]}`, "(1 = 0 AND age = ?)", []any{int64(1)}},
+	}
+	for _, test := range tests {
+		spec, err := ParseFilter([]byte(test.filter), "age", "name")
+		if err != nil {
+			t.Errorf("ParseFilter(%s): %v", test.filter, err)
+			continue
+		}
+		query, err := buildQuery(spec, nil)
+		if err != nil {
+			t.Errorf("ParseFilter(%s) query: %v", test.filter, err)
+			continue
+		}
+		if query != test.query || !reflect.DeepEqual(spec.GetValues(), test.values) {
+			t.Errorf("ParseFilter(%s) = %q %#v, want %q %#v", test.filter, query, spec.GetValues(), test.query, test.values)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		filter string
+		err    error
+	}{
+		{`{"field":"secret","op":"eq","value":1}`, ErrFieldNotAllowed},
+		{`{"not":{"field":"secret","op":"is_null"}}`, ErrFieldNotAllowed},
+		{`{"field":"age","op":"matches","value":1}`, ErrInvalidOperator},
+		{`{"field":"age","op":"eq"}`, ErrInvalidFilter},
+		{`{"field":"age","op":"eq","value":[1]}`, ErrInvalidFilter},
+		{`{"field":"age","op":"between","value":[1]}`, ErrInvalidFilter},
+		{`{"field":"age","op":"contains","value":1}`, ErrInvalidFilter},
+		{`{"field":"age","op":"is_null","value":1}`, ErrInvalidFilter},
+		{`{"field":"age","op":"eq","value":1,"and":[]}`, ErrInvalidFilter},
+		{`{"field":"age","op":"eq","valeu":1}`, ErrInvalidFilter},
+		{`[`, ErrInvalidFilter},
+	}
+	for _, test := range tests {
+		if _, err := ParseFilter([]byte(test.filter), "age"); !errors.Is(err, test.err) {
+			t.Errorf("ParseFilter(%s) error = %v, want %v", test.filter, err, test.err)
+		}
+	}
+}
+
+func TestMarshalFilter(t *testing.T) {
+	tests := []struct {
+		spec Specification
+		json string
+	}{
+		{And(Equal("age", 1), Or(IsNull("name"), Contains("name", "a"))),
+			`{"and":[{"field":"age","op":"eq","value":1},{"or":[{"field":"name","op":"is_null"},{"field":"name","op":"contains","value":"a"}]}]}`},
+		{Or(), `{"or":[]}`},
+		{Not(And()), `{"not":{}}`},
+	}
+	for _, test := range tests {
+		data, err := MarshalFilter(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.json {
+			t.Errorf("MarshalFilter = %s, want %s", data, test.json)
+		}
+		spec, err := ParseFilter(data, "age", "name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := buildQuery(test.spec, nil)
+		if got, _ := buildQuery(spec, nil); got != want {
+			t.Errorf("ParseFilter(%s) = %q, want %q", data, got, want)
+		}
+	}
+	if _, err := MarshalFilter(OrderBy("age", Ascending)); !errors.Is(err, ErrNotSerializable) {
+		t.Errorf("MarshalFilter(OrderBy) error = %v, want ErrNotSerializable", err)
+	}
+}
+
+func TestEmptyFilterMatches(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	items := []testItem{{Code: "a"}, {Code: "b"}}
+	if err := repo.InsertBatch(ctx, items, 0); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]int{
+		`{}`:                 2,
+		`{"and":[]}`:         2,
+		`{"or":[]}`:          0,
+		`{"not":{"and":[]}}`: 0,
+		`{"not":{"or":[]}}`:  2,
+	}
+	for filter, want := range tests {
+		spec, err := ParseFilter([]byte(filter))
+		if err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.Find(ctx, spec)
+		if err != nil {
+			t.Fatalf("%s: %v", filter, err)
+		}
+		if len(found) != want {
+			t.Errorf("%s matched %d rows, want %d", filter, len(found), want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const defaultPageSize = 20
+
+var ErrInvalidCursor = errors.New("db: invalid cursor")
+
+// SortKey is a field rows are sorted by
+type SortKey struct {
+	Field     string    `json:"field"`
+	Direction Direction `json:"direction"`
+}
+
+// PageRequest selects a page of rows sorted by Sort
+// The primary key is appended to Sort when missing so the order is total and no row is skipped or repeated
+// Sort fields should not be nullable, rows with a null sort field are not paged correctly
+type PageRequest struct {
+	// Size is the number of rows per page, it defaults to 20
+	Size int       `json:"size"`
+	Sort []SortKey `json:"sort"`
+	// After is the Next cursor of the previous page, Before is the Prev cursor of the following page, only one of them is used
+	After  string `json:"after,omitempty"`
+	Before string `json:"before,omitempty"`
+	// WithTotal counts the rows matching the specifications, which costs a query
+	WithTotal bool `json:"with_total,omitempty"`
+}
+
+// Page is a page of entities with the cursors of the adjacent pages, a cursor is empty when there is no such page
+type Page[E any] struct {
+	Items []E    `json:"items"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Total *int64 `json:"total,omitempty"`
+}
+
+// pageCursor holds the sort values of the row a page starts after, the sort keys tell cursors of another order apart
+type pageCursor struct {
+	Keys   []string          `json:"k"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// FindPage returns a page of the entities matching the specifications with keyset pagination
+// Rows are selected by comparing their sort fields to the cursor, so pages stay fast and consistent while rows are added
+// OrderBy specifications are used as sort keys after PageRequest.Sort
+func (r *GormRepository[M, E]) FindPage(ctx context.Context, request PageRequest, specifications ...Specification) (Page[E], error) {
+	size := request.Size
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	keys := append([]SortKey(nil), request.Sort...)
+	conditions := make([]Specification, 0, len(specifications))
+	for _, s := range specifications {
+		if order, ok := s.(orderSpecification); ok {
+			keys = append(keys, SortKey{Field: order.field, Direction: order.direction})
+		} else {
+			conditions = append(conditions, s)
+		}
+	}
+	keys, err := r.pageKeys(keys)
+	if err != nil {
+		return Page[E]{}, err
+	}
+
+	backward := request.Before != "" && request.After == ""
+	token := request.After
+	if backward {
+		token = request.Before
+	}
+	if token != "" {
+		values, err := r.decodeCursor(token, keys)
+		if err != nil {
+			return Page[E]{}, err
+		}
+		conditions = append(conditions, keysetSpecification{keys: keys, values: values, backward: backward})
+	}
+	query := r.getPreWarmDbForSelect(ctx, conditions...)
+	for _, key := range keys {
+		direction := key.Direction
+		if backward {
+			direction = reverse(direction)
+		}
+		query = r.applySpecifications(query, OrderBy(key.Field, direction))
+	}
+
+	var models []M
+	if err := query.Limit(size + 1).Find(&models).Error; err != nil {
+		return Page[E]{}, err
+	}
+	more := len(models) > size
+	if more {
+		models = models[:size]
+	}
+	if backward {
+		for i, j := 0, len(models)-1; i < j; i, j = i+1, j-1 {
+			models[i], models[j] = models[j], models[i]
+		}
+	}
+
+	page := Page[E]{Items: make([]E, 0, len(models))}
+	for _, model := range models {
+		page.Items = append(page.Items, model.ToEntity())
+	}
+	// the page the request came from is assumed to still exist
+	next, prev := more, token != ""
+	if backward {
+		next, prev = true, more
+	}
+	if next && len(models) > 0 {
+		if page.Next, err = r.encodeCursor(ctx, keys, &models[len(models)-1]); err != nil {
+			return Page[E]{}, err
+		}
+	}
+	if prev && len(models) > 0 {
+		if page.Prev, err = r.encodeCursor(ctx, keys, &models[0]); err != nil {
+			return Page[E]{}, err
+		}
+	}
+	if request.WithTotal {
+		total, err := r.Count(ctx, specifications...)
+		if err != nil {
+			return Page[E]{}, err
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// pageKeys checks the sort keys against the schema and appends the primary key when missing
+func (r *GormRepository[M, E]) pageKeys(keys []SortKey) ([]SortKey, error) {
+	sc, err := r.getSchema()
+	if err != nil {
+		return nil, err
+	}
+	b := &builder{schema: sc}
+	hasPrimaryKey := false
+	for i, key := range keys {
+		if keys[i].Field, err = b.dbName(key.Field); err != nil {
+			return nil, err
+		}
+		if keys[i].Direction == "" {
+			keys[i].Direction = Ascending
+		}
+		if keys[i].Direction != Ascending && keys[i].Direction != Descending {
+			return nil, fmt.Errorf("%w %q", ErrInvalidOperator, key.Direction)
+		}
+		if sc.PrioritizedPrimaryField != nil && keys[i].Field == sc.PrioritizedPrimaryField.DBName {
+			hasPrimaryKey = true
+		}
+	}
+	if !hasPrimaryKey {
+		if sc.PrioritizedPrimaryField == nil {
+			return nil, fmt.Errorf("db: %s has no primary key to page by", sc.Name)
+		}
+		keys = append(keys, SortKey{Field: sc.PrioritizedPrimaryField.DBName, Direction: Ascending})
+	}
+	return keys, nil
+}
+
+func (r *GormRepository[M, E]) encodeCursor(ctx context.Context, keys []SortKey, model *M) (string, error) {
+	sc, err := r.getSchema()
+	if err != nil {
+		return "", err
+	}
+	cursor := pageCursor{Keys: sortKeyNames(keys)}
+	for _, key := range keys {
+		value, _ := sc.LookUpField(key.Field).ValueOf(ctx, reflect.ValueOf(model))
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		cursor.Values = append(cursor.Values, data)
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the sort values of a cursor decoded to the types of their fields
+func (r *GormRepository[M, E]) decodeCursor(token string, keys []SortKey) ([]any, error) {
+	sc, err := r.getSchema()
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || len(cursor.Values) != len(keys) ||
+		strings.Join(cursor.Keys, ",") != strings.Join(sortKeyNames(keys), ",") {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]any, 0, len(keys))
+	for i, key := range keys {
+		value := reflect.New(sc.LookUpField(key.Field).FieldType)
+		if err := json.Unmarshal(cursor.Values[i], value.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values = append(values, value.Elem().Interface())
+	}
+	return values, nil
+}
+
+func sortKeyNames(keys []SortKey) []string {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, key.Field+" "+string(key.Direction))
+	}
+	return names
+}
+
+func reverse(direction Direction) Direction {
+	if direction == Descending {
+		return Ascending
+	}
+	return Descending
+}
+
+// keysetSpecification matches the rows sorted after the cursor values, or before them when backward
+// (a > ?) OR (a = ? AND b > ?) ... with < for descending keys
+type keysetSpecification struct {
+	keys     []SortKey
+	values   []any
+	backward bool
+}
+
+func (s keysetSpecification) GetQuery() string {
+	return rawQuery(s)
+}
+
+func (s keysetSpecification) GetValues() []any {
+	var values []any
+	for i := range s.keys {
+		values = append(values, s.values[:i+1]...)
+	}
+	return values
+}
+
+func (s keysetSpecification) build(b *builder) (string, error) {
+	columns := make([]string, 0, len(s.keys))
+	clauses := make([]string, 0, len(s.keys))
+	for _, key := range s.keys {
+		column, err := b.column(key.Field)
+		if err != nil {
+			return "", err
+		}
+		operator := ">"
+		if (key.Direction == Descending) != s.backward {
+			operator = "<"
+		}
+		parts := make([]string, 0, len(columns)+1)
+		for _, previous := range columns {
+			parts = append(parts, previous+" = ?")
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", column, operator))
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+		columns = append(columns, column)
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", nil
+}
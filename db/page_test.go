@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFindPage(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	ranks := []int{3, 1, 2, 3, 1, 2, 3}
+	items := make([]testItem, 0, len(ranks))
+	for i, rank := range ranks {
+		items = append(items, testItem{Code: fmt.Sprint(i), Rank: rank})
+	}
+	insertTestItems(t, repo, items)
+	// rank descending then id ascending, ids start at 1
+	want := []uint{1, 4, 7, 3, 6, 2, 5}
+	request := PageRequest{Size: 3, Sort: []SortKey{{Field: "Rank", Direction: Descending}}}
+
+	var pages []Page[testItem]
+	var forward []uint
+	for {
+		page, err := repo.FindPage(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+		forward = append(forward, itemIDs(page.Items)...)
+		if page.Next == "" {
+			break
+		}
+		request.After = page.Next
+	}
+	if !reflect.DeepEqual(forward, want) {
+		t.Fatalf("paged forward through %v, want %v", forward, want)
+	}
+	if len(pages) != 3 || pages[0].Prev != "" {
+		t.Fatalf("got %d pages, first Prev %q, want 3 pages and no Prev on the first", len(pages), pages[0].Prev)
+	}
+
+	request.After = ""
+	var backward []uint
+	for before := pages[len(pages)-1].Prev; before != ""; {
+		request.Before = before
+		page, err := repo.FindPage(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backward = append(itemIDs(page.Items), backward...)
+		before = page.Prev
+	}
+	if !reflect.DeepEqual(backward, want[:6]) {
+		t.Fatalf("paged backward through %v, want %v", backward, want[:6])
+	}
+}
+
+func TestFindPageTotal(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	items := []testItem{{Code: "a", Rank: 1}, {Code: "b", Rank: 2}, {Code: "c", Rank: 2}}
+	insertTestItems(t, repo, items)
+	page, err := repo.FindPage(ctx, PageRequest{Size: 1, WithTotal: true}, Equal("rank", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total == nil || *page.Total != 2 || !reflect.DeepEqual(itemIDs(page.Items), []uint{2}) {
+		t.Fatalf("got %v with total %v, want item 2 of 2", itemIDs(page.Items), page.Total)
+	}
+}
+
+func TestFindPageInvalidCursor(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	items := []testItem{{Code: "a", Rank: 1}, {Code: "b", Rank: 2}}
+	insertTestItems(t, repo, items)
+	page, err := repo.FindPage(ctx, PageRequest{Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]PageRequest{
+		"garbage":    {After: "not a cursor"},
+		"other sort": {After: page.Next, Sort: []SortKey{{Field: "rank"}}},
+	}
+	for name, request := range tests {
+		if _, err := repo.FindPage(ctx, request); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: FindPage error = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+	if _, err := repo.FindPage(ctx, PageRequest{Sort: []SortKey{{Field: "missing"}}}); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("FindPage with an unknown sort field error = %v, want ErrUnknownField", err)
+	}
+}
+
+func itemIDs(items []testItem) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
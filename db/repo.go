@@ -2,8 +2,11 @@ package db
 
 import (
 	"context"
+	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/schema"
+	"reflect"
 	"sync"
 )
 
@@ -23,6 +26,10 @@ type GormRepository[M GormModel[E], E any] struct {
 	assocationsLoaded  bool
 	preloadAssocations bool
 	associations       []string
+	schemaOnce         sync.Once
+	schema             *schema.Schema
+	schemaErr          error
+	hooks              hooks[E]
 }
 
 func (r *GormRepository[M, E]) EnablePreloadAssociations() *GormRepository[M, E] {
@@ -44,7 +51,10 @@ func (r *GormRepository[M, E]) SetPreloadAssociations(association bool) *GormRep
 }
 
 func (r *GormRepository[M, E]) setAssociations(model *M) *GormRepository[M, E] {
-	sc, _ := schema.Parse(model, &sync.Map{}, r.db.NamingStrategy)
+	sc, err := r.getSchema()
+	if err != nil {
+		return r
+	}
 	for _, i := range sc.Relationships.Many2Many {
 		r.associations = append(r.associations, i.Name)
 	}
@@ -52,50 +62,129 @@ func (r *GormRepository[M, E]) setAssociations(model *M) *GormRepository[M, E] {
 	return r
 }
 
+// Insert creates the entity, BeforeInsert handlers run first and AfterInsert handlers once the row is committed
 func (r *GormRepository[M, E]) Insert(ctx context.Context, entity *E) error {
-	var start M
-	model := start.FromEntity(*entity).(M)
-
-	err := r.db.WithContext(ctx).Create(&model).Error
-	if err != nil {
+	if err := r.publish(ctx, Event[E]{Type: BeforeInsert, New: entity}); err != nil {
 		return err
 	}
+	return r.writeTx(ctx, func(ctx context.Context) error {
+		var start M
+		model := start.FromEntity(*entity).(M)
+		if _, err := r.stamp(ctx, &model, true); err != nil {
+			return err
+		}
 
-	*entity = model.ToEntity()
-	return nil
+		err := r.conn(ctx).Create(&model).Error
+		if err != nil {
+			return err
+		}
+
+		inserted := model.ToEntity()
+		if err := r.emit(ctx, Event[E]{Type: AfterInsert, New: &inserted}); err != nil {
+			return err
+		}
+		*entity = model.ToEntity()
+		return nil
+	})
 }
 
 func (r *GormRepository[M, E]) Delete(ctx context.Context, entity *E) error {
-	var start M
-	model := start.FromEntity(*entity).(M)
-	err := r.db.WithContext(ctx).Delete(model).Error
-	if err != nil {
-		return err
-	}
-	return nil
+	return r.writeTx(ctx, func(ctx context.Context) error {
+		var start M
+		model := start.FromEntity(*entity).(M)
+		result := r.conn(ctx).Delete(&model)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		deleted := *entity
+		return r.emit(ctx, Event[E]{Type: AfterDelete, Old: &deleted})
+	})
 }
 
 func (r *GormRepository[M, E]) DeleteByID(ctx context.Context, id any) error {
-	var start M
-	err := r.db.WithContext(ctx).Delete(&start, &id).Error
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return r.writeTx(ctx, func(ctx context.Context) error {
+		var start M
+		var old *E
+		if r.listening(AfterDelete) {
+			if err := r.conn(ctx).First(&start, id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil
+				}
+				return err
+			}
+			entity := start.ToEntity()
+			old = &entity
+		}
+		result := r.conn(ctx).Delete(&start, &id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if old == nil || result.RowsAffected == 0 {
+			return nil
+		}
+		return r.emit(ctx, Event[E]{Type: AfterDelete, Old: old})
+	})
 }
 
+// Update saves every field of the entity, created_by is left as it was
+// When the model has a version column the row is only updated if its version is the one of the entity,
+// ErrStaleEntity is returned otherwise
 func (r *GormRepository[M, E]) Update(ctx context.Context, entity *E) error {
 	var start M
 	model := start.FromEntity(*entity).(M)
-
-	err := r.db.WithContext(ctx).Save(&model).Error
+	sc, err := r.getSchema()
 	if err != nil {
 		return err
 	}
+	if sc.PrioritizedPrimaryField == nil {
+		return gorm.ErrPrimaryKeyRequired
+	}
+	pk, zero := sc.PrioritizedPrimaryField.ValueOf(ctx, reflect.ValueOf(&model))
+	if zero {
+		return r.Insert(ctx, entity)
+	}
+	return r.writeTx(ctx, func(ctx context.Context) error {
+		var old *E
+		if r.listening(AfterUpdate) {
+			var current M
+			if err := r.conn(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: pk}).First(&current).Error; err == nil {
+				entity := current.ToEntity()
+				old = &entity
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		version, err := r.stamp(ctx, &model, false)
+		if err != nil {
+			return err
+		}
 
-	*entity = model.ToEntity()
-	return nil
+		db := r.conn(ctx)
+		if r.field(createdByColumn) != nil {
+			db = db.Omit(createdByColumn)
+		}
+		if r.field(versionColumn) != nil {
+			result := db.Select("*").Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: versionColumn}, Value: version}).Save(&model)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrStaleEntity
+			}
+		} else if err := db.Save(&model).Error; err != nil {
+			return err
+		}
+
+		updated := model.ToEntity()
+		if err := r.emit(ctx, Event[E]{Type: AfterUpdate, Old: old, New: &updated}); err != nil {
+			return err
+		}
+		*entity = model.ToEntity()
+		return nil
+	})
 }
 
 func (r *GormRepository[M, E]) FindByID(ctx context.Context, id any) (E, error) {
@@ -113,17 +202,70 @@ func (r *GormRepository[M, E]) Find(ctx context.Context, specifications ...Speci
 	return r.FindWithLimit(ctx, -1, -1, specifications...)
 }
 
+// Count ignores the OrderBy, Select and Preload specifications
 func (r *GormRepository[M, E]) Count(ctx context.Context, specifications ...Specification) (i int64, err error) {
 	model := new(M)
-	err = r.getPreWarmDbForSelect(ctx, specifications...).Model(model).Count(&i).Error
+	conditions := make([]Specification, 0, len(specifications))
+	for _, s := range specifications {
+		if _, ok := s.(queryOption); !ok {
+			conditions = append(conditions, s)
+		}
+	}
+	err = r.getPreWarmDbForSelect(ctx, conditions...).Model(model).Count(&i).Error
 	return
 }
 
-func (r *GormRepository[M, E]) getPreWarmDbForSelect(ctx context.Context, specification ...Specification) *gorm.DB {
-	dbPrewarm := r.db.WithContext(ctx)
-	for _, s := range specification {
-		dbPrewarm = dbPrewarm.Where(s.GetQuery(), s.GetValues()...)
+// getSchema returns the parsed schema of the model, the fields of the specifications are checked against it
+func (r *GormRepository[M, E]) getSchema() (*schema.Schema, error) {
+	r.schemaOnce.Do(func() {
+		r.schema, r.schemaErr = schema.Parse(new(M), &sync.Map{}, r.db.NamingStrategy)
+	})
+	return r.schema, r.schemaErr
+}
+
+// newBuilder returns a builder checking fields against the model schema and quoting them for the dialect of db
+func (r *GormRepository[M, E]) newBuilder(db *gorm.DB) (*builder, error) {
+	sc, err := r.getSchema()
+	if err != nil {
+		return nil, err
+	}
+	stmt := db.Statement
+	return &builder{dialect: db.Dialector.Name(), schema: sc, quote: func(field string) string { return stmt.Quote(field) }}, nil
+}
+
+// applySpecifications adds the conditions and query options of the specifications to db
+// an unknown field, relation or invalid json path is added as an error so the query is not run
+func (r *GormRepository[M, E]) applySpecifications(db *gorm.DB, specifications ...Specification) *gorm.DB {
+	if len(specifications) == 0 {
+		return db
 	}
+	b, err := r.newBuilder(db)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	for _, s := range specifications {
+		if option, ok := s.(queryOption); ok {
+			if db, err = option.apply(db, b); err != nil {
+				_ = db.AddError(err)
+				return db
+			}
+			continue
+		}
+		query, err := buildQuery(s, b)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		if query != "" {
+			db = db.Where(query, s.GetValues()...)
+		}
+	}
+	return db
+}
+
+func (r *GormRepository[M, E]) getPreWarmDbForSelect(ctx context.Context, specification ...Specification) *gorm.DB {
+	dbPrewarm := r.applySpecifications(r.conn(ctx), specification...)
 
 	if r.preloadAssocations {
 		if !r.assocationsLoaded {
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testItem is its own entity so tests read and write the columns directly
+type testItem struct {
+	ID        uint   `gorm:"primaryKey"`
+	Code      string `gorm:"uniqueIndex"`
+	Name      string
+	Rank      int
+	CreatedBy string
+	UpdatedBy string
+	Version   int64
+	CreatedAt time.Time
+}
+
+func (m testItem) ToEntity() testItem {
+	return m
+}
+
+func (m testItem) FromEntity(entity testItem) interface{} {
+	return entity
+}
+
+func newTestRepository(t *testing.T) *GormRepository[testItem, testItem] {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&testItem{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewRepository[testItem, testItem](db)
+}
+
+func insertTestItems(t *testing.T, repo *GormRepository[testItem, testItem], items []testItem) {
+	t.Helper()
+	for i := range items {
+		if err := repo.Insert(context.Background(), &items[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -1,8 +1,23 @@
 package db
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
 	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrUnknownField     = errors.New("db: unknown field")
+	ErrInvalidJSONPath  = errors.New("db: invalid json path")
+	ErrInvalidOperator  = errors.New("db: invalid operator")
+	ErrUnknownRelation  = errors.New("db: unknown relation")
+	jsonPathSegmentRule = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
 )
 
 type Specification interface {
@@ -10,19 +25,66 @@ type Specification interface {
 	GetValues() []any
 }
 
+// builder resolves the fields of the specifications of this package against a model schema
+// Without a schema fields are used as given, which is what GetQuery returns
+type builder struct {
+	dialect string
+	schema  *schema.Schema
+	quote   func(field string) string
+}
+
+// buildable is implemented by the specifications of this package
+type buildable interface {
+	build(b *builder) (string, error)
+}
+
+// queryOption is a Specification changing how rows are selected rather than which rows match
+type queryOption interface {
+	Specification
+	apply(db *gorm.DB, b *builder) (*gorm.DB, error)
+}
+
+// dbName returns the column name of a schema field, fields may be given by column or struct field name
+func (b *builder) dbName(field string) (string, error) {
+	if b == nil || b.schema == nil {
+		return field, nil
+	}
+	if f := b.schema.LookUpField(field); f != nil && f.DBName != "" {
+		return f.DBName, nil
+	}
+	return "", fmt.Errorf("%w %q on %s", ErrUnknownField, field, b.schema.Name)
+}
+
+// column returns the quoted column name of a schema field
+func (b *builder) column(field string) (string, error) {
+	name, err := b.dbName(field)
+	if err != nil || b == nil || b.quote == nil {
+		return name, err
+	}
+	return b.quote(name), nil
+}
+
+// buildQuery returns the query of a specification with its fields checked against the builder schema
+// Specifications implemented outside this package are trusted and used as is
+func buildQuery(spec Specification, b *builder) (string, error) {
+	if s, ok := spec.(buildable); ok {
+		return s.build(b)
+	}
+	return spec.GetQuery(), nil
+}
+
+func rawQuery(spec buildable) string {
+	query, _ := spec.build(nil)
+	return query
+}
+
 type joinSpecification struct {
 	specifications []Specification
 	separator      string
 }
 
 func (s joinSpecification) GetQuery() string {
-	queries := make([]string, 0, len(s.specifications))
-
-	for _, spec := range s.specifications {
-		queries = append(queries, spec.GetQuery())
-	}
-
-	return strings.Join(queries, fmt.Sprintf(" %s ", s.separator))
+	return rawQuery(s)
 }
 
 func (s joinSpecification) GetValues() []any {
@@ -35,6 +97,37 @@ func (s joinSpecification) GetValues() []any {
 	return values
 }
 
+// matchNone is the query of a specification no row matches
+const matchNone = "1 = 0"
+
+// build joins the queries of the specifications, query options have no query and are skipped
+// the result is parenthesized so nested And and Or keep their precedence
+// And without a query matches every row and Or without one matches none
+func (s joinSpecification) build(b *builder) (string, error) {
+	queries := make([]string, 0, len(s.specifications))
+
+	for _, spec := range s.specifications {
+		query, err := buildQuery(spec, b)
+		if err != nil {
+			return "", err
+		}
+		if query != "" {
+			queries = append(queries, query)
+		}
+	}
+
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+	if len(queries) == 0 && s.separator == "OR" {
+		return matchNone, nil
+	}
+	if len(queries) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(queries, fmt.Sprintf(" %s ", s.separator)) + ")", nil
+}
+
 func And(specifications ...Specification) Specification {
 	return joinSpecification{
 		specifications: specifications,
@@ -54,7 +147,19 @@ type notSpecification struct {
 }
 
 func (s notSpecification) GetQuery() string {
-	return fmt.Sprintf(" NOT (%s)", s.Specification.GetQuery())
+	return rawQuery(s)
+}
+
+// build negates the query of the specification, a specification without a query matches every row so its negation matches none
+func (s notSpecification) build(b *builder) (string, error) {
+	query, err := buildQuery(s.Specification, b)
+	if err != nil {
+		return "", err
+	}
+	if query == "" {
+		return matchNone, nil
+	}
+	return fmt.Sprintf(" NOT (%s)", query), nil
 }
 
 func Not(specification Specification) Specification {
@@ -70,13 +175,21 @@ type binaryOperatorSpecification[T any] struct {
 }
 
 func (s binaryOperatorSpecification[T]) GetQuery() string {
-	return fmt.Sprintf("%s %s ?", s.field, s.operator)
+	return rawQuery(s)
 }
 
 func (s binaryOperatorSpecification[T]) GetValues() []any {
 	return []any{s.value}
 }
 
+func (s binaryOperatorSpecification[T]) build(b *builder) (string, error) {
+	column, err := b.column(s.field)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s ?", column, s.operator), nil
+}
+
 func Equal[T any](field string, value T) Specification {
 	return binaryOperatorSpecification[T]{
 		field:    field,
@@ -85,6 +198,14 @@ func Equal[T any](field string, value T) Specification {
 	}
 }
 
+func NotEqual[T any](field string, value T) Specification {
+	return binaryOperatorSpecification[T]{
+		field:    field,
+		operator: "<>",
+		value:    value,
+	}
+}
+
 func GreaterThan[T comparable](field string, value T) Specification {
 	return binaryOperatorSpecification[T]{
 		field:    field,
@@ -112,7 +233,7 @@ func LessThan[T comparable](field string, value T) Specification {
 func LessOrEqual[T comparable](field string, value T) Specification {
 	return binaryOperatorSpecification[T]{
 		field:    field,
-		operator: ">=",
+		operator: "<=",
 		value:    value,
 	}
 }
@@ -125,16 +246,443 @@ func In[T any](field string, value []T) Specification {
 	}
 }
 
-type stringSpecification string
+func NotIn[T any](field string, value []T) Specification {
+	return binaryOperatorSpecification[[]T]{
+		field:    field,
+		operator: "NOT IN",
+		value:    value,
+	}
+}
+
+// Like matches field against a pattern where % matches any sequence and _ any character
+func Like(field string, pattern string) Specification {
+	return binaryOperatorSpecification[string]{
+		field:    field,
+		operator: "LIKE",
+		value:    pattern,
+	}
+}
+
+type insensitiveLikeSpecification struct {
+	field   string
+	pattern string
+}
+
+func (s insensitiveLikeSpecification) GetQuery() string {
+	return rawQuery(s)
+}
+
+func (s insensitiveLikeSpecification) GetValues() []any {
+	return []any{s.pattern}
+}
+
+func (s insensitiveLikeSpecification) build(b *builder) (string, error) {
+	column, err := b.column(s.field)
+	if err != nil {
+		return "", err
+	}
+	if b != nil && b.dialect == "postgres" {
+		return fmt.Sprintf("%s ILIKE ?", column), nil
+	}
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", column), nil
+}
+
+// ILike is a case-insensitive Like, it uses ILIKE on postgres and compares lower cased values elsewhere
+func ILike(field string, pattern string) Specification {
+	return insensitiveLikeSpecification{
+		field:   field,
+		pattern: pattern,
+	}
+}
+
+type patternKind int
+
+const (
+	containing patternKind = iota
+	prefixed
+	suffixed
+)
+
+// patternSpecification matches a literal substring, % and _ in the text are escaped with !
+type patternSpecification struct {
+	field string
+	text  string
+	kind  patternKind
+	not   bool
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func (s patternSpecification) GetQuery() string {
+	return rawQuery(s)
+}
+
+func (s patternSpecification) GetValues() []any {
+	pattern := likeEscaper.Replace(s.text)
+	switch s.kind {
+	case prefixed:
+		return []any{pattern + "%"}
+	case suffixed:
+		return []any{"%" + pattern}
+	}
+	return []any{"%" + pattern + "%"}
+}
+
+func (s patternSpecification) build(b *builder) (string, error) {
+	column, err := b.column(s.field)
+	if err != nil {
+		return "", err
+	}
+	if s.not {
+		return fmt.Sprintf("%s NOT LIKE ? ESCAPE '!'", column), nil
+	}
+	return fmt.Sprintf("%s LIKE ? ESCAPE '!'", column), nil
+}
+
+// Contains matches the field values containing text, unlike Like the text has no wildcards
+func Contains(field string, text string) Specification {
+	return patternSpecification{
+		field: field,
+		text:  text,
+	}
+}
+
+func NotContains(field string, text string) Specification {
+	return patternSpecification{
+		field: field,
+		text:  text,
+		not:   true,
+	}
+}
 
-func (s stringSpecification) GetQuery() string {
-	return string(s)
+func StartsWith(field string, prefix string) Specification {
+	return patternSpecification{
+		field: field,
+		text:  prefix,
+		kind:  prefixed,
+	}
+}
+
+func EndsWith(field string, suffix string) Specification {
+	return patternSpecification{
+		field: field,
+		text:  suffix,
+		kind:  suffixed,
+	}
+}
+
+type betweenSpecification[T any] struct {
+	field string
+	from  T
+	to    T
+}
+
+func (s betweenSpecification[T]) GetQuery() string {
+	return rawQuery(s)
+}
+
+func (s betweenSpecification[T]) GetValues() []any {
+	return []any{s.from, s.to}
+}
+
+func (s betweenSpecification[T]) build(b *builder) (string, error) {
+	column, err := b.column(s.field)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s BETWEEN ? AND ?", column), nil
 }
 
-func (s stringSpecification) GetValues() []any {
+// Between matches field values from from to to, both included
+func Between[T comparable](field string, from, to T) Specification {
+	return betweenSpecification[T]{
+		field: field,
+		from:  from,
+		to:    to,
+	}
+}
+
+type nullSpecification struct {
+	field string
+	not   bool
+}
+
+func (s nullSpecification) GetQuery() string {
+	return rawQuery(s)
+}
+
+func (s nullSpecification) GetValues() []any {
 	return nil
 }
 
+func (s nullSpecification) build(b *builder) (string, error) {
+	column, err := b.column(s.field)
+	if err != nil {
+		return "", err
+	}
+	if s.not {
+		return fmt.Sprintf("%s IS NOT NULL", column), nil
+	}
+	return fmt.Sprintf("%s IS NULL", column), nil
+}
+
 func IsNull(field string) Specification {
-	return stringSpecification(fmt.Sprintf("%s IS NULL", field))
+	return nullSpecification{
+		field: field,
+	}
+}
+
+func IsNotNull(field string) Specification {
+	return nullSpecification{
+		field: field,
+		not:   true,
+	}
+}
+
+type existsSpecification struct {
+	subquery *gorm.DB
+	not      bool
+}
+
+func (s existsSpecification) GetQuery() string {
+	if s.not {
+		return "NOT EXISTS (?)"
+	}
+	return "EXISTS (?)"
+}
+
+func (s existsSpecification) GetValues() []any {
+	return []any{s.subquery}
+}
+
+// Exists matches when the subquery returns a row, the subquery is built by the caller with db.Model(...).Where(...)
+func Exists(subquery *gorm.DB) Specification {
+	return existsSpecification{
+		subquery: subquery,
+	}
+}
+
+func NotExists(subquery *gorm.DB) Specification {
+	return existsSpecification{
+		subquery: subquery,
+		not:      true,
+	}
+}
+
+type jsonSpecification struct {
+	field    string
+	path     string
+	operator string
+	value    any
+}
+
+func (s jsonSpecification) GetQuery() string {
+	return rawQuery(s)
+}
+
+func (s jsonSpecification) GetValues() []any {
+	if s.operator == "" {
+		return nil
+	}
+	return []any{s.value}
+}
+
+func (s jsonSpecification) build(b *builder) (string, error) {
+	column, err := b.column(s.field)
+	if err != nil {
+		return "", err
+	}
+	segments := strings.Split(s.path, ".")
+	for _, segment := range segments {
+		if !jsonPathSegmentRule.MatchString(segment) {
+			return "", fmt.Errorf("%w %q", ErrInvalidJSONPath, s.path)
+		}
+	}
+	dialect := ""
+	if b != nil {
+		dialect = b.dialect
+	}
+	value := jsonExtract(dialect, column, segments, s.value)
+	if s.operator == "" {
+		return fmt.Sprintf("%s IS NOT NULL", value), nil
+	}
+	switch s.operator {
+	case "=", "<>", ">", ">=", "<", "<=", "LIKE":
+		return fmt.Sprintf("%s %s ?", value, s.operator), nil
+	}
+	return "", fmt.Errorf("%w %q", ErrInvalidOperator, s.operator)
+}
+
+// jsonExtract returns the expression extracting a path from a json column
+// on postgres the extracted text is cast when value is a number or a boolean so it compares like the value
+func jsonExtract(dialect, column string, segments []string, value any) string {
+	switch dialect {
+	case "postgres":
+		extract := fmt.Sprintf("%s #>> '{%s}'", column, strings.Join(segments, ","))
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return fmt.Sprintf("CAST(%s AS NUMERIC)", extract)
+		case reflect.Bool:
+			return fmt.Sprintf("CAST(%s AS BOOLEAN)", extract)
+		}
+		return "(" + extract + ")"
+	case "mysql":
+		return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))", column, jsonPath(segments))
+	case "sqlserver":
+		return fmt.Sprintf("JSON_VALUE(%s, '%s')", column, jsonPath(segments))
+	}
+	return fmt.Sprintf("JSON_EXTRACT(%s, '%s')", column, jsonPath(segments))
+}
+
+// jsonPath returns segments as a $.a.b[0] path
+func jsonPath(segments []string) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, segment := range segments {
+		if strings.Trim(segment, "0123456789") == "" {
+			sb.WriteString("[" + segment + "]")
+		} else {
+			sb.WriteString("." + segment)
+		}
+	}
+	return sb.String()
+}
+
+// JSONPath compares the value at a dotted path of a json column, numeric segments index arrays
+// operator is one of =, <>, >, >=, <, <= and LIKE
+func JSONPath[T any](field, path, operator string, value T) Specification {
+	return jsonSpecification{
+		field:    field,
+		path:     path,
+		operator: strings.ToUpper(operator),
+		value:    value,
+	}
+}
+
+func JSONEqual[T any](field, path string, value T) Specification {
+	return JSONPath(field, path, "=", value)
+}
+
+// JSONExists matches when a dotted path of a json column holds a non null value
+func JSONExists(field, path string) Specification {
+	return jsonSpecification{
+		field: field,
+		path:  path,
+	}
+}
+
+// Direction is the sort direction of OrderBy
+type Direction string
+
+const (
+	Ascending  Direction = "ASC"
+	Descending Direction = "DESC"
+)
+
+type orderSpecification struct {
+	field     string
+	direction Direction
+}
+
+func (s orderSpecification) GetQuery() string {
+	return ""
+}
+
+func (s orderSpecification) GetValues() []any {
+	return nil
+}
+
+func (s orderSpecification) apply(db *gorm.DB, b *builder) (*gorm.DB, error) {
+	name, err := b.dbName(s.field)
+	if err != nil {
+		return db, err
+	}
+	return db.Order(clause.OrderByColumn{Column: clause.Column{Name: name}, Desc: s.direction == Descending}), nil
+}
+
+// OrderBy sorts the rows found by field, several OrderBy sort by each field in turn
+func OrderBy(field string, direction Direction) Specification {
+	return orderSpecification{
+		field:     field,
+		direction: direction,
+	}
+}
+
+type selectSpecification struct {
+	fields []string
+}
+
+func (s selectSpecification) GetQuery() string {
+	return ""
+}
+
+func (s selectSpecification) GetValues() []any {
+	return nil
+}
+
+func (s selectSpecification) apply(db *gorm.DB, b *builder) (*gorm.DB, error) {
+	names := make([]string, 0, len(s.fields))
+	for _, field := range s.fields {
+		name, err := b.dbName(field)
+		if err != nil {
+			return db, err
+		}
+		names = append(names, name)
+	}
+	return db.Select(names), nil
+}
+
+// Select loads only fields, the other fields of the entities found keep their zero value
+func Select(fields ...string) Specification {
+	return selectSpecification{
+		fields: fields,
+	}
+}
+
+type preloadSpecification struct {
+	association    string
+	specifications []Specification
+}
+
+func (s preloadSpecification) GetQuery() string {
+	return ""
+}
+
+func (s preloadSpecification) GetValues() []any {
+	return nil
+}
+
+// apply preloads the association, the specifications filter the associated rows and are checked against their schema
+func (s preloadSpecification) apply(db *gorm.DB, b *builder) (*gorm.DB, error) {
+	related := &builder{}
+	if b != nil && b.schema != nil {
+		related.dialect, related.quote = b.dialect, b.quote
+		related.schema = b.schema
+		for _, name := range strings.Split(s.association, ".") {
+			relation, ok := related.schema.Relationships.Relations[name]
+			if !ok {
+				return db, fmt.Errorf("%w %q on %s", ErrUnknownRelation, name, related.schema.Name)
+			}
+			related.schema = relation.FieldSchema
+		}
+	}
+	if len(s.specifications) == 0 {
+		return db.Preload(s.association), nil
+	}
+	spec := And(s.specifications...)
+	query, err := buildQuery(spec, related)
+	if err != nil {
+		return db, err
+	}
+	return db.Preload(s.association, append([]any{query}, spec.GetValues()...)...), nil
+}
+
+// Preload loads an association of the entities found, nested associations are separated by dots
+// The specifications filter the associated rows
+func Preload(association string, specifications ...Specification) Specification {
+	return preloadSpecification{
+		association:    association,
+		specifications: specifications,
+	}
 }
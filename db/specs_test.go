@@ -0,0 +1,137 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type specModel struct {
+	ID   uint
+	Name string
+	Rank int
+	Meta string `gorm:"column:meta_data"`
+}
+
+func newTestBuilder(t *testing.T, dialect string) *builder {
+	t.Helper()
+	sc, err := schema.Parse(&specModel{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	quote := func(field string) string { return "`" + field + "`" }
+	if dialect == "postgres" {
+		quote = func(field string) string { return `"` + field + `"` }
+	}
+	return &builder{dialect: dialect, schema: sc, quote: quote}
+}
+
+func TestSpecificationBuild(t *testing.T) {
+	b := newTestBuilder(t, "sqlite")
+	tests := []struct {
+		name   string
+		spec   Specification
+		query  string
+		values []any
+	}{
+		{name: "struct field", spec: Equal("Name", "a"), query: "`name` = ?", values: []any{"a"}},
+		{name: "column", spec: Equal("meta_data", "a"), query: "`meta_data` = ?", values: []any{"a"}},
+		{name: "less or equal", spec: LessOrEqual("rank", 3), query: "`rank` <= ?", values: []any{3}},
+		{name: "greater or equal", spec: GreaterOrEqual("rank", 3), query: "`rank` >= ?", values: []any{3}},
+		{name: "not in", spec: NotIn("rank", []int{1, 2}), query: "`rank` NOT IN ?", values: []any{[]int{1, 2}}},
+		{name: "between", spec: Between("rank", 1, 5), query: "`rank` BETWEEN ? AND ?", values: []any{1, 5}},
+		{name: "like", spec: Like("name", "a%"), query: "`name` LIKE ?", values: []any{"a%"}},
+		{name: "ilike", spec: ILike("name", "A%"), query: "LOWER(`name`) LIKE LOWER(?)", values: []any{"A%"}},
+		{name: "contains", spec: Contains("name", "50%_!"), query: "`name` LIKE ? ESCAPE '!'", values: []any{"%50!%!_!!%"}},
+		{name: "starts with", spec: StartsWith("name", "a"), query: "`name` LIKE ? ESCAPE '!'", values: []any{"a%"}},
+		{name: "not contains", spec: NotContains("name", "a"), query: "`name` NOT LIKE ? ESCAPE '!'", values: []any{"%a%"}},
+		{name: "is null", spec: IsNull("name"), query: "`name` IS NULL", values: nil},
+		{name: "nested", spec: Or(And(Equal("name", "a"), GreaterThan("rank", 1)), Not(IsNotNull("Meta"))), query: "((`name` = ? AND `rank` > ?) OR  NOT (`meta_data` IS NOT NULL))", values: []any{"a", 1}},
+		{name: "empty and", spec: And(), query: "", values: []any{}},
+		{name: "empty or", spec: Or(), query: "1 = 0", values: []any{}},
+		{name: "not of empty", spec: Not(And()), query: "1 = 0", values: []any{}},
+		{name: "options skipped", spec: And(OrderBy("rank", Descending), Equal("name", "a")), query: "`name` = ?", values: []any{"a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := buildQuery(test.spec, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != test.query {
+				t.Errorf("query = %q, want %q", query, test.query)
+			}
+			if values := test.spec.GetValues(); !reflect.DeepEqual(values, test.values) {
+				t.Errorf("values = %#v, want %#v", values, test.values)
+			}
+		})
+	}
+}
+
+func TestSpecificationUnknownField(t *testing.T) {
+	b := newTestBuilder(t, "sqlite")
+	for _, spec := range []Specification{
+		Equal("missing", 1),
+		And(Equal("name", "a"), LessOrEqual("missing", 1)),
+		Not(IsNull("missing")),
+		Between("missing", 1, 2),
+		Like("name; DROP TABLE spec_models", "a"),
+		JSONEqual("missing", "a", 1),
+	} {
+		if _, err := buildQuery(spec, b); !errors.Is(err, ErrUnknownField) {
+			t.Errorf("%#v built with %v, want ErrUnknownField", spec, err)
+		}
+	}
+	for _, spec := range []Specification{OrderBy("missing", Ascending), Select("name", "missing")} {
+		if _, err := spec.(queryOption).apply(nil, b); !errors.Is(err, ErrUnknownField) {
+			t.Errorf("%#v applied with %v, want ErrUnknownField", spec, err)
+		}
+	}
+
+	// without a schema the fields are used as given
+	if query := LessOrEqual("Rank", 3).GetQuery(); query != "Rank <= ?" {
+		t.Errorf("GetQuery = %q, want %q", query, "Rank <= ?")
+	}
+}
+
+func TestJSONPathDialects(t *testing.T) {
+	tests := []struct {
+		dialect string
+		spec    Specification
+		query   string
+	}{
+		{dialect: "sqlite", spec: JSONEqual("Meta", "a.b.0", "x"), query: "JSON_EXTRACT(`meta_data`, '$.a.b[0]') = ?"},
+		{dialect: "mysql", spec: JSONPath("Meta", "a.b", ">=", 2), query: "JSON_UNQUOTE(JSON_EXTRACT(`meta_data`, '$.a.b')) >= ?"},
+		{dialect: "sqlserver", spec: JSONPath("Meta", "a", "like", "x%"), query: "JSON_VALUE(`meta_data`, '$.a') LIKE ?"},
+		{dialect: "postgres", spec: JSONEqual("Meta", "a.b.0", "x"), query: `("meta_data" #>> '{a,b,0}') = ?`},
+		{dialect: "postgres", spec: JSONPath("Meta", "a", "<", 2.5), query: `CAST("meta_data" #>> '{a}' AS NUMERIC) < ?`},
+		{dialect: "postgres", spec: JSONEqual("Meta", "a", true), query: `CAST("meta_data" #>> '{a}' AS BOOLEAN) = ?`},
+		{dialect: "postgres", spec: JSONExists("Meta", "a"), query: `("meta_data" #>> '{a}') IS NOT NULL`},
+		{dialect: "postgres", spec: ILike("name", "a%"), query: `"name" ILIKE ?`},
+	}
+	for _, test := range tests {
+		query, err := buildQuery(test.spec, newTestBuilder(t, test.dialect))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query != test.query {
+			t.Errorf("%s query = %q, want %q", test.dialect, query, test.query)
+		}
+	}
+	if values := JSONExists("Meta", "a").GetValues(); values != nil {
+		t.Errorf("JSONExists values = %v, want none", values)
+	}
+
+	b := newTestBuilder(t, "sqlite")
+	for _, path := range []string{"", "a..b", "a.b-c", "a'); DROP TABLE x; --"} {
+		if _, err := buildQuery(JSONEqual("Meta", path, 1), b); !errors.Is(err, ErrInvalidJSONPath) {
+			t.Errorf("path %q built with %v, want ErrInvalidJSONPath", path, err)
+		}
+	}
+	if _, err := buildQuery(JSONPath("Meta", "a", "; DROP", 1), b); !errors.Is(err, ErrInvalidOperator) {
+		t.Errorf("operator built with %v, want ErrInvalidOperator", err)
+	}
+}
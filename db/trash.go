@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotSoftDeletable is returned by Restore when the model has no deleted_at column
+var ErrNotSoftDeletable = errors.New("db: model has no deleted_at column")
+
+// FindWithTrashed returns the entities matching the specifications including the soft deleted ones
+// Models with a gorm.DeletedAt field are soft deleted, Delete and DeleteWhere set deleted_at and the other Find methods skip those rows
+func (r *GormRepository[M, E]) FindWithTrashed(ctx context.Context, specifications ...Specification) ([]E, error) {
+	var models []M
+	if err := r.getPreWarmDbForSelect(ctx, specifications...).Unscoped().Find(&models).Error; err != nil {
+		return nil, err
+	}
+	result := make([]E, 0, len(models))
+	for _, row := range models {
+		result = append(result, row.ToEntity())
+	}
+	return result, nil
+}
+
+// Restore clears the deleted_at column of a soft deleted row, gorm.ErrRecordNotFound is returned when there is no such row
+func (r *GormRepository[M, E]) Restore(ctx context.Context, id any) error {
+	if r.field(deletedAtColumn) == nil {
+		return ErrNotSoftDeletable
+	}
+	values := map[string]any{deletedAtColumn: nil}
+	if actor, ok := ActorFromContext(ctx); ok && r.field(updatedByColumn) != nil {
+		values[updatedByColumn] = actor
+	}
+	result := r.conn(ctx).Unscoped().Model(new(M)).
+		Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).
+		Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: deletedAtColumn}, Value: nil}).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
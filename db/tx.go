@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// txState is the transaction carried by a context with the events to publish once it is committed
+type txState struct {
+	db *gorm.DB
+	// managed is set for the transactions of WithTx, the commit of other ones is not known so their events are published right away
+	managed bool
+	mu      sync.Mutex
+	pending []func(ctx context.Context) error
+}
+
+func (s *txState) afterCommit(fn ...func(ctx context.Context) error) {
+	s.mu.Lock()
+	s.pending = append(s.pending, fn...)
+	s.mu.Unlock()
+}
+
+func (s *txState) commit(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	var errs []error
+	for _, fn := range pending {
+		errs = append(errs, fn(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// ContextWithTx returns a context carrying tx, repositories called with it run their queries in tx
+// The repositories cannot tell when tx is committed so their events are published right away, WithTx publishes them after the commit
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, &txState{db: tx})
+}
+
+// TxFromContext returns the transaction carried by ctx
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	state := txFromContext(ctx)
+	if state == nil {
+		return nil, false
+	}
+	return state.db, true
+}
+
+func txFromContext(ctx context.Context) *txState {
+	state, _ := ctx.Value(txKey{}).(*txState)
+	if state == nil || state.db == nil {
+		return nil
+	}
+	return state
+}
+
+// conn returns the transaction of ctx, or the repository db when there is none
+func (r *GormRepository[M, E]) conn(ctx context.Context) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return r.db.WithContext(ctx)
+}
+
+// WithTx runs fn in a transaction committed when fn returns nil and rolled back otherwise
+// The transaction is carried by the context given to fn, so every repository called with it shares the transaction
+// A WithTx inside another one runs in a savepoint of the outer transaction
+// The After events of the writes are published once the outermost transaction is committed, and dropped on rollback,
+// the errors of their handlers are returned by WithTx although the transaction is committed
+func (r *GormRepository[M, E]) WithTx(ctx context.Context, fn func(ctx context.Context, repo *GormRepository[M, E]) error) error {
+	parent := txFromContext(ctx)
+	state := &txState{managed: true}
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		state.db = tx
+		return fn(context.WithValue(ctx, txKey{}, state), r)
+	})
+	if err != nil {
+		return err
+	}
+	if parent != nil && parent.managed {
+		parent.afterCommit(state.pending...)
+		return nil
+	}
+	return state.commit(ctx)
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	failed := errors.New("failed")
+	err := repo.WithTx(ctx, func(ctx context.Context, repo *GormRepository[testItem, testItem]) error {
+		if err := repo.InsertBatch(ctx, []testItem{{Code: "a"}, {Code: "b"}, {Code: "c"}}, 2); err != nil {
+			return err
+		}
+		// a nested transaction runs in a savepoint, its rollback keeps the outer writes
+		err := repo.WithTx(ctx, func(ctx context.Context, repo *GormRepository[testItem, testItem]) error {
+			if _, err := repo.DeleteWhere(ctx, Equal("code", "a")); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("nested WithTx error = %v, want %v", err, failed)
+		}
+		count, err := repo.Count(ctx)
+		if err != nil {
+			return err
+		}
+		if count != 3 {
+			t.Errorf("counted %d rows in the transaction, want 3", count)
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithTx error = %v, want %v", err, failed)
+	}
+	if count, err := repo.Count(ctx); err != nil || count != 0 {
+		t.Fatalf("counted %d rows after the rollback (%v), want 0", count, err)
+	}
+
+	items := []testItem{{Code: "a"}, {Code: "b"}, {Code: "c"}}
+	if err := repo.InsertBatch(ctx, items, 2); err != nil {
+		t.Fatal(err)
+	}
+	if items[0].ID == 0 || items[2].ID == 0 {
+		t.Fatalf("inserted %+v, want their ids set", items)
+	}
+	if deleted, err := repo.DeleteWhere(ctx, In("code", []string{"a", "c"})); err != nil || deleted != 2 {
+		t.Fatalf("DeleteWhere deleted %d (%v), want 2", deleted, err)
+	}
+	if _, err := repo.DeleteWhere(ctx, OrderBy("code", Ascending)); err == nil {
+		t.Error("DeleteWhere without a condition succeeded")
+	}
+}
@@ -11,14 +11,33 @@ import (
 )
 
 func main() {
-	icds := readFile()
-	db := fts.New[ICD]("icd")
-	db.InsertBatchAsync(icds)
+	db := loadIndex()
 	start := time.Now()
 	fmt.Println(db.SearchExact("third trimester pregnancy diabetes"))
 	fmt.Printf("Time to search %s", time.Since(start))
 }
 
+// loadIndex restores the index from a snapshot when present, otherwise it indexes the json file and saves a snapshot
+func loadIndex() *fts.FTS[ICD] {
+	db := fts.New[ICD]("icd")
+	if file, err := os.Open("icd10_codes.idx"); err == nil {
+		defer file.Close()
+		if err := db.Load(file); err == nil {
+			return db
+		}
+	}
+	db.InsertBatchAsync(readFile())
+	file, err := os.Create("icd10_codes.idx")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+	if err := db.Save(file); err != nil {
+		log.Fatal(err)
+	}
+	return db
+}
+
 type ICD struct {
 	Code string `json:"code"`
 	Desc string `json:"desc"`
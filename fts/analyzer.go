@@ -0,0 +1,272 @@
+package fts
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sujit-baniya/pkg/lang"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Token is a single term produced by an Analyzer
+// Position is the index of the token in the analyzed text, Start and End are its byte offsets
+type Token struct {
+	Term     string `json:"term"`
+	Position int    `json:"position"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// Analyzer turns text into the tokens stored in and looked up from the index
+type Analyzer interface {
+	Analyze(text string) []Token
+}
+
+// Tokenizer splits text into tokens
+type Tokenizer interface {
+	Tokenize(text string) []Token
+}
+
+// TokenFilter transforms, removes or adds tokens produced by a Tokenizer
+type TokenFilter interface {
+	Filter(tokens []Token) []Token
+}
+
+// TokenizerFunc adapts a function to the Tokenizer interface
+type TokenizerFunc func(text string) []Token
+
+func (fn TokenizerFunc) Tokenize(text string) []Token {
+	return fn(text)
+}
+
+// TokenFilterFunc adapts a function to the TokenFilter interface
+type TokenFilterFunc func(tokens []Token) []Token
+
+func (fn TokenFilterFunc) Filter(tokens []Token) []Token {
+	return fn(tokens)
+}
+
+// TermFilter returns a TokenFilter applying fn to every term, tokens mapped to an empty term are dropped
+func TermFilter(fn func(term string) string) TokenFilter {
+	return TokenFilterFunc(func(tokens []Token) []Token {
+		filtered := tokens[:0]
+		for _, token := range tokens {
+			if token.Term = fn(token.Term); token.Term != "" {
+				filtered = append(filtered, token)
+			}
+		}
+		return filtered
+	})
+}
+
+// pipeline is an Analyzer running a tokenizer followed by a chain of filters
+type pipeline struct {
+	tokenizer Tokenizer
+	filters   []TokenFilter
+}
+
+// NewAnalyzer returns an Analyzer running tokenizer and then each filter in order
+func NewAnalyzer(tokenizer Tokenizer, filters ...TokenFilter) Analyzer {
+	return &pipeline{tokenizer: tokenizer, filters: filters}
+}
+
+func (p *pipeline) Analyze(text string) []Token {
+	tokens := p.tokenizer.Tokenize(text)
+	for _, filter := range p.filters {
+		tokens = filter.Filter(tokens)
+	}
+	return tokens
+}
+
+// StandardTokenizer splits text on whitespace and strips punctuation from each word, so "E11.9" becomes "E119"
+var StandardTokenizer Tokenizer = TokenizerFunc(standardTokenize)
+
+func standardTokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+	emit := func(end int) {
+		word := text[start:end]
+		// offsets exclude leading and trailing punctuation so highlights wrap the word only
+		first := strings.IndexFunc(word, isWordRune)
+		if first < 0 {
+			return
+		}
+		last := strings.LastIndexFunc(word, isWordRune)
+		_, size := utf8.DecodeRuneInString(word[last:])
+		tokens = append(tokens, Token{
+			Term:     strings.Map(keepWordRune, word),
+			Position: len(tokens),
+			Start:    start + first,
+			End:      start + last + size,
+		})
+	}
+	for i, char := range text {
+		if unicode.IsSpace(char) {
+			if start >= 0 {
+				emit(i)
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		emit(len(text))
+	}
+	return tokens
+}
+
+func isWordRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_'
+}
+
+func keepWordRune(char rune) rune {
+	if isWordRune(char) {
+		return char
+	}
+	return -1
+}
+
+// LowercaseFilter lowercases every term
+func LowercaseFilter() TokenFilter {
+	return TermFilter(strings.ToLower)
+}
+
+// ASCIIFoldingFilter replaces accented and special latin letters by their ascii equivalent, e.g. "niño" becomes "nino"
+func ASCIIFoldingFilter() TokenFilter {
+	return TermFilter(foldASCII)
+}
+
+// foldings are letters without a canonical decomposition into an ascii letter and combining marks
+var foldings = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'ø': "o", 'Ø': "O",
+	'đ': "d", 'Đ': "D", 'ð': "d", 'Ð': "D", 'þ': "th", 'Þ': "TH", 'ł': "l", 'Ł': "L", 'ı': "i",
+}
+
+func foldASCII(term string) string {
+	ascii := true
+	for i := 0; i < len(term); i++ {
+		if term[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return term
+	}
+	var sb strings.Builder
+	for _, char := range norm.NFD.String(term) {
+		if unicode.Is(unicode.Mn, char) {
+			continue
+		}
+		if folded, ok := foldings[char]; ok {
+			sb.WriteString(folded)
+		} else {
+			sb.WriteRune(char)
+		}
+	}
+	return sb.String()
+}
+
+// StopWordsFilter removes the given words, terms are compared as they are so place it after LowercaseFilter
+func StopWordsFilter(words ...string) TokenFilter {
+	list := make(map[string]bool, len(words))
+	for _, word := range words {
+		list[word] = true
+	}
+	return stopFilter(list)
+}
+
+// LanguageStopWordsFilter removes the stop words of an ISO 639-1 language, unknown languages remove nothing
+func LanguageStopWordsFilter(language string) TokenFilter {
+	return stopFilter(stopWordLists[language])
+}
+
+func stopFilter(list map[string]bool) TokenFilter {
+	return TermFilter(func(term string) string {
+		if list[term] {
+			return ""
+		}
+		return term
+	})
+}
+
+// StemmerFilter reduces terms to their stem for an ISO 639-1 language, terms are kept as they are for languages without a stemmer
+func StemmerFilter(language string) TokenFilter {
+	stem, ok := stemmers[language]
+	if !ok {
+		return TokenFilterFunc(func(tokens []Token) []Token { return tokens })
+	}
+	return TermFilter(stem)
+}
+
+// stemmers are the available stemmers by ISO 639-1 language code
+var stemmers = map[string]func(string) string{
+	"en": stemEnglish,
+	"es": stemSpanish,
+	"de": stemGerman,
+}
+
+// DefaultAnalyzer lowercases words and removes english stop words without stemming
+func DefaultAnalyzer() Analyzer {
+	return NewAnalyzer(StandardTokenizer, LowercaseFilter(), LanguageStopWordsFilter("en"))
+}
+
+// LanguageAnalyzer returns an analyzer removing stop words, stemming and folding accents for an ISO 639-1 language
+// Languages without stop words or stemmer fall back to DefaultAnalyzer
+func LanguageAnalyzer(language string) Analyzer {
+	_, hasStopWords := stopWordLists[language]
+	_, hasStemmer := stemmers[language]
+	if !hasStopWords && !hasStemmer {
+		return DefaultAnalyzer()
+	}
+	// stemmers rely on accents so folding runs last
+	return NewAnalyzer(StandardTokenizer,
+		LowercaseFilter(),
+		LanguageStopWordsFilter(language),
+		StemmerFilter(language),
+		ASCIIFoldingFilter(),
+	)
+}
+
+// autoAnalyzer picks a language analyzer for every text it analyzes
+type autoAnalyzer struct {
+	fallback  Analyzer
+	analyzers map[string]Analyzer
+}
+
+// AutoAnalyzer guesses the language of each text with lang.Guess and analyzes it with the matching LanguageAnalyzer
+// Texts whose language can't be guessed, such as most short queries, use fallback or the english LanguageAnalyzer when nil
+// The fallback should match the dominant language of the documents so queries stem like the documents they look for
+func AutoAnalyzer(fallback Analyzer) Analyzer {
+	if fallback == nil {
+		fallback = LanguageAnalyzer("en")
+	}
+	analyzers := make(map[string]Analyzer, len(stopWordLists))
+	for language := range stopWordLists {
+		analyzers[language] = LanguageAnalyzer(language)
+	}
+	return &autoAnalyzer{fallback: fallback, analyzers: analyzers}
+}
+
+func (a *autoAnalyzer) Analyze(text string) []Token {
+	if language, err := lang.Guess(text); err == nil {
+		if analyzer, ok := a.analyzers[language]; ok {
+			return analyzer.Analyze(text)
+		}
+	}
+	return a.fallback.Analyze(text)
+}
+
+// defaultAnalyzer is used by Tokenize and by instances configured without an analyzer
+var defaultAnalyzer = DefaultAnalyzer()
+
+// terms returns the terms of tokens in order
+func terms(tokens []Token) []string {
+	result := make([]string, len(tokens))
+	for i, token := range tokens {
+		result[i] = token.Term
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package fts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStandardTokenizer(t *testing.T) {
+	got := DefaultAnalyzer().Analyze("The Quick, brown FOX! E11.9 (x)")
+	want := []Token{
+		{Term: "quick", Position: 1, Start: 4, End: 9},
+		{Term: "brown", Position: 2, Start: 11, End: 16},
+		{Term: "fox", Position: 3, Start: 17, End: 20},
+		{Term: "e119", Position: 4, Start: 22, End: 27},
+		{Term: "x", Position: 5, Start: 29, End: 30},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Analyze = %v, want %v", got, want)
+	}
+}
+
+func TestStemmers(t *testing.T) {
+	tests := []struct {
+		language string
+		words    map[string]string
+	}{
+		{language: "en", words: map[string]string{
+			"running": "run", "runs": "run", "connection": "connect", "connected": "connect",
+			"ponies": "poni", "caresses": "caress", "relational": "relat", "agreed": "agre", "sky": "sky",
+		}},
+		{language: "es", words: map[string]string{
+			"canciones": "cancion", "corriendo": "corr", "bibliotecas": "bibliotec", "rápidamente": "rapid",
+		}},
+		{language: "de", words: map[string]string{
+			"häuser": "haus", "katzen": "katz", "laufen": "lauf", "kinder": "kind",
+		}},
+	}
+	for _, test := range tests {
+		stem := stemmers[test.language]
+		for word, want := range test.words {
+			if got := stem(word); got != want {
+				t.Errorf("%s stemmer(%q) = %q, want %q", test.language, word, got, want)
+			}
+		}
+	}
+}
+
+func TestTokenFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		analyzer Analyzer
+		text     string
+		want     []string
+	}{
+		{name: "folding", analyzer: NewAnalyzer(StandardTokenizer, ASCIIFoldingFilter()), text: "niño Straße Øre café", want: []string{"nino", "Strasse", "Ore", "cafe"}},
+		{name: "stop words", analyzer: NewAnalyzer(StandardTokenizer, LowercaseFilter(), StopWordsFilter("foo", "bar")), text: "Foo baz BAR qux", want: []string{"baz", "qux"}},
+		{name: "unknown stemmer", analyzer: NewAnalyzer(StandardTokenizer, StemmerFilter("xx")), text: "running", want: []string{"running"}},
+		{name: "term filter", analyzer: NewAnalyzer(StandardTokenizer, TermFilter(func(term string) string {
+			if len(term) < 3 {
+				return ""
+			}
+			return term
+		})), text: "a bc def", want: []string{"def"}},
+		{name: "spanish", analyzer: LanguageAnalyzer("es"), text: "Los niños cantaban canciones", want: []string{"nin", "cant", "cancion"}},
+		{name: "german", analyzer: LanguageAnalyzer("de"), text: "Die Häuser der Straße", want: []string{"haus", "strass"}},
+		{name: "unknown language", analyzer: LanguageAnalyzer("xx"), text: "The running dogs", want: []string{"running", "dogs"}},
+		{name: "auto", analyzer: AutoAnalyzer(nil), text: "Los niños cantaban canciones en la escuela todos los días", want: []string{"nin", "cant", "cancion", "escuel", "dias"}},
+		{name: "auto fallback", analyzer: AutoAnalyzer(nil), text: "running", want: []string{"run"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := terms(test.analyzer.Analyze(test.text)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Analyze(%q) = %v, want %v", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchWithLanguageAnalyzer(t *testing.T) {
+	db := NewWithConfig[map[string]any]("test", Config{Analyzer: LanguageAnalyzer("en")})
+	for _, text := range []string{"running shoes", "the runner ran", "connected devices"} {
+		if _, err := db.Insert(map[string]any{"text": text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "runs", want: []string{"running shoes"}},
+		{query: "connection", want: []string{"connected devices"}},
+		{query: "shoe", want: []string{"running shoes"}},
+	}
+	for _, test := range tests {
+		if got := hitTexts(db.Search(test.query, Option{Exact: true, Size: 10})); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Search(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
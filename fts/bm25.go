@@ -0,0 +1,26 @@
+package fts
+
+import "math"
+
+// BM25 tuning parameters
+const (
+	// k1 controls how quickly repeated terms saturate the score
+	k1 = 1.2
+
+	// b controls how strongly the score is normalized by document length
+	b = 0.75
+)
+
+// inverseDocumentFrequency returns the BM25 idf of a term found in docsWithTerm out of totalDocs documents
+func inverseDocumentFrequency(totalDocs, docsWithTerm float64) float64 {
+	return math.Log(1 + (totalDocs-docsWithTerm+0.5)/(docsWithTerm+0.5))
+}
+
+// termScore returns the BM25 term frequency component for a document of the given length
+func termScore(frequency, length, avgLength float64) float64 {
+	norm := 1.0
+	if avgLength > 0 {
+		norm = 1 - b + b*length/avgLength
+	}
+	return frequency * (k1 + 1) / (frequency + k1*norm)
+}
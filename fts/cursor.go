@@ -0,0 +1,44 @@
+package fts
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var ErrInvalidCursor = errors.New("fts: invalid cursor")
+
+// cursor is the rank of a hit, hits are ordered by descending score and then by ascending id
+type cursor struct {
+	score float64
+	id    int64
+}
+
+// before reports whether c ranks before other
+func (c cursor) before(other cursor) bool {
+	if c.score != other.score {
+		return c.score > other.score
+	}
+	return c.id < other.id
+}
+
+// encode returns the cursor as an opaque url safe string
+func (c cursor) encode() string {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], math.Float64bits(c.score))
+	binary.BigEndian.PutUint64(buf[8:], uint64(c.id))
+	return base64.RawURLEncoding.EncodeToString(buf[:])
+}
+
+func decodeCursor(s string) (cursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(buf) != 16 {
+		return cursor{}, ErrInvalidCursor
+	}
+	score := math.Float64frombits(binary.BigEndian.Uint64(buf[:8]))
+	if math.IsNaN(score) {
+		return cursor{}, ErrInvalidCursor
+	}
+	return cursor{score: score, id: int64(binary.BigEndian.Uint64(buf[8:]))}, nil
+}
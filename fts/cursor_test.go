@@ -0,0 +1,128 @@
+package fts
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestCursorEncoding(t *testing.T) {
+	for _, c := range []cursor{{}, {score: 1.5, id: 42}, {score: -2, id: -7}, {score: math.Inf(1), id: math.MaxInt64}} {
+		got, err := decodeCursor(c.encode())
+		if err != nil || got != c {
+			t.Errorf("decodeCursor(encode(%v)) = %v, %v", c, got, err)
+		}
+	}
+	nan := cursor{score: math.NaN()}.encode()
+	for _, s := range []string{"", "not a cursor", "AAAA", nan} {
+		if _, err := decodeCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) returned %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestQueryCursorPagination(t *testing.T) {
+	db := New[map[string]any]("test")
+	for i := 0; i < 25; i++ {
+		// every third document repeats the term so pages mix distinct scores and ties
+		text := "golang"
+		if i%3 == 0 {
+			text = "golang golang"
+		}
+		if _, err := db.Insert(map[string]any{"text": fmt.Sprintf("%s doc%d", text, i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := db.Query("golang", Option{Exact: true, Size: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Hits) != 25 || all.Next != "" {
+		t.Fatalf("Query returned %d hits and cursor %q, want 25 hits and no cursor", len(all.Hits), all.Next)
+	}
+
+	for _, size := range []int{1, 4, 10, 25} {
+		var paged []Record[map[string]any]
+		after := ""
+		for pages := 0; ; pages++ {
+			if pages > 25 {
+				t.Fatalf("Size %d did not reach the last page", size)
+			}
+			page, err := db.Query("golang", Option{Exact: true, Size: size, After: after})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != 25 {
+				t.Errorf("page Total = %d, want 25", page.Total)
+			}
+			paged = append(paged, page.Hits...)
+			if page.Next == "" {
+				break
+			}
+			after = page.Next
+		}
+		if len(paged) != len(all.Hits) {
+			t.Fatalf("paging with Size %d returned %d hits, want %d", size, len(paged), len(all.Hits))
+		}
+		for i := range paged {
+			if paged[i].Id != all.Hits[i].Id {
+				t.Fatalf("paging with Size %d returned %v at %d, want %v", size, paged[i].S, i, all.Hits[i].S)
+			}
+		}
+	}
+
+	// the same cursor returns the same page
+	first, _ := db.Query("golang", Option{Exact: true, Size: 5})
+	second, _ := db.Query("golang", Option{Exact: true, Size: 5, After: first.Next})
+	again, _ := db.Query("golang", Option{Exact: true, Size: 5, After: first.Next})
+	for i := range second.Hits {
+		if second.Hits[i].Id != again.Hits[i].Id {
+			t.Fatalf("a cursor returned different pages")
+		}
+	}
+}
+
+func TestQueryOffset(t *testing.T) {
+	db := newTestIndex(t, "apple", "apple apple", "apple apple apple", "apple pie", "apple tart")
+	all, _ := db.Query("apple", Option{Exact: true, Size: 10})
+	tests := []struct {
+		option Option
+		want   []Record[map[string]any]
+	}{
+		{option: Option{Exact: true, Size: 2, Offset: 1}, want: all.Hits[1:3]},
+		{option: Option{Exact: true, Size: 10, Offset: 3}, want: all.Hits[3:]},
+		{option: Option{Exact: true, Size: 10, Offset: 9}, want: nil},
+	}
+	for _, test := range tests {
+		result, err := db.Query("apple", test.option)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Hits) != len(test.want) {
+			t.Fatalf("Query with %+v returned %d hits, want %d", test.option, len(result.Hits), len(test.want))
+		}
+		for i := range test.want {
+			if result.Hits[i].Id != test.want[i].Id {
+				t.Errorf("Query with %+v returned %v at %d, want %v", test.option, result.Hits[i].S, i, test.want[i].S)
+			}
+		}
+	}
+
+	// Offset is applied after the cursor
+	first, _ := db.Query("apple", Option{Exact: true, Size: 1})
+	result, _ := db.Query("apple", Option{Exact: true, Size: 1, After: first.Next, Offset: 1})
+	if len(result.Hits) != 1 || result.Hits[0].Id != all.Hits[2].Id {
+		t.Errorf("Query with After and Offset returned %v, want %v", result.Hits, all.Hits[2].S)
+	}
+}
+
+func TestQueryInvalidCursor(t *testing.T) {
+	db := newTestIndex(t, "apple")
+	if _, err := db.Query("apple", Option{Size: 10, After: "bogus"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Query with an invalid cursor returned %v, want ErrInvalidCursor", err)
+	}
+	if hits := db.Search("apple", Option{Size: 10, After: "bogus"}); len(hits) != 0 {
+		t.Errorf("Search with an invalid cursor returned %d hits", len(hits))
+	}
+}
@@ -0,0 +1,187 @@
+package fts
+
+import (
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+// AutoFuzziness picks the allowed edit distance from the length of each query term
+const AutoFuzziness = -1
+
+// maxExpansions bounds how many dictionary terms a single prefix or fuzzy term may expand to
+const maxExpansions = 64
+
+// dictionary is a trie of every token in the index used for prefix and fuzzy lookups
+type dictionary struct {
+	mu   sync.RWMutex
+	root *trieNode
+}
+
+// trieNode is a node of the dictionary trie, children are kept sorted by rune so walks are deterministic
+type trieNode struct {
+	char     rune
+	term     bool
+	children []*trieNode
+}
+
+// expansion is a dictionary term matched by a query term along with its edit distance
+type expansion struct {
+	token    string
+	distance int
+}
+
+func newDictionary() *dictionary {
+	return &dictionary{root: &trieNode{}}
+}
+
+// add inserts token into the dictionary
+func (d *dictionary) add(token string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	node := d.root
+	for _, char := range token {
+		node = node.child(char, true)
+	}
+	node.term = true
+}
+
+// remove deletes token from the dictionary and prunes the nodes left without terms
+func (d *dictionary) remove(token string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	path := []*trieNode{d.root}
+	node := d.root
+	for _, char := range token {
+		if node = node.child(char, false); node == nil {
+			return
+		}
+		path = append(path, node)
+	}
+	node.term = false
+	for i := len(path) - 1; i > 0 && !path[i].term && len(path[i].children) == 0; i-- {
+		path[i-1].removeChild(path[i].char)
+	}
+}
+
+// child returns the child of node for char, creating it when create is set
+func (node *trieNode) child(char rune, create bool) *trieNode {
+	i := sort.Search(len(node.children), func(i int) bool { return node.children[i].char >= char })
+	if i < len(node.children) && node.children[i].char == char {
+		return node.children[i]
+	}
+	if !create {
+		return nil
+	}
+	child := &trieNode{char: char}
+	node.children = append(node.children, nil)
+	copy(node.children[i+1:], node.children[i:])
+	node.children[i] = child
+	return child
+}
+
+// removeChild removes the child of node for char
+func (node *trieNode) removeChild(char rune) {
+	i := sort.Search(len(node.children), func(i int) bool { return node.children[i].char >= char })
+	if i < len(node.children) && node.children[i].char == char {
+		node.children = append(node.children[:i], node.children[i+1:]...)
+	}
+}
+
+// prefix returns up to limit terms starting with prefix in lexical order
+func (d *dictionary) prefix(prefix string, limit int) []expansion {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	node := d.root
+	for _, char := range prefix {
+		if node = node.child(char, false); node == nil {
+			return nil
+		}
+	}
+	var matches []expansion
+	var walk func(node *trieNode, term []rune)
+	walk = func(node *trieNode, term []rune) {
+		if len(matches) >= limit {
+			return
+		}
+		if node.term {
+			matches = append(matches, expansion{token: string(term), distance: len(term) - utf8.RuneCountInString(prefix)})
+		}
+		for _, child := range node.children {
+			walk(child, append(term, child.char))
+		}
+	}
+	walk(node, []rune(prefix))
+	return matches
+}
+
+// fuzzy returns up to limit terms within maxDistance Levenshtein edits of term, closest first
+func (d *dictionary) fuzzy(term string, maxDistance int, limit int) []expansion {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	target := []rune(term)
+	row := make([]int, len(target)+1)
+	for i := range row {
+		row[i] = i
+	}
+	var matches []expansion
+	var walk func(node *trieNode, prev []int, word []rune)
+	walk = func(node *trieNode, prev []int, word []rune) {
+		// each trie level computes one row of the edit distance matrix against the target
+		row := make([]int, len(target)+1)
+		row[0] = prev[0] + 1
+		best := row[0]
+		for i := 1; i <= len(target); i++ {
+			cost := 1
+			if target[i-1] == node.char {
+				cost = 0
+			}
+			row[i] = minimum(row[i-1]+1, prev[i]+1, prev[i-1]+cost)
+			if row[i] < best {
+				best = row[i]
+			}
+		}
+		if node.term && row[len(target)] <= maxDistance {
+			matches = append(matches, expansion{token: string(word), distance: row[len(target)]})
+		}
+		if best > maxDistance {
+			return
+		}
+		for _, child := range node.children {
+			walk(child, row, append(word, child.char))
+		}
+	}
+	for _, child := range d.root.children {
+		walk(child, row, []rune{child.char})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// fuzziness returns the edit distance allowed for term
+func fuzziness(term string, fuzziness int) int {
+	if fuzziness != AutoFuzziness {
+		return fuzziness
+	}
+	switch length := utf8.RuneCountInString(term); {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func minimum(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
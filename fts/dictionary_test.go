@@ -0,0 +1,161 @@
+package fts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestDictionary(tokens ...string) *dictionary {
+	d := newDictionary()
+	for _, token := range tokens {
+		d.add(token)
+	}
+	return d
+}
+
+func expansionTokens(expansions []expansion) []string {
+	tokens := make([]string, 0, len(expansions))
+	for _, exp := range expansions {
+		tokens = append(tokens, exp.token)
+	}
+	return tokens
+}
+
+func TestDictionaryPrefix(t *testing.T) {
+	d := newTestDictionary("cart", "dog", "car", "carbon", "cat")
+	tests := []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{prefix: "car", limit: 10, want: []string{"car", "carbon", "cart"}},
+		{prefix: "car", limit: 2, want: []string{"car", "carbon"}},
+		{prefix: "ca", limit: 10, want: []string{"car", "carbon", "cart", "cat"}},
+		{prefix: "x", limit: 10, want: []string{}},
+	}
+	for _, test := range tests {
+		if got := expansionTokens(d.prefix(test.prefix, test.limit)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("prefix(%q, %d) = %v, want %v", test.prefix, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestDictionaryFuzzy(t *testing.T) {
+	d := newTestDictionary("cart", "dog", "car", "carbon", "cat")
+	tests := []struct {
+		term     string
+		distance int
+		limit    int
+		want     []expansion
+	}{
+		{term: "cart", distance: 0, limit: 10, want: []expansion{{token: "cart"}}},
+		{term: "cart", distance: 1, limit: 10, want: []expansion{{token: "cart"}, {token: "car", distance: 1}, {token: "cat", distance: 1}}},
+		{term: "cart", distance: 1, limit: 2, want: []expansion{{token: "cart"}, {token: "car", distance: 1}}},
+		{term: "crabon", distance: 2, limit: 10, want: []expansion{{token: "carbon", distance: 2}}},
+		{term: "bird", distance: 1, limit: 10, want: nil},
+	}
+	for _, test := range tests {
+		if got := d.fuzzy(test.term, test.distance, test.limit); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("fuzzy(%q, %d, %d) = %v, want %v", test.term, test.distance, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestFuzziness(t *testing.T) {
+	tests := []struct {
+		term      string
+		fuzziness int
+		want      int
+	}{
+		{term: "ab", fuzziness: AutoFuzziness, want: 0},
+		{term: "abcde", fuzziness: AutoFuzziness, want: 1},
+		{term: "abcdef", fuzziness: AutoFuzziness, want: 2},
+		{term: "ab", fuzziness: 1, want: 1},
+	}
+	for _, test := range tests {
+		if got := fuzziness(test.term, test.fuzziness); got != test.want {
+			t.Errorf("fuzziness(%q, %d) = %d, want %d", test.term, test.fuzziness, got, test.want)
+		}
+	}
+}
+
+func TestDictionaryRemove(t *testing.T) {
+	d := newTestDictionary("car", "cart", "cat")
+	d.remove("ca")
+	if got := expansionTokens(d.prefix("ca", 10)); !reflect.DeepEqual(got, []string{"car", "cart", "cat"}) {
+		t.Fatalf("removing a prefix that is not a term changed the dictionary to %v", got)
+	}
+	d.remove("car")
+	if got := expansionTokens(d.prefix("ca", 10)); !reflect.DeepEqual(got, []string{"cart", "cat"}) {
+		t.Fatalf("prefix after removing car = %v", got)
+	}
+	d.remove("cart")
+	d.remove("cat")
+	if len(d.root.children) != 0 {
+		t.Errorf("removing every term left %d nodes under the root", len(d.root.children))
+	}
+}
+
+func TestSearchPrefixAndFuzzy(t *testing.T) {
+	db := newTestIndex(t, "diabetes mellitus", "diagnosis", "color", "colour")
+	tests := []struct {
+		query  string
+		option Option
+		want   int
+	}{
+		{query: "diab", option: Option{Exact: true, Size: 10}, want: 0},
+		{query: "diab", option: Option{Exact: true, Size: 10, Prefix: true}, want: 1},
+		{query: "dia", option: Option{Exact: true, Size: 10, Prefix: true}, want: 2},
+		{query: "diabetis", option: Option{Exact: true, Size: 10}, want: 0},
+		{query: "diabetis", option: Option{Exact: true, Size: 10, Fuzziness: 1}, want: 1},
+		{query: "diabetis", option: Option{Exact: true, Size: 10, Fuzziness: AutoFuzziness}, want: 1},
+		{query: "diabetis mellitus", option: Option{Exact: true, Size: 10, Fuzziness: AutoFuzziness}, want: 1},
+	}
+	for _, test := range tests {
+		if hits := db.Search(test.query, test.option); len(hits) != test.want {
+			t.Errorf("Search(%q, %+v) returned %d hits, want %d", test.query, test.option, len(hits), test.want)
+		}
+	}
+
+	hits := db.Search("color", Option{Exact: true, Size: 10, Fuzziness: 1})
+	if len(hits) != 2 || hits[0].S["text"] != "color" {
+		t.Fatalf("Search(color) with fuzziness returned %v, want the exact match first", hitTexts(hits))
+	}
+	if hits[0].Score <= hits[1].Score {
+		t.Errorf("exact match scored %v, not above the fuzzy match %v", hits[0].Score, hits[1].Score)
+	}
+}
+
+func TestDeletePrunesIndex(t *testing.T) {
+	db := New[map[string]any]("test")
+	first, err := db.Insert(map[string]any{"text": "alpha beta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := db.Insert(map[string]any{"text": "beta gamma"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(first.Id); err != nil {
+		t.Fatal(err)
+	}
+	if n := db.IndexLen(); n != 2 {
+		t.Errorf("IndexLen after delete = %d, want 2", n)
+	}
+	if got := db.terms.prefix("al", 10); len(got) != 0 {
+		t.Errorf("dictionary still holds %v after its only document was deleted", expansionTokens(got))
+	}
+	if hits := db.Search("alp", Option{Exact: true, Size: 10, Prefix: true}); len(hits) != 0 {
+		t.Errorf("Search(alp) returned %d hits after delete", len(hits))
+	}
+
+	if _, err := db.Update(second.Id, map[string]any{"text": "delta"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := db.IndexLen(); n != 1 {
+		t.Errorf("IndexLen after update = %d, want 1", n)
+	}
+	if got := expansionTokens(db.terms.prefix("", 10)); !reflect.DeepEqual(got, []string{"delta"}) {
+		t.Errorf("dictionary after update = %v, want [delta]", got)
+	}
+}
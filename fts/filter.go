@@ -0,0 +1,147 @@
+package fts
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/goccy/go-reflect"
+	"github.com/sujit-baniya/pkg/rule"
+)
+
+// FacetCount is the number of matching documents having Value in a facet field
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// normalizeFilters copies filters with their field names normalized like the indexed fields
+func normalizeFilters(filters []*rule.Condition) []*rule.Condition {
+	normalized := make([]*rule.Condition, 0, len(filters))
+	for _, filter := range filters {
+		if filter != nil {
+			normalized = append(normalized, rule.NewCondition(fieldName(filter.Field), filter.Operator, filter.Value))
+		}
+	}
+	return normalized
+}
+
+// matchFilters reports whether fields satisfy every filter
+func matchFilters(fields map[string]any, filters []*rule.Condition) bool {
+	for _, filter := range filters {
+		if !filter.Validate(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// documentFields returns the fields of a document by normalized name
+// Integers and floats are widened to int and float64 which are the types rule.Condition compares
+func documentFields(doc any) map[string]any {
+	fields := make(map[string]any)
+	switch v := doc.(type) {
+	case map[string]any:
+		for field, val := range v {
+			fields[fieldName(field)] = filterValue(reflect.ValueOf(val))
+		}
+		return fields
+	}
+	val := reflect.Indirect(reflect.ValueOf(doc))
+	if val.Kind() != reflect.Struct {
+		fields[defaultField] = filterValue(val)
+		return fields
+	}
+	t := val.Type()
+	for i := 0; i < val.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" {
+			fields[structFieldName(f)] = filterValue(val.Field(i))
+		}
+	}
+	return fields
+}
+
+func filterValue(val reflect.Value) any {
+	switch val.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		return val.Float()
+	case reflect.String:
+		return val.String()
+	}
+	if !val.CanInterface() {
+		return nil
+	}
+	return val.Interface()
+}
+
+// facetCounter counts the values of facet fields
+type facetCounter struct {
+	fields []string
+	values map[string]map[string]int
+}
+
+// newFacetCounter returns a counter for fields or nil when there is no facet to count
+func newFacetCounter(fields []string) *facetCounter {
+	if len(fields) == 0 {
+		return nil
+	}
+	counter := &facetCounter{values: make(map[string]map[string]int, len(fields))}
+	for _, field := range fields {
+		field = fieldName(field)
+		counter.fields = append(counter.fields, field)
+		counter.values[field] = make(map[string]int)
+	}
+	return counter
+}
+
+// add counts the facet values of a document, every element of a slice value is counted
+func (c *facetCounter) add(fields map[string]any) {
+	if c == nil {
+		return
+	}
+	for _, field := range c.fields {
+		val, ok := fields[field]
+		if !ok || val == nil {
+			continue
+		}
+		rv := reflect.ValueOf(val)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			seen := make(map[string]bool, rv.Len())
+			for i := 0; i < rv.Len(); i++ {
+				if value := fmt.Sprintf("%v", rv.Index(i).Interface()); !seen[value] {
+					seen[value] = true
+					c.values[field][value]++
+				}
+			}
+			continue
+		}
+		c.values[field][fmt.Sprintf("%v", val)]++
+	}
+}
+
+// counts returns the facet values of each field from the most to the least frequent
+func (c *facetCounter) counts() map[string][]FacetCount {
+	if c == nil {
+		return nil
+	}
+	facets := make(map[string][]FacetCount, len(c.fields))
+	for _, field := range c.fields {
+		counts := make([]FacetCount, 0, len(c.values[field]))
+		for value, count := range c.values[field] {
+			counts = append(counts, FacetCount{Value: value, Count: count})
+		}
+		sort.Slice(counts, func(i, j int) bool {
+			if counts[i].Count != counts[j].Count {
+				return counts[i].Count > counts[j].Count
+			}
+			return counts[i].Value < counts[j].Value
+		})
+		facets[field] = counts
+	}
+	return facets
+}
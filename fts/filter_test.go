@@ -0,0 +1,116 @@
+package fts
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/sujit-baniya/pkg/rule"
+)
+
+type book struct {
+	Title string   `json:"title"`
+	Genre string   `json:"genre"`
+	Year  int      `json:"year"`
+	Tags  []string `json:"tags"`
+}
+
+func newTestLibrary(t *testing.T) *FTS[book] {
+	t.Helper()
+	db := New[book]("test", map[string]bool{"title": true})
+	for _, doc := range []book{
+		{Title: "learning golang", Genre: "programming", Year: 2021, Tags: []string{"go", "beginner"}},
+		{Title: "advanced golang", Genre: "programming", Year: 2023, Tags: []string{"go", "go", "expert"}},
+		{Title: "golang poems", Genre: "poetry", Year: 2019, Tags: []string{"go"}},
+		{Title: "rust in action", Genre: "programming", Year: 2022, Tags: []string{"rust"}},
+	} {
+		if _, err := db.Insert(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func titles(hits []Record[book]) []string {
+	result := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, hit.S.Title)
+	}
+	return result
+}
+
+func TestQueryFilters(t *testing.T) {
+	db := newTestLibrary(t)
+	tests := []struct {
+		name    string
+		query   string
+		filters []*rule.Condition
+		want    []string
+	}{
+		{name: "query and filter", query: "golang", filters: []*rule.Condition{rule.NewCondition("genre", rule.EQ, "programming")}, want: []string{"advanced golang", "learning golang"}},
+		{name: "field name case", query: "golang", filters: []*rule.Condition{rule.NewCondition("Genre", rule.EQ, "poetry")}, want: []string{"golang poems"}},
+		{name: "every filter", query: "golang", filters: []*rule.Condition{
+			rule.NewCondition("genre", rule.EQ, "programming"),
+			rule.NewCondition("year", rule.GTE, 2022),
+		}, want: []string{"advanced golang"}},
+		{name: "empty query", query: "", filters: []*rule.Condition{rule.NewCondition("genre", rule.EQ, "programming")}, want: []string{"advanced golang", "learning golang", "rust in action"}},
+		{name: "no match", query: "golang", filters: []*rule.Condition{rule.NewCondition("genre", rule.EQ, "cooking")}, want: []string{}},
+		{name: "nil filter", query: "rust", filters: []*rule.Condition{nil}, want: []string{"rust in action"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := db.Query(test.query, Option{Size: 10, Filters: test.filters})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := titles(result.Hits)
+			// documents matching only filters all score zero so compare them as a set
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Query(%q) = %v, want %v", test.query, got, test.want)
+			}
+			if result.Total != len(test.want) {
+				t.Errorf("Query(%q) Total = %d, want %d", test.query, result.Total, len(test.want))
+			}
+		})
+	}
+
+	// filters run before Size so a page is filled and Total counts every filtered match
+	result, err := db.Query("golang", Option{Size: 1, Filters: []*rule.Condition{rule.NewCondition("genre", rule.EQ, "programming")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hits) != 1 || result.Total != 2 || result.Hits[0].S.Genre != "programming" {
+		t.Errorf("Query with Size 1 returned %v with Total %d", titles(result.Hits), result.Total)
+	}
+}
+
+func TestQueryFacets(t *testing.T) {
+	db := newTestLibrary(t)
+	result, err := db.Query("golang", Option{Size: 1, Facets: []string{"Genre", "tags", "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]FacetCount{
+		"genre":   {{Value: "programming", Count: 2}, {Value: "poetry", Count: 1}},
+		"tags":    {{Value: "go", Count: 3}, {Value: "beginner", Count: 1}, {Value: "expert", Count: 1}},
+		"missing": {},
+	}
+	if !reflect.DeepEqual(result.Facets, want) {
+		t.Errorf("Facets = %v, want %v", result.Facets, want)
+	}
+	if len(result.Hits) != 1 || result.Total != 3 {
+		t.Errorf("facets changed the page: %d hits of %d", len(result.Hits), result.Total)
+	}
+
+	result, err = db.Query("golang", Option{Size: 10, Facets: []string{"genre"}, Filters: []*rule.Condition{rule.NewCondition("year", rule.GTE, 2021)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []FacetCount{{Value: "programming", Count: 2}}; !reflect.DeepEqual(result.Facets["genre"], want) {
+		t.Errorf("Facets of filtered hits = %v, want %v", result.Facets["genre"], want)
+	}
+	if result, _ := db.Query("golang"); result.Facets != nil {
+		t.Errorf("Query without facets returned %v", result.Facets)
+	}
+}
@@ -4,217 +4,102 @@ import (
 	"fmt"
 	"github.com/goccy/go-reflect"
 	"github.com/sujit-baniya/pkg/maps"
+	"github.com/sujit-baniya/pkg/rule"
 	"github.com/sujit-baniya/pkg/str"
 	"github.com/sujit-baniya/xid"
-	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var punctuationRegex = regexp.MustCompile(`[^\w|\s]`)
-
-var stopWords = map[string]bool{
-	"a":          true,
-	"about":      true,
-	"above":      true,
-	"after":      true,
-	"again":      true,
-	"against":    true,
-	"all":        true,
-	"am":         true,
-	"an":         true,
-	"and":        true,
-	"any":        true,
-	"are":        true,
-	"aren't":     true,
-	"as":         true,
-	"at":         true,
-	"be":         true,
-	"because":    true,
-	"been":       true,
-	"before":     true,
-	"being":      true,
-	"below":      true,
-	"between":    true,
-	"both":       true,
-	"but":        true,
-	"by":         true,
-	"can't":      true,
-	"cannot":     true,
-	"could":      true,
-	"couldn't":   true,
-	"did":        true,
-	"didn't":     true,
-	"do":         true,
-	"does":       true,
-	"doesn't":    true,
-	"doing":      true,
-	"don't":      true,
-	"down":       true,
-	"during":     true,
-	"each":       true,
-	"few":        true,
-	"for":        true,
-	"from":       true,
-	"further":    true,
-	"had":        true,
-	"hadn't":     true,
-	"has":        true,
-	"hasn't":     true,
-	"have":       true,
-	"haven't":    true,
-	"having":     true,
-	"he":         true,
-	"he'd":       true,
-	"he'll":      true,
-	"he's":       true,
-	"her":        true,
-	"here":       true,
-	"here's":     true,
-	"hers":       true,
-	"herself":    true,
-	"him":        true,
-	"himself":    true,
-	"his":        true,
-	"how":        true,
-	"how's":      true,
-	"i":          true,
-	"i'd":        true,
-	"i'll":       true,
-	"i'm":        true,
-	"i've":       true,
-	"if":         true,
-	"in":         true,
-	"into":       true,
-	"is":         true,
-	"isn't":      true,
-	"it":         true,
-	"it's":       true,
-	"its":        true,
-	"itself":     true,
-	"let's":      true,
-	"me":         true,
-	"more":       true,
-	"most":       true,
-	"mustn't":    true,
-	"my":         true,
-	"myself":     true,
-	"no":         true,
-	"nor":        true,
-	"not":        true,
-	"of":         true,
-	"off":        true,
-	"on":         true,
-	"once":       true,
-	"only":       true,
-	"or":         true,
-	"other":      true,
-	"ought":      true,
-	"our":        true,
-	"ours":       true,
-	"ourselves":  true,
-	"out":        true,
-	"over":       true,
-	"own":        true,
-	"same":       true,
-	"shan't":     true,
-	"she":        true,
-	"she'd":      true,
-	"she'll":     true,
-	"she's":      true,
-	"should":     true,
-	"shouldn't":  true,
-	"so":         true,
-	"some":       true,
-	"such":       true,
-	"than":       true,
-	"that":       true,
-	"that's":     true,
-	"the":        true,
-	"their":      true,
-	"theirs":     true,
-	"them":       true,
-	"themselves": true,
-	"then":       true,
-	"there":      true,
-	"there's":    true,
-	"these":      true,
-	"they":       true,
-	"they'd":     true,
-	"they'll":    true,
-	"they're":    true,
-	"they've":    true,
-	"this":       true,
-	"those":      true,
-	"through":    true,
-	"to":         true,
-	"too":        true,
-	"under":      true,
-	"until":      true,
-	"up":         true,
-	"very":       true,
-	"was":        true,
-	"wasn't":     true,
-	"we":         true,
-	"we'd":       true,
-	"we'll":      true,
-	"we're":      true,
-	"we've":      true,
-	"were":       true,
-	"weren't":    true,
-	"what":       true,
-	"what's":     true,
-	"when":       true,
-	"when's":     true,
-	"where":      true,
-	"where's":    true,
-	"which":      true,
-	"while":      true,
-	"who":        true,
-	"who's":      true,
-	"whom":       true,
-	"why":        true,
-	"why's":      true,
-	"with":       true,
-	"won't":      true,
-	"would":      true,
-	"wouldn't":   true,
-	"you":        true,
-	"you'd":      true,
-	"you'll":     true,
-	"you're":     true,
-	"you've":     true,
-	"your":       true,
-	"yours":      true,
-	"yourself":   true,
-	"yourselves": true,
-}
-
 type SchemaProps any
 
 type Record[Schema SchemaProps] struct {
-	Id int64  `json:"id"`
-	S  Schema `json:"data"`
+	Id         int64               `json:"id"`
+	S          Schema              `json:"data"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
 }
 
+// RecordInfo is the posting stored for a token in a document
 type RecordInfo struct {
-	recId int64
+	Frequency int         `json:"frequency"`
+	Fields    []FieldInfo `json:"fields"`
+}
+
+// FieldInfo holds the frequency, the positions and the byte offsets of a token within one field of a document
+type FieldInfo struct {
+	Name      string   `json:"name"`
+	Frequency int      `json:"frequency"`
+	Positions []int    `json:"positions"`
+	Offsets   []Offset `json:"offsets"`
+}
+
+// Offset is the byte range of a token in the text of a field
+type Offset struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }
 
 type Option struct {
+	// Exact requires every clause of the query to match, otherwise documents matching any clause are returned
 	Exact bool
 	Size  int
+	// Offset skips that many hits, it is applied after After
+	Offset int
+	// After is the Result.Next cursor of the previous page, only hits ranked after it are returned
+	After string
+	// Prefix also matches indexed tokens starting with a query term
+	Prefix bool
+	// Fuzziness is the maximum Levenshtein distance between a query term and the tokens it matches, use AutoFuzziness to scale it with the term length
+	Fuzziness int
+	// Filters must all be satisfied by the fields of a document for it to match, an empty query with filters matches every document
+	Filters []*rule.Condition
+	// Facets are the fields whose values are counted over every matching document
+	Facets []string
+	// Highlight returns the fragments of the matched fields with the matches wrapped in markers when set
+	Highlight *Highlight
+}
+
+// Result holds a page of search hits along with the total number of matches and the facet counts
+type Result[Schema SchemaProps] struct {
+	Hits   []Record[Schema]        `json:"hits"`
+	Total  int                     `json:"total"`
+	Facets map[string][]FacetCount `json:"facets,omitempty"`
+	// Next is the cursor to pass as Option.After to fetch the following page, it is empty on the last page
+	Next string `json:"next,omitempty"`
+}
+
+// Config configures which fields are indexed and how much a match in each field weighs
+type Config struct {
+	// Rules enables or disables indexing per field, all fields are indexed when empty
+	Rules map[string]bool
+	// Boosts multiplies the score of matches in a field, fields default to a boost of 1
+	Boosts map[string]float64
+	// Analyzer turns documents and queries into tokens, DefaultAnalyzer is used when nil
+	Analyzer Analyzer
 }
 
 type FTS[Schema SchemaProps] struct {
-	key   string
-	rules map[string]bool
-	docs  maps.IMap[int64, Schema]
-	index maps.IMap[string, maps.IMap[int64, RecordInfo]]
+	// mu guards replacing the maps below in Load and pruning emptied tokens in Update and Delete, the other methods hold it for reading
+	mu           sync.RWMutex
+	key          string
+	rules        map[string]bool
+	boosts       map[string]float64
+	analyzer     Analyzer
+	docs         maps.IMap[int64, Schema]
+	index        maps.IMap[string, maps.IMap[int64, RecordInfo]]
+	lengths      maps.IMap[int64, map[string]int]
+	fieldLengths maps.IMap[string, *atomic.Int64]
+	terms        *dictionary
 }
 
+// defaultField is the field name used for documents that are a single value rather than a struct or map
+const defaultField = "value"
+
 var defaultSize = 20
 
 func New[Schema SchemaProps](key string, rules ...map[string]bool) *FTS[Schema] {
@@ -222,15 +107,39 @@ func New[Schema SchemaProps](key string, rules ...map[string]bool) *FTS[Schema]
 	if len(rules) > 0 {
 		r = rules[0]
 	}
+	return NewWithConfig[Schema](key, Config{Rules: r})
+}
+
+// NewWithConfig returns a new FTS instance using the field rules and boosts from config
+func NewWithConfig[Schema SchemaProps](key string, config Config) *FTS[Schema] {
+	rules := make(map[string]bool, len(config.Rules))
+	for field, canIndex := range config.Rules {
+		rules[fieldName(field)] = canIndex
+	}
+	boosts := make(map[string]float64, len(config.Boosts))
+	for field, boost := range config.Boosts {
+		boosts[fieldName(field)] = boost
+	}
+	analyzer := config.Analyzer
+	if analyzer == nil {
+		analyzer = defaultAnalyzer
+	}
 	return &FTS[Schema]{
-		key:   key,
-		docs:  maps.New[int64, Schema](),
-		index: maps.New[string, maps.IMap[int64, RecordInfo]](),
-		rules: r,
+		key:          key,
+		analyzer:     analyzer,
+		docs:         maps.New[int64, Schema](),
+		index:        maps.New[string, maps.IMap[int64, RecordInfo]](),
+		lengths:      maps.New[int64, map[string]int](),
+		fieldLengths: maps.New[string, *atomic.Int64](),
+		terms:        newDictionary(),
+		rules:        rules,
+		boosts:       boosts,
 	}
 }
 
 func (db *FTS[Schema]) Insert(doc Schema) (Record[Schema], error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	id := xid.New()
 	db.docs.Set(id.Int64(), doc)
 	db.indexDocument(id.Int64(), doc)
@@ -238,10 +147,14 @@ func (db *FTS[Schema]) Insert(doc Schema) (Record[Schema], error) {
 }
 
 func (db *FTS[Schema]) IndexLen() uintptr {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	return db.index.Len()
 }
 
 func (db *FTS[Schema]) DocumentLen() uintptr {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	return db.docs.Len()
 }
 
@@ -293,6 +206,8 @@ func (db *FTS[Schema]) InsertBatchAsync(docs []Schema) []error {
 }
 
 func (db *FTS[Schema]) Update(id int64, doc Schema) (Record[Schema], error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
 	prevDoc, ok := db.docs.Get(id)
 	if !ok {
 		return Record[Schema]{}, fmt.Errorf("document not found")
@@ -304,6 +219,8 @@ func (db *FTS[Schema]) Update(id int64, doc Schema) (Record[Schema], error) {
 }
 
 func (db *FTS[Schema]) Delete(id int64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
 	doc, ok := db.docs.Get(id)
 	if !ok {
 		return fmt.Errorf("document not found")
@@ -313,33 +230,142 @@ func (db *FTS[Schema]) Delete(id int64) error {
 	return nil
 }
 
+// Search returns the documents matching the query ranked by their BM25 score
+// Documents with equal score are ordered by id so results are deterministic
+// The query supports "quoted phrases", -excluded terms, OR, parentheses and terms prefixed with a field name such as "code:E11"
+// Search returns no hits when Option.After is not a valid cursor, use Query to get the error
 func (db *FTS[Schema]) Search(query string, params ...Option) []Record[Schema] {
+	result, err := db.Query(query, params...)
+	if err != nil {
+		return make([]Record[Schema], 0)
+	}
+	return result.Hits
+}
+
+// Query searches like Search and also returns the total number of matches, the facet counts of Option.Facets and the cursor of the next page
+// Filters are applied before Size so a page is always filled when enough documents match
+// Pages are stable as long as the index is not modified between calls
+func (db *FTS[Schema]) Query(query string, params ...Option) (Result[Schema], error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	option := Option{Size: defaultSize, Exact: true}
 	if len(params) > 0 {
 		option = params[0]
 	}
-	recordsIds := make(map[int64]int)
-	records := make([]Record[Schema], 0)
-	tokens := Tokenize(query)
-	for _, token := range tokens {
-		infos, _ := db.index.Get(token)
-		infos.ForEach(func(id int64, info RecordInfo) bool {
-			recordsIds[id] += 1
-			return true
-		})
+	var after *cursor
+	if option.After != "" {
+		c, err := decodeCursor(option.After)
+		if err != nil {
+			return Result[Schema]{}, err
+		}
+		after = &c
+	}
+	var scores map[int64]float64
+	root := parseQuery(query, db.analyzer, option.Exact)
+	if root != nil {
+		scores = db.evaluate(root, option)
+	} else if len(option.Filters) > 0 {
+		scores = make(map[int64]float64)
+		for id := range db.docs.Keys() {
+			scores[id] = 0
+		}
 	}
-	i := 0
-	for id, tokensCount := range recordsIds {
-		if !option.Exact || tokensCount == len(tokens) {
-			if option.Size == 0 || (option.Size > 0 && i < option.Size) {
-				i++
-				doc, _ := db.docs.Get(id)
-				records = append(records, Record[Schema]{Id: id, S: doc})
+	filters := normalizeFilters(option.Filters)
+	facets := newFacetCounter(option.Facets)
+	records := make([]Record[Schema], 0, len(scores))
+	for id, score := range scores {
+		doc, ok := db.docs.Get(id)
+		if !ok {
+			continue
+		}
+		if len(filters) > 0 || facets != nil {
+			fields := documentFields(doc)
+			if !matchFilters(fields, filters) {
+				continue
+			}
+			facets.add(fields)
+		}
+		records = append(records, Record[Schema]{Id: id, S: doc, Score: score})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return cursor{score: records[i].Score, id: records[i].Id}.before(cursor{score: records[j].Score, id: records[j].Id})
+	})
+	result := Result[Schema]{Total: len(records), Facets: facets.counts()}
+	if after != nil {
+		records = records[sort.Search(len(records), func(i int) bool {
+			return after.before(cursor{score: records[i].Score, id: records[i].Id})
+		}):]
+	}
+	if option.Offset > 0 {
+		if option.Offset > len(records) {
+			option.Offset = len(records)
+		}
+		records = records[option.Offset:]
+	}
+	if option.Size > 0 && len(records) > option.Size {
+		records = records[:option.Size]
+		last := records[len(records)-1]
+		result.Next = cursor{score: last.Score, id: last.Id}.encode()
+	}
+	if option.Highlight != nil && root != nil {
+		db.highlight(records, root, option)
+	}
+	result.Hits = records
+	return result, nil
+}
+
+// scoreTerm returns the score of every document matching term
+// With prefix or fuzzy matching a document keeps the best score among the tokens the term expands to
+func (db *FTS[Schema]) scoreTerm(term queryTerm, option Option) map[int64]float64 {
+	totalDocs := float64(db.docs.Len())
+	scores := make(map[int64]float64)
+	for _, exp := range db.expand(term.token, option) {
+		infos, ok := db.index.Get(exp.token)
+		if !ok || infos.Len() == 0 {
+			continue
+		}
+		// matches on expanded tokens weigh less the further they are from the query term
+		weight := 1 / float64(1+exp.distance)
+		idf := inverseDocumentFrequency(totalDocs, float64(infos.Len()))
+		for id, info := range infos.All() {
+			lengths, _ := db.lengths.Get(id)
+			score, matched := 0.0, false
+			for _, field := range info.Fields {
+				if term.field != "" && term.field != field.Name {
+					continue
+				}
+				matched = true
+				score += db.boost(field.Name) * idf * termScore(float64(field.Frequency), float64(lengths[field.Name]), db.averageLength(field.Name))
+			}
+			if matched && weight*score >= scores[id] {
+				scores[id] = weight * score
 			}
 		}
 	}
+	return scores
+}
 
-	return records
+// expand returns the indexed tokens a query token matches given the prefix and fuzziness options
+func (db *FTS[Schema]) expand(token string, option Option) []expansion {
+	expansions := []expansion{{token: token}}
+	seen := map[string]bool{token: true}
+	if option.Prefix {
+		for _, exp := range db.terms.prefix(token, maxExpansions) {
+			if !seen[exp.token] {
+				seen[exp.token] = true
+				expansions = append(expansions, expansion{token: exp.token})
+			}
+		}
+	}
+	if distance := fuzziness(token, option.Fuzziness); distance > 0 {
+		for _, exp := range db.terms.fuzzy(token, distance, maxExpansions) {
+			if !seen[exp.token] {
+				seen[exp.token] = true
+				expansions = append(expansions, exp)
+			}
+		}
+	}
+	return expansions
 }
 
 func (db *FTS[Schema]) SearchExact(query string, size ...int) []Record[Schema] {
@@ -351,71 +377,183 @@ func (db *FTS[Schema]) SearchExact(query string, size ...int) []Record[Schema] {
 }
 
 func (db *FTS[Schema]) indexDocument(id int64, doc Schema) {
-	text := strings.Join(db.getIndexFields(doc), " ")
-	tokens := Tokenize(text)
-	tokensCount := Count(tokens)
+	postings := make(map[string]*RecordInfo)
+	lengths := make(map[string]int)
+	for _, field := range db.getIndexFields(doc) {
+		tokens := db.analyzer.Analyze(field.value)
+		lengths[field.name] += len(tokens)
+		for _, token := range tokens {
+			info, ok := postings[token.Term]
+			if !ok {
+				info = &RecordInfo{}
+				postings[token.Term] = info
+			}
+			// tokens of a field are analyzed together so the field is always the last one appended
+			if n := len(info.Fields); n == 0 || info.Fields[n-1].Name != field.name {
+				info.Fields = append(info.Fields, FieldInfo{Name: field.name})
+			}
+			fieldInfo := &info.Fields[len(info.Fields)-1]
+			fieldInfo.Frequency++
+			fieldInfo.Positions = append(fieldInfo.Positions, token.Position)
+			fieldInfo.Offsets = append(fieldInfo.Offsets, Offset{Start: token.Start, End: token.End})
+			info.Frequency++
+		}
+	}
 
-	for token, count := range tokensCount {
-		recordsInfos, _ := db.index.GetOrSet(token, maps.New[int64, RecordInfo]())
-		recordsInfos.Set(id, RecordInfo{count})
+	for token, info := range postings {
+		recordsInfos, loaded := db.index.GetOrCompute(token, func() maps.IMap[int64, RecordInfo] {
+			return maps.New[int64, RecordInfo]()
+		})
+		if !loaded {
+			db.terms.add(token)
+		}
+		recordsInfos.Set(id, *info)
+	}
+	db.lengths.Set(id, lengths)
+	for field, length := range lengths {
+		db.fieldLength(field).Add(int64(length))
 	}
 }
 
+// deIndexDocument removes the postings of doc and drops the tokens no other document contains from the index and the dictionary
 func (db *FTS[Schema]) deIndexDocument(id int64, doc Schema) {
-	text := strings.Join(db.getIndexFields(doc), " ")
-	tokens := Tokenize(text)
-
-	for _, token := range tokens {
-		if recordsInfos, ok := db.index.Get(token); ok {
-			recordsInfos.Del(id)
+	for _, field := range db.getIndexFields(doc) {
+		for _, token := range uniqueSlice(terms(db.analyzer.Analyze(field.value))) {
+			if recordsInfos, ok := db.index.Get(token); ok {
+				recordsInfos.Del(id)
+				if recordsInfos.Len() == 0 {
+					db.index.Del(token)
+					db.terms.remove(token)
+				}
+			}
 		}
 	}
+	if lengths, ok := db.lengths.GetAndDel(id); ok {
+		for field, length := range lengths {
+			db.fieldLength(field).Add(-int64(length))
+		}
+	}
+}
+
+// fieldLength returns the running total of tokens indexed for field
+func (db *FTS[Schema]) fieldLength(field string) *atomic.Int64 {
+	total, _ := db.fieldLengths.GetOrCompute(field, func() *atomic.Int64 {
+		return &atomic.Int64{}
+	})
+	return total
+}
+
+// averageLength returns the average number of tokens indexed for field per document
+func (db *FTS[Schema]) averageLength(field string) float64 {
+	count := db.lengths.Len()
+	if count == 0 {
+		return 0
+	}
+	total, ok := db.fieldLengths.Get(field)
+	if !ok {
+		return 0
+	}
+	return float64(total.Load()) / float64(count)
+}
+
+// boost returns the configured weight of field
+func (db *FTS[Schema]) boost(field string) float64 {
+	if boost, ok := db.boosts[field]; ok {
+		return boost
+	}
+	return 1
+}
+
+// canIndex reports whether field passes the configured rules
+func (db *FTS[Schema]) canIndex(field string) bool {
+	if len(db.rules) == 0 {
+		return true
+	}
+	canIndex, ok := db.rules[field]
+	return ok && canIndex
 }
 
-func (db *FTS[Schema]) getIndexFields(obj any) (fields []string) {
+// indexField is the text of a single document field
+type indexField struct {
+	name  string
+	value string
+}
+
+func (db *FTS[Schema]) getIndexFields(obj any) (fields []indexField) {
 	switch v := obj.(type) {
 	case string, bool, time.Time, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
-		fields = append(fields, fmt.Sprintf("%v", v))
+		fields = append(fields, indexField{name: defaultField, value: fmt.Sprintf("%v", v)})
 	case map[string]any:
-		rules := make(map[string]bool)
-		if db.rules != nil {
-			rules = db.rules
+		keys := make([]string, 0, len(v))
+		for field := range v {
+			keys = append(keys, field)
 		}
-		for field, val := range v {
-			if len(rules) > 0 {
-				if canIndex, ok := rules[field]; ok && canIndex {
-					fields = append(fields, fmt.Sprintf("%v", val))
-				}
-			} else {
-				fields = append(fields, fmt.Sprintf("%v", val))
+		sort.Strings(keys)
+		for _, field := range keys {
+			name := fieldName(field)
+			if db.canIndex(name) {
+				fields = append(fields, indexField{name: name, value: fmt.Sprintf("%v", v[field])})
 			}
 		}
 	default:
-		val := reflect.ValueOf(obj)
-		t := reflect.TypeOf(obj)
+		val := reflect.Indirect(reflect.ValueOf(obj))
+		if val.Kind() != reflect.Struct {
+			fields = append(fields, indexField{name: defaultField, value: fmt.Sprintf("%v", obj)})
+			return
+		}
+		t := val.Type()
 		hasIndexField := false
 		for i := 0; i < val.NumField(); i++ {
 			f := t.Field(i)
 			if v, ok := f.Tag.Lookup("index"); ok && str.EqualFold(v, "true") {
 				hasIndexField = true
-				fields = append(fields, val.Field(i).String())
+				fields = append(fields, indexField{name: structFieldName(f), value: fieldValue(val.Field(i))})
 			}
 		}
 		if !hasIndexField {
 			for i := 0; i < val.NumField(); i++ {
-				fields = append(fields, val.Field(i).String())
+				f := t.Field(i)
+				if f.PkgPath != "" {
+					continue
+				}
+				if name := structFieldName(f); db.canIndex(name) {
+					fields = append(fields, indexField{name: name, value: fieldValue(val.Field(i))})
+				}
 			}
 		}
 	}
 	return
 }
 
+// structFieldName returns the index name of a struct field, preferring its json tag
+func structFieldName(f reflect.StructField) string {
+	if tag, ok := f.Tag.Lookup("json"); ok {
+		if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+			return fieldName(name)
+		}
+	}
+	return fieldName(f.Name)
+}
+
+// fieldValue formats a struct field value as text
+func fieldValue(val reflect.Value) string {
+	if val.Kind() == reflect.String {
+		return val.String()
+	}
+	if !val.CanInterface() {
+		return ""
+	}
+	return fmt.Sprintf("%v", val.Interface())
+}
+
+// fieldName normalizes a field name so rules, boosts and queries match case-insensitively
+func fieldName(name string) string {
+	return strings.ToLower(name)
+}
+
+// Tokenize returns the unique tokens of data produced by DefaultAnalyzer
 func Tokenize(data string) []string {
-	data = punctuationRegex.ReplaceAllString(data, "")
-	data = str.ToLower(data)
-	arr := strings.Fields(data)
-	noStopWords := removeStopWords(arr)
-	return uniqueSlice(noStopWords)
+	return uniqueSlice(terms(defaultAnalyzer.Analyze(data)))
 }
 
 func Count(tokens []string) map[string]int64 {
@@ -426,18 +564,6 @@ func Count(tokens []string) map[string]int64 {
 	return dict
 }
 
-func removeStopWords(tokens []string) []string {
-	var newSlice []string
-	for _, value := range tokens {
-		_, ok := stopWords[value]
-		if !ok {
-			newSlice = append(newSlice, value)
-		}
-	}
-
-	return newSlice
-}
-
 func uniqueSlice(tokens []string) []string {
 	tokenHash := make(map[string]bool)
 	var newSlice []string
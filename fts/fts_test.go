@@ -0,0 +1,207 @@
+package fts
+
+import (
+	"testing"
+)
+
+func newTestIndex(t *testing.T, texts ...string) *FTS[map[string]any] {
+	t.Helper()
+	db := New[map[string]any]("test")
+	for _, text := range texts {
+		if _, err := db.Insert(map[string]any{"text": text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func hitTexts(hits []Record[map[string]any]) []string {
+	texts := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		texts = append(texts, hit.S["text"].(string))
+	}
+	return texts
+}
+
+func TestSearchBM25Ranking(t *testing.T) {
+	db := newTestIndex(t,
+		"golang tutorial beginners examples",
+		"golang golang golang",
+		"golang",
+		"rust",
+	)
+	hits := db.Search("golang", Option{Exact: true, Size: 10})
+	want := []string{"golang golang golang", "golang", "golang tutorial beginners examples"}
+	got := hitTexts(hits)
+	if len(got) != len(want) {
+		t.Fatalf("Search returned %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Search returned %v, want %v", got, want)
+		}
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Score > hits[i-1].Score {
+			t.Errorf("hit %d scored %v above hit %d with %v", i, hits[i].Score, i-1, hits[i-1].Score)
+		}
+	}
+
+	// the rarer term weighs more than the common one
+	hits = db.Search("golang rust", Option{Size: 10})
+	if len(hits) != 4 || hitTexts(hits)[0] != "rust" {
+		t.Errorf("Search(golang rust) returned %v, want rust first", hitTexts(hits))
+	}
+}
+
+func TestSearchTiesOrderedById(t *testing.T) {
+	db := newTestIndex(t, "identical text", "identical text", "identical text")
+	hits := db.Search("identical", Option{Exact: true, Size: 10})
+	if len(hits) != 3 {
+		t.Fatalf("Search returned %d hits, want 3", len(hits))
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Score != hits[0].Score {
+			t.Fatalf("equal documents scored %v and %v", hits[0].Score, hits[i].Score)
+		}
+		if hits[i].Id < hits[i-1].Id {
+			t.Errorf("tied hits are not ordered by id: %d before %d", hits[i-1].Id, hits[i].Id)
+		}
+	}
+}
+
+func TestSearchSize(t *testing.T) {
+	db := newTestIndex(t,
+		"apple",
+		"apple apple",
+		"apple apple apple",
+		"apple banana cherry",
+		"banana",
+	)
+	all := db.Search("apple", Option{Exact: true, Size: 10})
+	if len(all) != 4 {
+		t.Fatalf("Search returned %d hits, want 4", len(all))
+	}
+	for _, size := range []int{1, 2, 4} {
+		hits := db.Search("apple", Option{Exact: true, Size: size})
+		if len(hits) != size {
+			t.Fatalf("Search with Size %d returned %d hits", size, len(hits))
+		}
+		for i, hit := range hits {
+			if hit.Id != all[i].Id {
+				t.Errorf("Search with Size %d returned %v at %d, want the top hits %v", size, hit.S, i, all[i].S)
+			}
+		}
+	}
+	if hits := db.Search("apple"); len(hits) != 4 {
+		t.Errorf("Search without options returned %d hits, want 4", len(hits))
+	}
+}
+
+type article struct {
+	Title string `json:"headline"`
+	Body  string
+}
+
+func TestSearchFieldBoosts(t *testing.T) {
+	docs := []map[string]any{
+		{"title": "golang", "body": "rust"},
+		{"title": "rust", "body": "golang"},
+	}
+	for _, test := range []struct {
+		boosts map[string]float64
+		want   string
+	}{
+		{boosts: map[string]float64{"title": 5}, want: "golang"},
+		{boosts: map[string]float64{"Body": 5}, want: "rust"},
+	} {
+		db := NewWithConfig[map[string]any]("test", Config{Boosts: test.boosts})
+		for _, doc := range docs {
+			if _, err := db.Insert(doc); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hits := db.Search("golang", Option{Exact: true, Size: 10})
+		if len(hits) != 2 {
+			t.Fatalf("Search with boosts %v returned %d hits, want 2", test.boosts, len(hits))
+		}
+		if hits[0].S["title"] != test.want {
+			t.Errorf("Search with boosts %v ranked %v first, want the document titled %q", test.boosts, hits[0].S, test.want)
+		}
+		if hits[0].Score <= hits[1].Score {
+			t.Errorf("boosted hit scored %v, not above %v", hits[0].Score, hits[1].Score)
+		}
+	}
+}
+
+func TestSearchFieldScopedTerms(t *testing.T) {
+	db := New[map[string]any]("test")
+	for _, doc := range []map[string]any{
+		{"title": "golang", "body": "rust"},
+		{"title": "rust", "body": "golang"},
+	} {
+		if _, err := db.Insert(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "title:golang", want: []string{"golang"}},
+		{query: "body:golang", want: []string{"rust"}},
+		{query: "Title:Golang", want: []string{"golang"}},
+		{query: "title:golang body:rust", want: []string{"golang"}},
+		{query: "title:golang body:golang", want: nil},
+		{query: "author:golang", want: nil},
+	}
+	for _, test := range tests {
+		hits := db.Search(test.query, Option{Exact: true, Size: 10})
+		var got []string
+		for _, hit := range hits {
+			got = append(got, hit.S["title"].(string))
+		}
+		if len(got) != len(test.want) || (len(got) > 0 && got[0] != test.want[0]) {
+			t.Errorf("Search(%q) returned titles %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestSearchFieldRules(t *testing.T) {
+	db := New[map[string]any]("test", map[string]bool{"title": true})
+	for _, doc := range []map[string]any{
+		{"title": "golang", "body": "rust"},
+		{"title": "rust", "body": "golang"},
+	} {
+		if _, err := db.Insert(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hits := db.Search("rust", Option{Exact: true, Size: 10})
+	if len(hits) != 1 || hits[0].S["title"] != "rust" {
+		t.Errorf("Search(rust) returned %v, want only the document titled rust", hits)
+	}
+	if hits := db.Search("body:golang", Option{Exact: true, Size: 10}); len(hits) != 0 {
+		t.Errorf("Search(body:golang) matched %d documents in a field excluded by the rules", len(hits))
+	}
+}
+
+func TestSearchStructFields(t *testing.T) {
+	db := New[article]("test")
+	for _, doc := range []article{
+		{Title: "golang generics", Body: "type parameters"},
+		{Title: "rust traits", Body: "golang interfaces"},
+	} {
+		if _, err := db.Insert(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hits := db.Search("headline:golang", Option{Exact: true, Size: 10})
+	if len(hits) != 1 || hits[0].S.Title != "golang generics" {
+		t.Errorf("Search(headline:golang) returned %v, want the document named by its json tag", hits)
+	}
+	hits = db.Search("body:golang", Option{Exact: true, Size: 10})
+	if len(hits) != 1 || hits[0].S.Title != "rust traits" {
+		t.Errorf("Search(body:golang) returned %v, want the document matched through the lowercased field name", hits)
+	}
+}
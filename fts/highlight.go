@@ -0,0 +1,175 @@
+package fts
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Highlight configures the fragments returned for each hit
+type Highlight struct {
+	// PreTag and PostTag wrap every match, they default to <em> and </em>
+	PreTag  string
+	PostTag string
+	// FragmentSize is the number of bytes of context kept around the matches, the whole field is returned when 0
+	FragmentSize int
+	// MaxFragments bounds the number of fragments per field, every fragment is returned when 0
+	MaxFragments int
+}
+
+const (
+	defaultPreTag  = "<em>"
+	defaultPostTag = "</em>"
+)
+
+// highlight sets the highlighted fragments of every field in which a record matched the query
+func (db *FTS[Schema]) highlight(records []Record[Schema], root queryNode, option Option) {
+	h := *option.Highlight
+	if h.PreTag == "" && h.PostTag == "" {
+		h.PreTag, h.PostTag = defaultPreTag, defaultPostTag
+	}
+	terms := db.matchedTerms(root, option, nil)
+	for i := range records {
+		offsets := make(map[string][]Offset)
+		for _, term := range terms {
+			infos, ok := db.index.Get(term.token)
+			if !ok {
+				continue
+			}
+			info, ok := infos.Get(records[i].Id)
+			if !ok {
+				continue
+			}
+			for _, field := range info.Fields {
+				if term.field == "" || term.field == field.Name {
+					offsets[field.Name] = append(offsets[field.Name], field.Offsets...)
+				}
+			}
+		}
+		if len(offsets) == 0 {
+			continue
+		}
+		highlights := make(map[string][]string, len(offsets))
+		for _, field := range db.getIndexFields(records[i].S) {
+			if fieldOffsets, ok := offsets[field.name]; ok {
+				if fragments := fragments(field.value, fieldOffsets, h); len(fragments) > 0 {
+					highlights[field.name] = fragments
+				}
+			}
+		}
+		records[i].Highlights = highlights
+	}
+}
+
+// matchedTerms returns the indexed tokens a query may have matched, excluded clauses are skipped
+// Phrase tokens are highlighted wherever they occur in the matched field
+func (db *FTS[Schema]) matchedTerms(node queryNode, option Option, terms []queryTerm) []queryTerm {
+	switch node := node.(type) {
+	case termNode:
+		for _, exp := range db.expand(node.term.token, option) {
+			terms = appendTerm(terms, queryTerm{field: node.term.field, token: exp.token})
+		}
+	case phraseNode:
+		for _, token := range node.tokens {
+			terms = appendTerm(terms, queryTerm{field: node.field, token: token.Term})
+		}
+	case *booleanNode:
+		for _, clause := range node.clauses {
+			terms = db.matchedTerms(clause, option, terms)
+		}
+	}
+	return terms
+}
+
+func appendTerm(terms []queryTerm, term queryTerm) []queryTerm {
+	for _, t := range terms {
+		if t == term {
+			return terms
+		}
+	}
+	return append(terms, term)
+}
+
+// fragments returns the parts of text around offsets with every offset wrapped in the highlight tags
+func fragments(text string, offsets []Offset, h Highlight) []string {
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i].Start < offsets[j].Start })
+	valid := offsets[:0]
+	for _, offset := range offsets {
+		// offsets are dropped when the document changed since it was indexed or when they overlap the previous one
+		if offset.Start < 0 || offset.End > len(text) || offset.Start >= offset.End {
+			continue
+		}
+		if n := len(valid); n > 0 && offset.Start < valid[n-1].End {
+			continue
+		}
+		valid = append(valid, offset)
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	if h.FragmentSize <= 0 {
+		return []string{mark(text, 0, len(text), valid, h)}
+	}
+
+	var result []string
+	context := h.FragmentSize / 2
+	for i := 0; i < len(valid); {
+		start := fragmentStart(text, valid[i].Start-context, valid[i].Start)
+		end := valid[i].End + context
+		j := i + 1
+		for j < len(valid) && valid[j].Start < end {
+			end = valid[j].End + context
+			j++
+		}
+		end = fragmentEnd(text, end, valid[j-1].End)
+		result = append(result, mark(text, start, end, valid[i:j], h))
+		if h.MaxFragments > 0 && len(result) == h.MaxFragments {
+			break
+		}
+		i = j
+	}
+	return result
+}
+
+// fragmentStart moves start forward to the beginning of a word without passing limit
+func fragmentStart(text string, start, limit int) int {
+	if start <= 0 {
+		return 0
+	}
+	for start < limit && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	if space := strings.IndexFunc(text[start:limit], unicode.IsSpace); space >= 0 {
+		start += space
+	}
+	return start + len(text[start:limit]) - len(strings.TrimLeftFunc(text[start:limit], unicode.IsSpace))
+}
+
+// fragmentEnd moves end back to the end of a word without passing limit
+func fragmentEnd(text string, end, limit int) int {
+	if end >= len(text) {
+		return len(text)
+	}
+	for end > limit && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	if space := strings.LastIndexFunc(text[limit:end], unicode.IsSpace); space >= 0 {
+		end = limit + space
+	}
+	return limit + len(strings.TrimRightFunc(text[limit:end], unicode.IsSpace))
+}
+
+// mark returns text[start:end] with offsets wrapped in the highlight tags
+func mark(text string, start, end int, offsets []Offset, h Highlight) string {
+	var sb strings.Builder
+	for _, offset := range offsets {
+		sb.WriteString(text[start:offset.Start])
+		sb.WriteString(h.PreTag)
+		sb.WriteString(text[offset.Start:offset.End])
+		sb.WriteString(h.PostTag)
+		start = offset.End
+	}
+	sb.WriteString(text[start:end])
+	return sb.String()
+}
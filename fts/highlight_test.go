@@ -0,0 +1,96 @@
+package fts
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+const highlightText = "Diabetes is common. Gestational diabetes appears during pregnancy and usually resolves after delivery of the baby."
+
+func TestIndexOffsets(t *testing.T) {
+	db := newTestIndex(t, highlightText)
+	want := []Offset{{Start: 0, End: 8}, {Start: 32, End: 40}}
+	check := func(db *FTS[map[string]any]) {
+		t.Helper()
+		infos, ok := db.index.Get("diabetes")
+		if !ok || infos.Len() != 1 {
+			t.Fatal("diabetes is not indexed")
+		}
+		infos.ForEach(func(_ int64, info RecordInfo) bool {
+			if len(info.Fields) != 1 || !reflect.DeepEqual(info.Fields[0].Offsets, want) {
+				t.Errorf("offsets of diabetes = %+v, want %v", info.Fields, want)
+			}
+			return true
+		})
+	}
+	check(db)
+
+	var buf bytes.Buffer
+	if err := db.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New[map[string]any]("test")
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	check(loaded)
+}
+
+func TestSearchHighlight(t *testing.T) {
+	db := newTestIndex(t, highlightText, "pregnancy nutrition")
+	tests := []struct {
+		query     string
+		highlight Highlight
+		want      []string
+	}{
+		{query: "diabetes", want: []string{"<em>Diabetes</em> is common. Gestational <em>diabetes</em> appears during pregnancy and usually resolves after delivery of the baby."}},
+		{query: "diabetes", highlight: Highlight{FragmentSize: 20}, want: []string{"<em>Diabetes</em> is", "<em>diabetes</em> appears"}},
+		{query: "diabetes pregnancy", highlight: Highlight{FragmentSize: 20}, want: []string{"<em>Diabetes</em> is", "<em>diabetes</em> appears", "during <em>pregnancy</em> and"}},
+		{query: "diabetes pregnancy", highlight: Highlight{FragmentSize: 20, MaxFragments: 2, PreTag: "[", PostTag: "]"}, want: []string{"[Diabetes] is", "[diabetes] appears"}},
+		{query: `"gestational diabetes"`, highlight: Highlight{FragmentSize: 20}, want: []string{"<em>Diabetes</em> is", "common. <em>Gestational</em> <em>diabetes</em> appears"}},
+		{query: "text:baby", highlight: Highlight{FragmentSize: 20}, want: []string{"of the <em>baby</em>."}},
+	}
+	for _, test := range tests {
+		highlight := test.highlight
+		hits := db.Search(test.query, Option{Exact: true, Size: 10, Highlight: &highlight})
+		if len(hits) == 0 {
+			t.Fatalf("Search(%q) returned no hits", test.query)
+		}
+		if got := hits[0].Highlights["text"]; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Search(%q) with %+v highlighted %q, want %q", test.query, test.highlight, got, test.want)
+		}
+	}
+
+	if hits := db.Search("diabetes", Option{Exact: true, Size: 10}); len(hits) != 1 || hits[0].Highlights != nil {
+		t.Errorf("Search without Highlight returned highlights %v", hits)
+	}
+	if hits := db.Search("other:diabetes", Option{Exact: true, Size: 10, Highlight: &Highlight{}}); len(hits) != 0 {
+		t.Errorf("Search(other:diabetes) returned %d hits", len(hits))
+	}
+}
+
+func TestFragments(t *testing.T) {
+	h := Highlight{PreTag: "<", PostTag: ">"}
+	tests := []struct {
+		name    string
+		text    string
+		offsets []Offset
+		size    int
+		want    []string
+	}{
+		{name: "unsorted and overlapping", text: "a b c", offsets: []Offset{{Start: 2, End: 3}, {Start: 2, End: 3}, {Start: 0, End: 1}}, want: []string{"<a> <b> c"}},
+		{name: "out of range", text: "a b c", offsets: []Offset{{Start: 4, End: 9}, {Start: 3, End: 3}}, want: nil},
+		{name: "word boundaries", text: "one two three four five six seven", offsets: []Offset{{Start: 0, End: 3}, {Start: 28, End: 33}}, size: 4, want: []string{"<one>", "<seven>"}},
+		{name: "merged fragments", text: "one two three four", offsets: []Offset{{Start: 0, End: 3}, {Start: 8, End: 13}}, size: 12, want: []string{"<one> two <three> four"}},
+		{name: "multibyte", text: "héllo wörld diabetes ünd mehr", offsets: []Offset{{Start: 14, End: 22}}, size: 8, want: []string{"<diabetes>"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h.FragmentSize = test.size
+			if got := fragments(test.text, test.offsets, h); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("fragments = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package fts
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sujit-baniya/pkg/maps"
+)
+
+// queryTerm is a single analyzed token of a query, optionally scoped to a field
+type queryTerm struct {
+	field string
+	token string
+}
+
+// queryNode is a node of a parsed query
+type queryNode interface {
+	isQueryNode()
+}
+
+// termNode matches documents containing a token
+type termNode struct {
+	term queryTerm
+}
+
+// phraseNode matches documents containing tokens next to each other in the same field
+type phraseNode struct {
+	field  string
+	tokens []Token
+}
+
+// booleanNode combines clauses, excluded clauses remove documents from the result
+type booleanNode struct {
+	any      bool
+	clauses  []queryNode
+	excluded []queryNode
+}
+
+func (termNode) isQueryNode()     {}
+func (phraseNode) isQueryNode()   {}
+func (*booleanNode) isQueryNode() {}
+
+// queryParser parses the query language
+//
+//	diabetes pregnancy       documents with both terms, or either one when Option.Exact is false
+//	"third trimester"        documents with the phrase
+//	-gestational             documents without the term
+//	diabetes OR obesity      documents with either term
+//	(a OR b) AND c           parentheses group clauses, AND is optional
+//	code:E11 desc:"type 2"   terms and phrases scoped to a field
+type queryParser struct {
+	input    []rune
+	pos      int
+	analyzer Analyzer
+	exact    bool
+}
+
+// parseQuery parses query into a tree of clauses analyzed by analyzer
+// Clauses written next to each other are all required when exact is set, otherwise any of them may match
+func parseQuery(query string, analyzer Analyzer, exact bool) queryNode {
+	p := &queryParser{input: []rune(query), analyzer: analyzer, exact: exact}
+	node := p.parseOr()
+	// unbalanced closing parentheses are skipped so the rest of the query still applies
+	for p.pos < len(p.input) {
+		p.pos++
+		if rest := p.parseOr(); rest == nil {
+			continue
+		} else if node == nil {
+			node = rest
+		} else {
+			node = &booleanNode{any: !exact, clauses: []queryNode{node, rest}}
+		}
+	}
+	return node
+}
+
+func (p *queryParser) parseOr() queryNode {
+	var clauses []queryNode
+	for {
+		if node := p.parseAnd(); node != nil {
+			clauses = append(clauses, node)
+		}
+		if !p.keyword("OR") {
+			break
+		}
+	}
+	switch len(clauses) {
+	case 0:
+		return nil
+	case 1:
+		return clauses[0]
+	}
+	return &booleanNode{any: true, clauses: clauses}
+}
+
+func (p *queryParser) parseAnd() queryNode {
+	node := &booleanNode{any: !p.exact}
+	explicit := false
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.input) || p.input[p.pos] == ')' || p.peekKeyword("OR") {
+			break
+		}
+		if p.keyword("AND") {
+			explicit = true
+			continue
+		}
+		excluded := false
+		if p.input[p.pos] == '-' {
+			p.pos++
+			// a lone or trailing - excludes nothing
+			if p.pos >= len(p.input) || unicode.IsSpace(p.input[p.pos]) || p.input[p.pos] == ')' {
+				continue
+			}
+			excluded = true
+		}
+		clause := p.parsePrimary()
+		if clause == nil {
+			continue
+		}
+		if excluded {
+			node.excluded = append(node.excluded, clause)
+		} else {
+			node.clauses = append(node.clauses, clause)
+		}
+	}
+	if explicit {
+		node.any = false
+	}
+	if len(node.clauses) == 0 && len(node.excluded) == 0 {
+		return nil
+	}
+	if len(node.clauses) == 1 && len(node.excluded) == 0 {
+		return node.clauses[0]
+	}
+	return node
+}
+
+func (p *queryParser) parsePrimary() queryNode {
+	switch p.input[p.pos] {
+	case '(':
+		p.pos++
+		node := p.parseOr()
+		p.skipSpace()
+		if p.pos < len(p.input) && p.input[p.pos] == ')' {
+			p.pos++
+		}
+		return node
+	case '"':
+		return p.phrase("", p.quoted())
+	}
+	start := p.pos
+	for p.pos < len(p.input) && !unicode.IsSpace(p.input[p.pos]) && !strings.ContainsRune(`()"`, p.input[p.pos]) {
+		p.pos++
+	}
+	word := string(p.input[start:p.pos])
+	field := ""
+	if name, text, ok := strings.Cut(word, ":"); ok && name != "" {
+		if text == "" && p.pos < len(p.input) && p.input[p.pos] == '"' {
+			return p.phrase(fieldName(name), p.quoted())
+		}
+		if text != "" {
+			field, word = fieldName(name), text
+		}
+	}
+	return p.phrase(field, word)
+}
+
+// phrase returns a node matching the analyzed text, a single token becomes a term
+func (p *queryParser) phrase(field, text string) queryNode {
+	tokens := p.analyzer.Analyze(text)
+	switch len(tokens) {
+	case 0:
+		return nil
+	case 1:
+		return termNode{term: queryTerm{field: field, token: tokens[0].Term}}
+	}
+	return phraseNode{field: field, tokens: tokens}
+}
+
+// quoted returns the text up to the closing quote, the opening quote is at the current position
+func (p *queryParser) quoted() string {
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != '"' {
+		p.pos++
+	}
+	text := string(p.input[start:p.pos])
+	if p.pos < len(p.input) {
+		p.pos++
+	}
+	return text
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// peekKeyword reports whether the next word is the upper case keyword
+func (p *queryParser) peekKeyword(keyword string) bool {
+	p.skipSpace()
+	end := p.pos + len(keyword)
+	if end > len(p.input) || string(p.input[p.pos:end]) != keyword {
+		return false
+	}
+	return end == len(p.input) || unicode.IsSpace(p.input[end]) || strings.ContainsRune(`()"-`, p.input[end])
+}
+
+// keyword consumes the next word when it is the upper case keyword
+func (p *queryParser) keyword(keyword string) bool {
+	if !p.peekKeyword(keyword) {
+		return false
+	}
+	p.pos += len(keyword)
+	return true
+}
+
+// evaluate returns the score of every document matching node
+func (db *FTS[Schema]) evaluate(node queryNode, option Option) map[int64]float64 {
+	switch node := node.(type) {
+	case termNode:
+		return db.scoreTerm(node.term, option)
+	case phraseNode:
+		return db.scorePhrase(node)
+	case *booleanNode:
+		var scores map[int64]float64
+		for i, clause := range node.clauses {
+			clauseScores := db.evaluate(clause, option)
+			switch {
+			case i == 0:
+				scores = clauseScores
+				if scores == nil {
+					scores = make(map[int64]float64)
+				}
+			case node.any:
+				for id, score := range clauseScores {
+					scores[id] += score
+				}
+			default:
+				for id := range scores {
+					if score, ok := clauseScores[id]; ok {
+						scores[id] += score
+					} else {
+						delete(scores, id)
+					}
+				}
+			}
+		}
+		for _, clause := range node.excluded {
+			for id := range db.evaluate(clause, option) {
+				delete(scores, id)
+			}
+		}
+		return scores
+	}
+	return nil
+}
+
+// scorePhrase returns the documents containing the phrase tokens at the same relative positions in one field
+// Matching documents are scored as the sum of the phrase terms
+func (db *FTS[Schema]) scorePhrase(node phraseNode) map[int64]float64 {
+	postings := make([]maps.IMap[int64, RecordInfo], len(node.tokens))
+	for i, token := range node.tokens {
+		infos, ok := db.index.Get(token.Term)
+		if !ok {
+			return nil
+		}
+		postings[i] = infos
+	}
+	scores := make(map[int64]float64)
+	for id, first := range postings[0].All() {
+		for _, field := range first.Fields {
+			if node.field != "" && node.field != field.Name {
+				continue
+			}
+			if db.phraseInField(id, field, node.tokens, postings) {
+				scores[id] = 0
+				break
+			}
+		}
+	}
+	for _, token := range node.tokens {
+		for id, score := range db.scoreTerm(queryTerm{field: node.field, token: token.Term}, Option{}) {
+			if _, ok := scores[id]; ok {
+				scores[id] += score
+			}
+		}
+	}
+	return scores
+}
+
+// phraseInField reports whether every phrase token follows the first one at its relative position within field
+func (db *FTS[Schema]) phraseInField(id int64, first FieldInfo, tokens []Token, postings []maps.IMap[int64, RecordInfo]) bool {
+	positions := make([][]int, len(tokens))
+	positions[0] = first.Positions
+	for i := 1; i < len(tokens); i++ {
+		info, ok := postings[i].Get(id)
+		if !ok {
+			return false
+		}
+		for _, field := range info.Fields {
+			if field.Name == first.Name {
+				positions[i] = field.Positions
+			}
+		}
+		if positions[i] == nil {
+			return false
+		}
+	}
+	for _, start := range positions[0] {
+		found := true
+		for i := 1; i < len(tokens) && found; i++ {
+			want := start + tokens[i].Position - tokens[0].Position
+			j := sort.SearchInts(positions[i], want)
+			found = j < len(positions[i]) && positions[i][j] == want
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package fts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func term(token string) termNode {
+	return termNode{term: queryTerm{token: token}}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  queryNode
+	}{
+		{query: "", want: nil},
+		{query: "-", want: nil},
+		{query: "cat -", want: term("cat")},
+		{query: "cat - dog", want: &booleanNode{clauses: []queryNode{term("cat"), term("dog")}}},
+		{query: "(cat -)", want: term("cat")},
+		{query: "cat -dog", want: &booleanNode{clauses: []queryNode{term("cat")}, excluded: []queryNode{term("dog")}}},
+		{query: "cat OR dog", want: &booleanNode{any: true, clauses: []queryNode{term("cat"), term("dog")}}},
+		{query: "(cat OR dog) AND bird", want: &booleanNode{clauses: []queryNode{
+			&booleanNode{any: true, clauses: []queryNode{term("cat"), term("dog")}},
+			term("bird"),
+		}}},
+		{query: "code:E11", want: termNode{term: queryTerm{field: "code", token: "e11"}}},
+		{query: "cat) dog", want: &booleanNode{clauses: []queryNode{term("cat"), term("dog")}}},
+		{query: `"cat`, want: term("cat")},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			got := parseQuery(test.query, defaultAnalyzer, true)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseQuery(%q) = %#v, want %#v", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchTrailingExclusion(t *testing.T) {
+	db := New[map[string]any]("test")
+	if _, err := db.Insert(map[string]any{"text": "diabetes in pregnancy"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{"diabetes -", "-", "diabetes - pregnancy"} {
+		if hits := db.Search(query); query != "-" && len(hits) != 1 {
+			t.Errorf("Search(%q) returned %d hits, want 1", query, len(hits))
+		}
+	}
+}
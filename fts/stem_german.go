@@ -0,0 +1,105 @@
+package fts
+
+import "strings"
+
+// stemGerman reduces a german word to its stem with the Snowball german stemmer
+// See https://snowballstem.org/algorithms/german/stemmer.html
+func stemGerman(word string) string {
+	word = strings.ReplaceAll(word, "ß", "ss")
+	w := []rune(word)
+	if len(w) <= 2 {
+		return word
+	}
+	// u and y between vowels are consonants, they are upper cased to tell them apart while stemming
+	for i := 1; i < len(w)-1; i++ {
+		if isGermanVowel(w[i-1]) && isGermanVowel(w[i+1]) {
+			switch w[i] {
+			case 'u':
+				w[i] = 'U'
+			case 'y':
+				w[i] = 'Y'
+			}
+		}
+	}
+	r1 := nextRegion(w, 0, isGermanVowel)
+	if r1 < 3 {
+		r1 = 3
+	}
+	r2 := nextRegion(w, r1, isGermanVowel)
+	s := &suffixWord{w: w}
+
+	switch suffix := s.longest([]string{"em", "ern", "er", "e", "en", "es", "s"}); suffix {
+	case "em", "ern", "er":
+		s.cut(suffix, r1)
+	case "e", "en", "es":
+		if s.cut(suffix, r1) && s.endsWith("niss") {
+			s.w = s.w[:len(s.w)-1]
+		}
+	case "s":
+		if s.start(suffix) >= r1 && s.start(suffix) > 0 && isGermanSEnding(s.w[s.start(suffix)-1]) {
+			s.cut(suffix, r1)
+		}
+	}
+
+	switch suffix := s.longest([]string{"en", "er", "est", "st"}); suffix {
+	case "en", "er", "est":
+		s.cut(suffix, r1)
+	case "st":
+		// the st-ending letter must itself be preceded by at least 3 letters
+		if at := s.start(suffix); at >= r1 && at >= 4 && isGermanSEnding(s.w[at-1]) && s.w[at-1] != 'r' {
+			s.cut(suffix, r1)
+		}
+	}
+
+	switch suffix := s.longest([]string{"end", "ung", "ig", "ik", "isch", "lich", "heit", "keit"}); suffix {
+	case "end", "ung":
+		if s.cut(suffix, r2) && s.endsWith("ig") && !s.endsWith("eig") {
+			s.cut("ig", r2)
+		}
+	case "ig", "ik", "isch":
+		if at := s.start(suffix); at > 0 && s.w[at-1] != 'e' {
+			s.cut(suffix, r2)
+		}
+	case "lich", "heit":
+		if s.cut(suffix, r2) && !s.cut("er", r1) {
+			s.cut("en", r1)
+		}
+	case "keit":
+		if s.cut(suffix, r2) && !s.cut("lich", r2) {
+			s.cut("ig", r2)
+		}
+	}
+
+	return strings.Map(func(char rune) rune {
+		switch char {
+		case 'U':
+			return 'u'
+		case 'Y':
+			return 'y'
+		case 'ä':
+			return 'a'
+		case 'ö':
+			return 'o'
+		case 'ü':
+			return 'u'
+		}
+		return char
+	}, string(s.w))
+}
+
+func isGermanVowel(char rune) bool {
+	switch char {
+	case 'a', 'e', 'i', 'o', 'u', 'y', 'ä', 'ö', 'ü':
+		return true
+	}
+	return false
+}
+
+// isGermanSEnding reports whether a final s can be removed after char
+func isGermanSEnding(char rune) bool {
+	switch char {
+	case 'b', 'd', 'f', 'g', 'h', 'k', 'l', 'm', 'n', 'r', 't':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package fts
+
+import "strings"
+
+// stemSpanish reduces a spanish word to its stem with the Snowball spanish stemmer
+// See https://snowballstem.org/algorithms/spanish/stemmer.html
+func stemSpanish(word string) string {
+	w := []rune(word)
+	if len(w) <= 2 {
+		return word
+	}
+	rv, r1, r2 := spanishRegions(w)
+	s := &suffixWord{w: w}
+
+	s.w = spanishAttachedPronoun(s, rv)
+	if !spanishStandardSuffix(s, r1, r2) && !spanishYVerbSuffix(s, rv) {
+		spanishVerbSuffix(s, rv)
+	}
+	spanishResidualSuffix(s, rv)
+	return strings.Map(removeAcute, string(s.w))
+}
+
+// suffixWord is a word whose suffixes are being removed
+type suffixWord struct {
+	w []rune
+}
+
+// longest returns the longest suffix from suffixes that w ends with
+func (s *suffixWord) longest(suffixes []string) string {
+	return s.longestIn(suffixes, 0)
+}
+
+// longestIn returns the longest suffix from suffixes that w ends with and that starts at or after region
+func (s *suffixWord) longestIn(suffixes []string, region int) string {
+	found := ""
+	for _, suffix := range suffixes {
+		if len(suffix) > len(found) && s.endsWith(suffix) && s.start(suffix) >= region {
+			found = suffix
+		}
+	}
+	return found
+}
+
+func (s *suffixWord) endsWith(suffix string) bool {
+	return strings.HasSuffix(string(s.w), suffix)
+}
+
+// start returns the position at which suffix begins
+func (s *suffixWord) start(suffix string) int {
+	return len(s.w) - len([]rune(suffix))
+}
+
+// cut removes suffix when it starts at or after region
+func (s *suffixWord) cut(suffix string, region int) bool {
+	if !s.endsWith(suffix) || s.start(suffix) < region {
+		return false
+	}
+	s.w = s.w[:s.start(suffix)]
+	return true
+}
+
+// replace substitutes suffix by replacement when it starts at or after region
+func (s *suffixWord) replace(suffix, replacement string, region int) bool {
+	if !s.endsWith(suffix) || s.start(suffix) < region {
+		return false
+	}
+	s.w = append(s.w[:s.start(suffix)], []rune(replacement)...)
+	return true
+}
+
+func isSpanishVowel(char rune) bool {
+	switch char {
+	case 'a', 'e', 'i', 'o', 'u', 'á', 'é', 'í', 'ó', 'ú', 'ü':
+		return true
+	}
+	return false
+}
+
+// spanishRegions returns the start of the RV, R1 and R2 regions
+func spanishRegions(w []rune) (rv, r1, r2 int) {
+	rv = len(w)
+	switch {
+	case !isSpanishVowel(w[1]):
+		for i := 2; i < len(w); i++ {
+			if isSpanishVowel(w[i]) {
+				rv = i + 1
+				break
+			}
+		}
+	case isSpanishVowel(w[0]):
+		for i := 2; i < len(w); i++ {
+			if !isSpanishVowel(w[i]) {
+				rv = i + 1
+				break
+			}
+		}
+	default:
+		rv = 3
+	}
+	r1 = nextRegion(w, 0, isSpanishVowel)
+	r2 = nextRegion(w, r1, isSpanishVowel)
+	return
+}
+
+// nextRegion returns the position after the first non-vowel following a vowel at or after from
+func nextRegion(w []rune, from int, isVowel func(rune) bool) int {
+	for i := from + 1; i < len(w); i++ {
+		if !isVowel(w[i]) && isVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+var spanishPronouns = []string{"me", "se", "sela", "selo", "selas", "selos", "la", "le", "lo", "las", "les", "los", "nos"}
+
+// spanishPronounVerbEndings maps the verb endings allowed before a pronoun to their unaccented form
+var spanishPronounVerbEndings = map[string]string{
+	"iéndo": "iendo", "ándo": "ando", "ár": "ar", "ér": "er", "ír": "ir",
+	"ando": "ando", "iendo": "iendo", "ar": "ar", "er": "er", "ir": "ir", "yendo": "yendo",
+}
+
+func spanishAttachedPronoun(s *suffixWord, rv int) []rune {
+	pronoun := s.longest(spanishPronouns)
+	if pronoun == "" {
+		return s.w
+	}
+	verb := &suffixWord{w: s.w[:s.start(pronoun)]}
+	endings := make([]string, 0, len(spanishPronounVerbEndings))
+	for ending := range spanishPronounVerbEndings {
+		endings = append(endings, ending)
+	}
+	ending := verb.longest(endings)
+	if ending == "" || verb.start(ending) < rv {
+		return s.w
+	}
+	if ending == "yendo" && (verb.start(ending) == 0 || verb.w[verb.start(ending)-1] != 'u') {
+		return s.w
+	}
+	verb.replace(ending, spanishPronounVerbEndings[ending], rv)
+	return verb.w
+}
+
+// spanishStandardSuffix removes a noun or adjective suffix and reports whether one was removed
+func spanishStandardSuffix(s *suffixWord, r1, r2 int) bool {
+	suffix := s.longest([]string{
+		"anza", "anzas", "ico", "ica", "icos", "icas", "ismo", "ismos", "able", "ables", "ible", "ibles",
+		"ista", "istas", "oso", "osa", "osos", "osas", "amiento", "amientos", "imiento", "imientos",
+		"adora", "ador", "ación", "adoras", "adores", "aciones", "ante", "antes", "ancia", "ancias",
+		"logía", "logías", "ución", "uciones", "encia", "encias", "amente", "mente",
+		"idad", "idades", "iva", "ivo", "ivas", "ivos",
+	})
+	switch suffix {
+	case "":
+		return false
+	case "adora", "ador", "ación", "adoras", "adores", "aciones", "ante", "antes", "ancia", "ancias":
+		if !s.cut(suffix, r2) {
+			return false
+		}
+		s.cut("ic", r2)
+	case "logía", "logías":
+		return s.replace(suffix, "log", r2)
+	case "ución", "uciones":
+		return s.replace(suffix, "u", r2)
+	case "encia", "encias":
+		return s.replace(suffix, "ente", r2)
+	case "amente":
+		if !s.cut(suffix, r1) {
+			return false
+		}
+		if s.cut("iv", r2) {
+			s.cut("at", r2)
+		} else if !s.cut("os", r2) && !s.cut("ic", r2) {
+			s.cut("ad", r2)
+		}
+	case "mente":
+		if !s.cut(suffix, r2) {
+			return false
+		}
+		if !s.cut("ante", r2) && !s.cut("able", r2) {
+			s.cut("ible", r2)
+		}
+	case "idad", "idades":
+		if !s.cut(suffix, r2) {
+			return false
+		}
+		if !s.cut("abil", r2) && !s.cut("ic", r2) {
+			s.cut("iv", r2)
+		}
+	case "iva", "ivo", "ivas", "ivos":
+		if !s.cut(suffix, r2) {
+			return false
+		}
+		s.cut("at", r2)
+	default:
+		return s.cut(suffix, r2)
+	}
+	return true
+}
+
+// spanishYVerbSuffix removes a verb suffix beginning with y that follows a u and reports whether one was removed
+func spanishYVerbSuffix(s *suffixWord, rv int) bool {
+	suffix := s.longestIn([]string{"ya", "ye", "yan", "yen", "yeron", "yendo", "yo", "yó", "yas", "yes", "yais", "yamos"}, rv)
+	if suffix == "" || s.start(suffix) == 0 || s.w[s.start(suffix)-1] != 'u' {
+		return false
+	}
+	return s.cut(suffix, rv)
+}
+
+var spanishVerbSuffixes = []string{
+	"arían", "arías", "arán", "arás", "aríais", "aría", "aréis", "aríamos", "aremos", "ará", "aré",
+	"erían", "erías", "erán", "erás", "eríais", "ería", "eréis", "eríamos", "eremos", "erá", "eré",
+	"irían", "irías", "irán", "irás", "iríais", "iría", "iréis", "iríamos", "iremos", "irá", "iré",
+	"aba", "ada", "ida", "ía", "ara", "iera", "ad", "ed", "id", "ase", "iese", "aste", "iste", "an",
+	"aban", "ían", "aran", "ieran", "asen", "iesen", "aron", "ieron", "ado", "ido", "ando", "iendo",
+	"ió", "ar", "er", "ir", "as", "abas", "adas", "idas", "ías", "aras", "ieras", "ases", "ieses",
+	"ís", "áis", "abais", "íais", "arais", "ierais", "aseis", "ieseis", "asteis", "isteis", "ados",
+	"idos", "amos", "ábamos", "íamos", "imos", "áramos", "iéramos", "iésemos", "ásemos",
+	"en", "es", "éis", "emos",
+}
+
+func spanishVerbSuffix(s *suffixWord, rv int) {
+	// the suffix is searched within RV only, so a longer suffix crossing into it does not hide a shorter one
+	suffix := s.longestIn(spanishVerbSuffixes, rv)
+	if suffix == "" {
+		return
+	}
+	s.cut(suffix, rv)
+	switch suffix {
+	case "en", "es", "éis", "emos":
+		if s.endsWith("gu") {
+			s.w = s.w[:len(s.w)-1]
+		}
+	}
+}
+
+func spanishResidualSuffix(s *suffixWord, rv int) {
+	switch suffix := s.longestIn([]string{"os", "a", "o", "á", "í", "ó", "e", "é"}, rv); suffix {
+	case "":
+	case "e", "é":
+		s.cut(suffix, rv)
+		if s.endsWith("gu") && len(s.w)-1 >= rv {
+			s.w = s.w[:len(s.w)-1]
+		}
+	default:
+		s.cut(suffix, rv)
+	}
+}
+
+func removeAcute(char rune) rune {
+	switch char {
+	case 'á':
+		return 'a'
+	case 'é':
+		return 'e'
+	case 'í':
+		return 'i'
+	case 'ó':
+		return 'o'
+	case 'ú':
+		return 'u'
+	}
+	return char
+}
@@ -0,0 +1,1012 @@
+package fts
+
+// stopWordLists are the stop words removed by LanguageStopWordsFilter by ISO 639-1 language code
+var stopWordLists = map[string]map[string]bool{
+	"en": englishStopWords,
+	"es": spanishStopWords,
+	"de": germanStopWords,
+	"fr": frenchStopWords,
+	"it": italianStopWords,
+	"pt": portugueseStopWords,
+}
+
+var englishStopWords = map[string]bool{
+	"a":          true,
+	"about":      true,
+	"above":      true,
+	"after":      true,
+	"again":      true,
+	"against":    true,
+	"all":        true,
+	"am":         true,
+	"an":         true,
+	"and":        true,
+	"any":        true,
+	"are":        true,
+	"aren't":     true,
+	"as":         true,
+	"at":         true,
+	"be":         true,
+	"because":    true,
+	"been":       true,
+	"before":     true,
+	"being":      true,
+	"below":      true,
+	"between":    true,
+	"both":       true,
+	"but":        true,
+	"by":         true,
+	"can't":      true,
+	"cannot":     true,
+	"could":      true,
+	"couldn't":   true,
+	"did":        true,
+	"didn't":     true,
+	"do":         true,
+	"does":       true,
+	"doesn't":    true,
+	"doing":      true,
+	"don't":      true,
+	"down":       true,
+	"during":     true,
+	"each":       true,
+	"few":        true,
+	"for":        true,
+	"from":       true,
+	"further":    true,
+	"had":        true,
+	"hadn't":     true,
+	"has":        true,
+	"hasn't":     true,
+	"have":       true,
+	"haven't":    true,
+	"having":     true,
+	"he":         true,
+	"he'd":       true,
+	"he'll":      true,
+	"he's":       true,
+	"her":        true,
+	"here":       true,
+	"here's":     true,
+	"hers":       true,
+	"herself":    true,
+	"him":        true,
+	"himself":    true,
+	"his":        true,
+	"how":        true,
+	"how's":      true,
+	"i":          true,
+	"i'd":        true,
+	"i'll":       true,
+	"i'm":        true,
+	"i've":       true,
+	"if":         true,
+	"in":         true,
+	"into":       true,
+	"is":         true,
+	"isn't":      true,
+	"it":         true,
+	"it's":       true,
+	"its":        true,
+	"itself":     true,
+	"let's":      true,
+	"me":         true,
+	"more":       true,
+	"most":       true,
+	"mustn't":    true,
+	"my":         true,
+	"myself":     true,
+	"no":         true,
+	"nor":        true,
+	"not":        true,
+	"of":         true,
+	"off":        true,
+	"on":         true,
+	"once":       true,
+	"only":       true,
+	"or":         true,
+	"other":      true,
+	"ought":      true,
+	"our":        true,
+	"ours":       true,
+	"ourselves":  true,
+	"out":        true,
+	"over":       true,
+	"own":        true,
+	"same":       true,
+	"shan't":     true,
+	"she":        true,
+	"she'd":      true,
+	"she'll":     true,
+	"she's":      true,
+	"should":     true,
+	"shouldn't":  true,
+	"so":         true,
+	"some":       true,
+	"such":       true,
+	"than":       true,
+	"that":       true,
+	"that's":     true,
+	"the":        true,
+	"their":      true,
+	"theirs":     true,
+	"them":       true,
+	"themselves": true,
+	"then":       true,
+	"there":      true,
+	"there's":    true,
+	"these":      true,
+	"they":       true,
+	"they'd":     true,
+	"they'll":    true,
+	"they're":    true,
+	"they've":    true,
+	"this":       true,
+	"those":      true,
+	"through":    true,
+	"to":         true,
+	"too":        true,
+	"under":      true,
+	"until":      true,
+	"up":         true,
+	"very":       true,
+	"was":        true,
+	"wasn't":     true,
+	"we":         true,
+	"we'd":       true,
+	"we'll":      true,
+	"we're":      true,
+	"we've":      true,
+	"were":       true,
+	"weren't":    true,
+	"what":       true,
+	"what's":     true,
+	"when":       true,
+	"when's":     true,
+	"where":      true,
+	"where's":    true,
+	"which":      true,
+	"while":      true,
+	"who":        true,
+	"who's":      true,
+	"whom":       true,
+	"why":        true,
+	"why's":      true,
+	"with":       true,
+	"won't":      true,
+	"would":      true,
+	"wouldn't":   true,
+	"you":        true,
+	"you'd":      true,
+	"you'll":     true,
+	"you're":     true,
+	"you've":     true,
+	"your":       true,
+	"yours":      true,
+	"yourself":   true,
+	"yourselves": true,
+}
+
+// spanishStopWords are common spanish words
+var spanishStopWords = map[string]bool{
+	"de":         true,
+	"la":         true,
+	"que":        true,
+	"el":         true,
+	"en":         true,
+	"y":          true,
+	"a":          true,
+	"los":        true,
+	"del":        true,
+	"se":         true,
+	"las":        true,
+	"por":        true,
+	"un":         true,
+	"para":       true,
+	"con":        true,
+	"no":         true,
+	"una":        true,
+	"su":         true,
+	"al":         true,
+	"lo":         true,
+	"como":       true,
+	"más":        true,
+	"pero":       true,
+	"sus":        true,
+	"le":         true,
+	"ya":         true,
+	"o":          true,
+	"este":       true,
+	"sí":         true,
+	"porque":     true,
+	"esta":       true,
+	"entre":      true,
+	"cuando":     true,
+	"muy":        true,
+	"sin":        true,
+	"sobre":      true,
+	"también":    true,
+	"me":         true,
+	"hasta":      true,
+	"hay":        true,
+	"donde":      true,
+	"quien":      true,
+	"desde":      true,
+	"todo":       true,
+	"nos":        true,
+	"durante":    true,
+	"todos":      true,
+	"uno":        true,
+	"les":        true,
+	"ni":         true,
+	"contra":     true,
+	"otros":      true,
+	"ese":        true,
+	"eso":        true,
+	"ante":       true,
+	"ellos":      true,
+	"e":          true,
+	"esto":       true,
+	"mí":         true,
+	"antes":      true,
+	"algunos":    true,
+	"qué":        true,
+	"unos":       true,
+	"yo":         true,
+	"otro":       true,
+	"otras":      true,
+	"otra":       true,
+	"él":         true,
+	"tanto":      true,
+	"esa":        true,
+	"estos":      true,
+	"mucho":      true,
+	"quienes":    true,
+	"nada":       true,
+	"muchos":     true,
+	"cual":       true,
+	"poco":       true,
+	"ella":       true,
+	"estar":      true,
+	"estas":      true,
+	"algunas":    true,
+	"algo":       true,
+	"nosotros":   true,
+	"mi":         true,
+	"mis":        true,
+	"tú":         true,
+	"te":         true,
+	"ti":         true,
+	"tu":         true,
+	"tus":        true,
+	"ellas":      true,
+	"nosotras":   true,
+	"vosotros":   true,
+	"vosotras":   true,
+	"os":         true,
+	"mío":        true,
+	"mía":        true,
+	"míos":       true,
+	"mías":       true,
+	"tuyo":       true,
+	"tuya":       true,
+	"tuyos":      true,
+	"tuyas":      true,
+	"suyo":       true,
+	"suya":       true,
+	"suyos":      true,
+	"suyas":      true,
+	"nuestro":    true,
+	"nuestra":    true,
+	"nuestros":   true,
+	"nuestras":   true,
+	"vuestro":    true,
+	"vuestra":    true,
+	"vuestros":   true,
+	"vuestras":   true,
+	"esos":       true,
+	"esas":       true,
+	"estoy":      true,
+	"estás":      true,
+	"está":       true,
+	"estamos":    true,
+	"estáis":     true,
+	"están":      true,
+	"esté":       true,
+	"estés":      true,
+	"estemos":    true,
+	"estéis":     true,
+	"estén":      true,
+	"estaba":     true,
+	"estabas":    true,
+	"estábamos":  true,
+	"estaban":    true,
+	"estuve":     true,
+	"estuvo":     true,
+	"estuvimos":  true,
+	"estuvieron": true,
+	"he":         true,
+	"has":        true,
+	"ha":         true,
+	"hemos":      true,
+	"habéis":     true,
+	"han":        true,
+	"haya":       true,
+	"hayan":      true,
+	"había":      true,
+	"habían":     true,
+	"hube":       true,
+	"hubo":       true,
+	"soy":        true,
+	"eres":       true,
+	"es":         true,
+	"somos":      true,
+	"sois":       true,
+	"son":        true,
+	"sea":        true,
+	"sean":       true,
+	"era":        true,
+	"eras":       true,
+	"éramos":     true,
+	"eran":       true,
+	"fui":        true,
+	"fue":        true,
+	"fuimos":     true,
+	"fueron":     true,
+	"tengo":      true,
+	"tienes":     true,
+	"tiene":      true,
+	"tenemos":    true,
+	"tenéis":     true,
+	"tienen":     true,
+	"tenía":      true,
+	"tenían":     true,
+	"tuve":       true,
+	"tuvo":       true,
+}
+
+// germanStopWords are common german words
+var germanStopWords = map[string]bool{
+	"aber":      true,
+	"alle":      true,
+	"allem":     true,
+	"allen":     true,
+	"aller":     true,
+	"alles":     true,
+	"als":       true,
+	"also":      true,
+	"am":        true,
+	"an":        true,
+	"ander":     true,
+	"andere":    true,
+	"anderem":   true,
+	"anderen":   true,
+	"anderer":   true,
+	"anderes":   true,
+	"anderm":    true,
+	"andern":    true,
+	"anderr":    true,
+	"anders":    true,
+	"auch":      true,
+	"auf":       true,
+	"aus":       true,
+	"bei":       true,
+	"bin":       true,
+	"bis":       true,
+	"bist":      true,
+	"da":        true,
+	"damit":     true,
+	"dann":      true,
+	"das":       true,
+	"dass":      true,
+	"dasselbe":  true,
+	"dazu":      true,
+	"daß":       true,
+	"dein":      true,
+	"deine":     true,
+	"deinem":    true,
+	"deinen":    true,
+	"deiner":    true,
+	"deines":    true,
+	"dem":       true,
+	"demselben": true,
+	"den":       true,
+	"denn":      true,
+	"denselben": true,
+	"der":       true,
+	"derer":     true,
+	"derselbe":  true,
+	"derselben": true,
+	"des":       true,
+	"desselben": true,
+	"dessen":    true,
+	"dich":      true,
+	"die":       true,
+	"dies":      true,
+	"diese":     true,
+	"dieselbe":  true,
+	"dieselben": true,
+	"diesem":    true,
+	"diesen":    true,
+	"dieser":    true,
+	"dieses":    true,
+	"dir":       true,
+	"doch":      true,
+	"dort":      true,
+	"du":        true,
+	"durch":     true,
+	"ein":       true,
+	"eine":      true,
+	"einem":     true,
+	"einen":     true,
+	"einer":     true,
+	"eines":     true,
+	"einig":     true,
+	"einige":    true,
+	"einigem":   true,
+	"einigen":   true,
+	"einiger":   true,
+	"einiges":   true,
+	"einmal":    true,
+	"er":        true,
+	"es":        true,
+	"etwas":     true,
+	"euch":      true,
+	"euer":      true,
+	"eure":      true,
+	"eurem":     true,
+	"euren":     true,
+	"eurer":     true,
+	"eures":     true,
+	"für":       true,
+	"gegen":     true,
+	"gewesen":   true,
+	"hab":       true,
+	"habe":      true,
+	"haben":     true,
+	"hat":       true,
+	"hatte":     true,
+	"hatten":    true,
+	"hier":      true,
+	"hin":       true,
+	"hinter":    true,
+	"ich":       true,
+	"ihm":       true,
+	"ihn":       true,
+	"ihnen":     true,
+	"ihr":       true,
+	"ihre":      true,
+	"ihrem":     true,
+	"ihren":     true,
+	"ihrer":     true,
+	"ihres":     true,
+	"im":        true,
+	"in":        true,
+	"indem":     true,
+	"ins":       true,
+	"ist":       true,
+	"jede":      true,
+	"jedem":     true,
+	"jeden":     true,
+	"jeder":     true,
+	"jedes":     true,
+	"jene":      true,
+	"jenem":     true,
+	"jenen":     true,
+	"jener":     true,
+	"jenes":     true,
+	"jetzt":     true,
+	"kann":      true,
+	"kein":      true,
+	"keine":     true,
+	"keinem":    true,
+	"keinen":    true,
+	"keiner":    true,
+	"keines":    true,
+	"können":    true,
+	"könnte":    true,
+	"machen":    true,
+	"man":       true,
+	"manche":    true,
+	"manchem":   true,
+	"manchen":   true,
+	"mancher":   true,
+	"manches":   true,
+	"mein":      true,
+	"meine":     true,
+	"meinem":    true,
+	"meinen":    true,
+	"meiner":    true,
+	"meines":    true,
+	"mich":      true,
+	"mir":       true,
+	"mit":       true,
+	"muss":      true,
+	"musste":    true,
+	"nach":      true,
+	"nicht":     true,
+	"nichts":    true,
+	"noch":      true,
+	"nun":       true,
+	"nur":       true,
+	"ob":        true,
+	"oder":      true,
+	"ohne":      true,
+	"sehr":      true,
+	"sein":      true,
+	"seine":     true,
+	"seinem":    true,
+	"seinen":    true,
+	"seiner":    true,
+	"seines":    true,
+	"selbst":    true,
+	"sich":      true,
+	"sie":       true,
+	"sind":      true,
+	"so":        true,
+	"solche":    true,
+	"solchem":   true,
+	"solchen":   true,
+	"solcher":   true,
+	"solches":   true,
+	"soll":      true,
+	"sollte":    true,
+	"sondern":   true,
+	"sonst":     true,
+	"über":      true,
+	"um":        true,
+	"und":       true,
+	"uns":       true,
+	"unsere":    true,
+	"unserem":   true,
+	"unseren":   true,
+	"unser":     true,
+	"unseres":   true,
+	"unter":     true,
+	"viel":      true,
+	"vom":       true,
+	"von":       true,
+	"vor":       true,
+	"während":   true,
+	"war":       true,
+	"waren":     true,
+	"warst":     true,
+	"was":       true,
+	"weg":       true,
+	"weil":      true,
+	"weiter":    true,
+	"welche":    true,
+	"welchem":   true,
+	"welchen":   true,
+	"welcher":   true,
+	"welches":   true,
+	"wenn":      true,
+	"werde":     true,
+	"werden":    true,
+	"wie":       true,
+	"wieder":    true,
+	"will":      true,
+	"wir":       true,
+	"wird":      true,
+	"wirst":     true,
+	"wo":        true,
+	"wollen":    true,
+	"wollte":    true,
+	"würde":     true,
+	"würden":    true,
+	"zu":        true,
+	"zum":       true,
+	"zur":       true,
+	"zwar":      true,
+	"zwischen":  true,
+}
+
+// frenchStopWords are common french words
+var frenchStopWords = map[string]bool{
+	"au":      true,
+	"aux":     true,
+	"avec":    true,
+	"ce":      true,
+	"ces":     true,
+	"dans":    true,
+	"de":      true,
+	"des":     true,
+	"du":      true,
+	"elle":    true,
+	"en":      true,
+	"et":      true,
+	"eux":     true,
+	"il":      true,
+	"ils":     true,
+	"je":      true,
+	"la":      true,
+	"le":      true,
+	"les":     true,
+	"leur":    true,
+	"lui":     true,
+	"ma":      true,
+	"mais":    true,
+	"me":      true,
+	"même":    true,
+	"mes":     true,
+	"moi":     true,
+	"mon":     true,
+	"ne":      true,
+	"nos":     true,
+	"notre":   true,
+	"nous":    true,
+	"on":      true,
+	"ou":      true,
+	"par":     true,
+	"pas":     true,
+	"pour":    true,
+	"qu":      true,
+	"que":     true,
+	"qui":     true,
+	"sa":      true,
+	"se":      true,
+	"ses":     true,
+	"son":     true,
+	"sur":     true,
+	"ta":      true,
+	"te":      true,
+	"tes":     true,
+	"toi":     true,
+	"ton":     true,
+	"tu":      true,
+	"un":      true,
+	"une":     true,
+	"vos":     true,
+	"votre":   true,
+	"vous":    true,
+	"c":       true,
+	"d":       true,
+	"j":       true,
+	"l":       true,
+	"à":       true,
+	"m":       true,
+	"n":       true,
+	"s":       true,
+	"t":       true,
+	"y":       true,
+	"été":     true,
+	"étée":    true,
+	"étées":   true,
+	"étés":    true,
+	"étant":   true,
+	"suis":    true,
+	"es":      true,
+	"est":     true,
+	"sommes":  true,
+	"êtes":    true,
+	"sont":    true,
+	"serai":   true,
+	"sera":    true,
+	"serons":  true,
+	"seront":  true,
+	"étais":   true,
+	"était":   true,
+	"étions":  true,
+	"étaient": true,
+	"fus":     true,
+	"fut":     true,
+	"furent":  true,
+	"sois":    true,
+	"soit":    true,
+	"soyons":  true,
+	"soient":  true,
+	"ai":      true,
+	"as":      true,
+	"avons":   true,
+	"avez":    true,
+	"ont":     true,
+	"aurai":   true,
+	"aura":    true,
+	"aurons":  true,
+	"auront":  true,
+	"avais":   true,
+	"avait":   true,
+	"avions":  true,
+	"avaient": true,
+	"eus":     true,
+	"eut":     true,
+	"eurent":  true,
+	"aie":     true,
+	"ait":     true,
+	"ayons":   true,
+	"aient":   true,
+	"ceci":    true,
+	"cela":    true,
+	"celà":    true,
+	"cet":     true,
+	"cette":   true,
+	"ici":     true,
+	"leurs":   true,
+	"quel":    true,
+	"quels":   true,
+	"quelle":  true,
+	"quelles": true,
+	"sans":    true,
+	"soi":     true,
+}
+
+// italianStopWords are common italian words
+var italianStopWords = map[string]bool{
+	"ad":      true,
+	"al":      true,
+	"allo":    true,
+	"ai":      true,
+	"agli":    true,
+	"all":     true,
+	"agl":     true,
+	"alla":    true,
+	"alle":    true,
+	"con":     true,
+	"col":     true,
+	"coi":     true,
+	"da":      true,
+	"dal":     true,
+	"dallo":   true,
+	"dai":     true,
+	"dagli":   true,
+	"dall":    true,
+	"dagl":    true,
+	"dalla":   true,
+	"dalle":   true,
+	"di":      true,
+	"del":     true,
+	"dello":   true,
+	"dei":     true,
+	"degli":   true,
+	"dell":    true,
+	"degl":    true,
+	"della":   true,
+	"delle":   true,
+	"in":      true,
+	"nel":     true,
+	"nello":   true,
+	"nei":     true,
+	"negli":   true,
+	"nell":    true,
+	"negl":    true,
+	"nella":   true,
+	"nelle":   true,
+	"su":      true,
+	"sul":     true,
+	"sullo":   true,
+	"sui":     true,
+	"sugli":   true,
+	"sull":    true,
+	"sugl":    true,
+	"sulla":   true,
+	"sulle":   true,
+	"per":     true,
+	"tra":     true,
+	"contro":  true,
+	"io":      true,
+	"tu":      true,
+	"lui":     true,
+	"lei":     true,
+	"noi":     true,
+	"voi":     true,
+	"loro":    true,
+	"mio":     true,
+	"mia":     true,
+	"miei":    true,
+	"mie":     true,
+	"tuo":     true,
+	"tua":     true,
+	"tuoi":    true,
+	"tue":     true,
+	"suo":     true,
+	"sua":     true,
+	"suoi":    true,
+	"sue":     true,
+	"nostro":  true,
+	"nostra":  true,
+	"nostri":  true,
+	"nostre":  true,
+	"vostro":  true,
+	"vostra":  true,
+	"vostri":  true,
+	"vostre":  true,
+	"mi":      true,
+	"ti":      true,
+	"ci":      true,
+	"vi":      true,
+	"lo":      true,
+	"la":      true,
+	"li":      true,
+	"le":      true,
+	"gli":     true,
+	"ne":      true,
+	"il":      true,
+	"un":      true,
+	"uno":     true,
+	"una":     true,
+	"ma":      true,
+	"ed":      true,
+	"se":      true,
+	"perché":  true,
+	"anche":   true,
+	"come":    true,
+	"dov":     true,
+	"dove":    true,
+	"che":     true,
+	"chi":     true,
+	"cui":     true,
+	"non":     true,
+	"più":     true,
+	"quale":   true,
+	"quanto":  true,
+	"quanti":  true,
+	"quanta":  true,
+	"quante":  true,
+	"quello":  true,
+	"quelli":  true,
+	"quella":  true,
+	"quelle":  true,
+	"questo":  true,
+	"questi":  true,
+	"questa":  true,
+	"queste":  true,
+	"si":      true,
+	"tutto":   true,
+	"tutti":   true,
+	"a":       true,
+	"c":       true,
+	"e":       true,
+	"i":       true,
+	"l":       true,
+	"o":       true,
+	"ho":      true,
+	"hai":     true,
+	"ha":      true,
+	"abbiamo": true,
+	"avete":   true,
+	"hanno":   true,
+	"sono":    true,
+	"sei":     true,
+	"è":       true,
+	"siamo":   true,
+	"siete":   true,
+	"era":     true,
+	"erano":   true,
+	"fu":      true,
+	"furono":  true,
+	"essere":  true,
+	"avere":   true,
+}
+
+// portugueseStopWords are common portuguese words
+var portugueseStopWords = map[string]bool{
+	"de":        true,
+	"a":         true,
+	"o":         true,
+	"que":       true,
+	"e":         true,
+	"do":        true,
+	"da":        true,
+	"em":        true,
+	"um":        true,
+	"para":      true,
+	"com":       true,
+	"não":       true,
+	"uma":       true,
+	"os":        true,
+	"no":        true,
+	"se":        true,
+	"na":        true,
+	"por":       true,
+	"mais":      true,
+	"as":        true,
+	"dos":       true,
+	"como":      true,
+	"mas":       true,
+	"ao":        true,
+	"ele":       true,
+	"das":       true,
+	"à":         true,
+	"seu":       true,
+	"sua":       true,
+	"ou":        true,
+	"quando":    true,
+	"muito":     true,
+	"nos":       true,
+	"já":        true,
+	"eu":        true,
+	"também":    true,
+	"só":        true,
+	"pelo":      true,
+	"pela":      true,
+	"até":       true,
+	"isso":      true,
+	"ela":       true,
+	"entre":     true,
+	"depois":    true,
+	"sem":       true,
+	"mesmo":     true,
+	"aos":       true,
+	"seus":      true,
+	"quem":      true,
+	"nas":       true,
+	"me":        true,
+	"esse":      true,
+	"eles":      true,
+	"você":      true,
+	"essa":      true,
+	"num":       true,
+	"nem":       true,
+	"suas":      true,
+	"meu":       true,
+	"às":        true,
+	"minha":     true,
+	"numa":      true,
+	"pelos":     true,
+	"elas":      true,
+	"qual":      true,
+	"nós":       true,
+	"lhe":       true,
+	"deles":     true,
+	"essas":     true,
+	"esses":     true,
+	"pelas":     true,
+	"este":      true,
+	"dele":      true,
+	"tu":        true,
+	"te":        true,
+	"vocês":     true,
+	"vos":       true,
+	"lhes":      true,
+	"meus":      true,
+	"minhas":    true,
+	"teu":       true,
+	"tua":       true,
+	"teus":      true,
+	"tuas":      true,
+	"nosso":     true,
+	"nossa":     true,
+	"nossos":    true,
+	"nossas":    true,
+	"dela":      true,
+	"delas":     true,
+	"esta":      true,
+	"estes":     true,
+	"estas":     true,
+	"aquele":    true,
+	"aquela":    true,
+	"aqueles":   true,
+	"aquelas":   true,
+	"isto":      true,
+	"aquilo":    true,
+	"estou":     true,
+	"está":      true,
+	"estamos":   true,
+	"estão":     true,
+	"estive":    true,
+	"esteve":    true,
+	"estivemos": true,
+	"estiveram": true,
+	"era":       true,
+	"eram":      true,
+	"fui":       true,
+	"foi":       true,
+	"fomos":     true,
+	"foram":     true,
+	"sou":       true,
+	"somos":     true,
+	"são":       true,
+	"ser":       true,
+	"tenho":     true,
+	"tem":       true,
+	"temos":     true,
+	"têm":       true,
+	"tinha":     true,
+	"tinham":    true,
+	"tive":      true,
+	"teve":      true,
+	"tivemos":   true,
+	"tiveram":   true,
+	"há":        true,
+	"havia":     true,
+}
@@ -0,0 +1,334 @@
+package fts
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync/atomic"
+
+	"github.com/sujit-baniya/pkg/maps"
+)
+
+// snapshotMagic identifies an index snapshot written by Save
+const snapshotMagic = "FTS"
+
+// snapshotVersion is bumped whenever the snapshot layout changes
+const snapshotVersion = 1
+
+var ErrInvalidSnapshot = errors.New("fts: invalid index snapshot")
+
+// Save writes a snapshot of the documents and the inverted index to w
+// The snapshot stores postings with delta encoded ids so Load does not need to tokenize documents again
+// Writes happening while Save runs may or may not be part of the snapshot
+func (db *FTS[Schema]) Save(w io.Writer) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	docLengths := db.lengths.Snapshot()
+	ids := make([]int64, 0, len(docLengths))
+	for id := range docLengths {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// field names are written once up front and referenced by their position afterwards
+	fieldIds := make(map[string]uint64)
+	var fields []string
+	for _, lengths := range docLengths {
+		for name := range lengths {
+			if _, ok := fieldIds[name]; !ok {
+				fieldIds[name] = 0
+				fields = append(fields, name)
+			}
+		}
+	}
+	sort.Strings(fields)
+	for i, name := range fields {
+		fieldIds[name] = uint64(i)
+	}
+
+	var tokens []string
+	for token, infos := range db.index.All() {
+		if infos.Len() > 0 {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Strings(tokens)
+
+	enc := &encoder{w: bufio.NewWriter(w)}
+	enc.writeString(snapshotMagic)
+	enc.writeUvarint(snapshotVersion)
+	enc.writeString(db.key)
+	enc.writeUvarint(uint64(len(fields)))
+	for _, name := range fields {
+		enc.writeString(name)
+	}
+
+	enc.writeUvarint(uint64(len(ids)))
+	for _, id := range ids {
+		doc, _ := db.docs.Get(id)
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		enc.writeVarint(id)
+		enc.writeBytes(data)
+		lengths := docLengths[id]
+		enc.writeUvarint(uint64(len(lengths)))
+		for name, length := range lengths {
+			enc.writeUvarint(fieldIds[name])
+			enc.writeUvarint(uint64(length))
+		}
+	}
+
+	enc.writeUvarint(uint64(len(tokens)))
+	for _, token := range tokens {
+		infos, _ := db.index.Get(token)
+		postings := make(map[int64]RecordInfo)
+		postingIds := make([]int64, 0, infos.Len())
+		for id, info := range infos.All() {
+			// postings of documents outside the snapshot would point at missing documents
+			if _, ok := docLengths[id]; ok {
+				postings[id] = info
+				postingIds = append(postingIds, id)
+			}
+		}
+		sort.Slice(postingIds, func(i, j int) bool { return postingIds[i] < postingIds[j] })
+		enc.writeString(token)
+		enc.writeUvarint(uint64(len(postingIds)))
+		var prev int64
+		for _, id := range postingIds {
+			info := postings[id]
+			enc.writeVarint(id - prev)
+			prev = id
+			enc.writeUvarint(uint64(len(info.Fields)))
+			for _, field := range info.Fields {
+				enc.writeUvarint(fieldIds[field.Name])
+				enc.writeUvarint(uint64(field.Frequency))
+				enc.writeUvarint(uint64(len(field.Positions)))
+				last := 0
+				for _, position := range field.Positions {
+					enc.writeUvarint(uint64(position - last))
+					last = position
+				}
+				enc.writeUvarint(uint64(len(field.Offsets)))
+				last = 0
+				for _, offset := range field.Offsets {
+					enc.writeUvarint(uint64(offset.Start - last))
+					enc.writeUvarint(uint64(offset.End - offset.Start))
+					last = offset.Start
+				}
+			}
+		}
+	}
+	return enc.flush()
+}
+
+// Load replaces the documents and the inverted index with a snapshot written by Save
+// The rules and boosts of db are kept, they are not part of the snapshot
+// Calls running while Load swaps the index in see either the old or the new one
+func (db *FTS[Schema]) Load(r io.Reader) error {
+	dec := &decoder{r: bufio.NewReader(r)}
+	if dec.readString() != snapshotMagic {
+		return ErrInvalidSnapshot
+	}
+	if version := dec.readUvarint(); dec.err == nil && version != snapshotVersion {
+		return fmt.Errorf("fts: unsupported snapshot version %d", version)
+	}
+	key := dec.readString()
+
+	var fields []string
+	for i := dec.readUvarint(); i > 0 && dec.err == nil; i-- {
+		fields = append(fields, dec.readString())
+	}
+	field := func(id uint64) string {
+		if id >= uint64(len(fields)) {
+			dec.fail(ErrInvalidSnapshot)
+			return ""
+		}
+		return fields[id]
+	}
+	if dec.err != nil {
+		return dec.err
+	}
+
+	docCount := dec.readUvarint()
+	docs := maps.New[int64, Schema](sizeHint(docCount))
+	lengths := maps.New[int64, map[string]int](sizeHint(docCount))
+	fieldLengths := maps.New[string, *atomic.Int64]()
+	totals := make(map[string]int64, len(fields))
+	for i := uint64(0); i < docCount && dec.err == nil; i++ {
+		id := dec.readVarint()
+		var doc Schema
+		if data := dec.readBytes(); dec.err == nil {
+			if err := json.Unmarshal(data, &doc); err != nil {
+				return err
+			}
+		}
+		docLengths := make(map[string]int)
+		for j := dec.readUvarint(); j > 0 && dec.err == nil; j-- {
+			name := field(dec.readUvarint())
+			length := dec.readUvarint()
+			docLengths[name] = int(length)
+			totals[name] += int64(length)
+		}
+		docs.Set(id, doc)
+		lengths.Set(id, docLengths)
+	}
+	for name, total := range totals {
+		length := &atomic.Int64{}
+		length.Store(total)
+		fieldLengths.Set(name, length)
+	}
+
+	tokenCount := dec.readUvarint()
+	index := maps.New[string, maps.IMap[int64, RecordInfo]](sizeHint(tokenCount))
+	terms := newDictionary()
+	for i := uint64(0); i < tokenCount && dec.err == nil; i++ {
+		token := dec.readString()
+		terms.add(token)
+		postingCount := dec.readUvarint()
+		infos := maps.New[int64, RecordInfo](sizeHint(postingCount))
+		var id int64
+		for j := uint64(0); j < postingCount && dec.err == nil; j++ {
+			id += dec.readVarint()
+			var info RecordInfo
+			for k := dec.readUvarint(); k > 0 && dec.err == nil; k-- {
+				fieldInfo := FieldInfo{Name: field(dec.readUvarint()), Frequency: int(dec.readUvarint())}
+				position := 0
+				for l := dec.readUvarint(); l > 0 && dec.err == nil; l-- {
+					position += int(dec.readUvarint())
+					fieldInfo.Positions = append(fieldInfo.Positions, position)
+				}
+				start := 0
+				for l := dec.readUvarint(); l > 0 && dec.err == nil; l-- {
+					start += int(dec.readUvarint())
+					fieldInfo.Offsets = append(fieldInfo.Offsets, Offset{Start: start, End: start + int(dec.readUvarint())})
+				}
+				info.Fields = append(info.Fields, fieldInfo)
+				info.Frequency += fieldInfo.Frequency
+			}
+			infos.Set(id, info)
+		}
+		index.Set(token, infos)
+	}
+	if dec.err != nil {
+		if errors.Is(dec.err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return dec.err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.key = key
+	db.docs = docs
+	db.index = index
+	db.lengths = lengths
+	db.fieldLengths = fieldLengths
+	db.terms = terms
+	return nil
+}
+
+// sizeHint bounds the initial map size taken from a snapshot so a corrupt count cannot exhaust memory
+func sizeHint(count uint64) uintptr {
+	if count > 1<<20 {
+		return 1 << 20
+	}
+	return uintptr(count) + 1
+}
+
+// encoder writes length prefixed values and keeps the first error
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *encoder) write(p []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+}
+
+func (e *encoder) writeUvarint(v uint64) {
+	e.write(e.buf[:binary.PutUvarint(e.buf[:], v)])
+}
+
+func (e *encoder) writeVarint(v int64) {
+	e.write(e.buf[:binary.PutVarint(e.buf[:], v)])
+}
+
+func (e *encoder) writeBytes(p []byte) {
+	e.writeUvarint(uint64(len(p)))
+	e.write(p)
+}
+
+func (e *encoder) writeString(s string) {
+	e.writeUvarint(uint64(len(s)))
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+func (e *encoder) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// decoder reads values written by encoder and keeps the first error
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+// maxSnapshotString guards against allocating huge buffers for a corrupt length prefix
+const maxSnapshotString = 1 << 30
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) readUvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	d.fail(err)
+	return v
+}
+
+func (d *decoder) readVarint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	d.fail(err)
+	return v
+}
+
+func (d *decoder) readBytes() []byte {
+	n := d.readUvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > maxSnapshotString {
+		d.fail(ErrInvalidSnapshot)
+		return nil
+	}
+	p := make([]byte, n)
+	_, err := io.ReadFull(d.r, p)
+	d.fail(err)
+	return p
+}
+
+func (d *decoder) readString() string {
+	return string(d.readBytes())
+}
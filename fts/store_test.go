@@ -0,0 +1,87 @@
+package fts
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	db := newTestIndex(t,
+		"diabetes in pregnancy",
+		"gestational diabetes screening",
+		"pregnancy nutrition",
+	)
+	var buf bytes.Buffer
+	if err := db.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New[map[string]any]("test")
+	if err := loaded.Load(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.DocumentLen() != db.DocumentLen() || loaded.IndexLen() != db.IndexLen() {
+		t.Fatalf("loaded %d documents and %d tokens, want %d and %d", loaded.DocumentLen(), loaded.IndexLen(), db.DocumentLen(), db.IndexLen())
+	}
+	for _, query := range []string{"diabetes", "pregnancy", "diabetes pregnancy", "nutrition"} {
+		want := db.Search(query, Option{Size: 10})
+		got := loaded.Search(query, Option{Size: 10})
+		if len(got) != len(want) {
+			t.Fatalf("Search(%q) returned %d hits after Load, want %d", query, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].Id != want[i].Id || got[i].Score != want[i].Score || got[i].S["text"] != want[i].S["text"] {
+				t.Errorf("Search(%q) hit %d = %+v after Load, want %+v", query, i, got[i], want[i])
+			}
+		}
+	}
+
+	if _, err := loaded.Insert(map[string]any{"text": "diabetes diet"}); err != nil {
+		t.Fatal(err)
+	}
+	if hits := loaded.Search("diabetes", Option{Size: 10}); len(hits) != 3 {
+		t.Errorf("Search after inserting into a loaded index returned %d hits, want 3", len(hits))
+	}
+}
+
+func TestLoadInvalidSnapshot(t *testing.T) {
+	db := newTestIndex(t, "diabetes in pregnancy")
+	var buf bytes.Buffer
+	if err := db.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := buf.Bytes()
+
+	var header bytes.Buffer
+	enc := &encoder{w: bufio.NewWriter(&header)}
+	enc.writeString(snapshotMagic)
+	enc.writeUvarint(snapshotVersion + 1)
+	if err := enc.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "magic", data: []byte("not a snapshot")},
+		{name: "version", data: header.Bytes()},
+		{name: "truncated", data: snapshot[:len(snapshot)/2]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loaded := newTestIndex(t, "pregnancy nutrition")
+			if err := loaded.Load(bytes.NewReader(test.data)); err == nil {
+				t.Fatal("Load succeeded")
+			}
+			if hits := loaded.Search("nutrition"); len(hits) != 1 {
+				t.Errorf("a failed Load replaced the index, Search returned %d hits", len(hits))
+			}
+		})
+	}
+	if err := New[map[string]any]("test").Load(bytes.NewReader([]byte("not a snapshot"))); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("Load of a foreign file returned %v, want ErrInvalidSnapshot", err)
+	}
+}
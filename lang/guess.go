@@ -8,7 +8,7 @@ import (
 	"regexp"
 	"strings"
 	"unicode/utf8"
-	"verify/utils/lang/models"
+	"github.com/sujit-baniya/pkg/lang/models"
 )
 
 var (
@@ -0,0 +1,543 @@
+package maps
+
+import (
+	"encoding/json"
+	"errors"
+	"iter"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoLoader       = errors.New("maps: cache has no loader")
+	ErrLoaderPanicked = errors.New("maps: cache loader panicked")
+)
+
+// EvictionReason tells why an entry left a Cache
+type EvictionReason int
+
+const (
+	// Evicted entries made room for new ones once the cache reached its capacity
+	Evicted EvictionReason = iota
+	// Expired entries outlived their TTL
+	Expired
+	// Removed entries were deleted explicitly
+	Removed
+)
+
+type (
+	// CacheConfig configures a Cache, the zero value is an unbounded cache without expiration
+	CacheConfig[K hashable, V any] struct {
+		// Capacity bounds the number of entries, entries are evicted with Policy once it is reached, 0 means unbounded
+		Capacity int
+		Policy   EvictionPolicy
+		// TTL is the default time to live of the entries, 0 means they never expire
+		TTL time.Duration
+		// CleanupInterval is the period at which expired entries are removed in the background
+		// Expired entries are otherwise removed when they are accessed
+		CleanupInterval time.Duration
+		// Loader computes the value of a missing key for Load
+		Loader func(K) (V, error)
+		// OnEvict is called after an entry left the cache, it may use the cache
+		OnEvict func(key K, value V, reason EvictionReason)
+	}
+
+	// CacheStats are the counters of a Cache since it was created
+	CacheStats struct {
+		Hits        uint64 `json:"hits"`
+		Misses      uint64 `json:"misses"`
+		Evictions   uint64 `json:"evictions"`
+		Expirations uint64 `json:"expirations"`
+		Loads       uint64 `json:"loads"`
+		LoadErrors  uint64 `json:"load_errors"`
+	}
+
+	// Cache is a concurrent map whose entries expire and are evicted once it is full
+	// Reads are lock-free for unbounded caches, bounded caches lock to record the use of an entry
+	Cache[K hashable, V any] struct {
+		items  *Map[K, *cacheEntry[V]]
+		config CacheConfig[K, V]
+		mu     sync.Mutex // serializes writes with the eviction policy
+		policy evictionPolicy[K]
+		calls  map[K]*loadCall[V]
+		callMu sync.Mutex
+		done   chan struct{}
+		once   sync.Once
+
+		hits, misses, evictions, expirations, loads, loadErrors atomicUintptr
+	}
+
+	cacheEntry[V any] struct {
+		value    V
+		expireAt int64 // unix nanoseconds, 0 when the entry does not expire
+	}
+
+	// loadCall is a Loader call shared by every concurrent Load of the same key
+	loadCall[V any] struct {
+		wg    sync.WaitGroup
+		value V
+		err   error
+	}
+
+	// eviction is an entry removed while holding the lock, its callback is called once the lock is released
+	eviction[K hashable, V any] struct {
+		key    K
+		value  V
+		reason EvictionReason
+	}
+)
+
+// NewCache returns a new Cache, Close must be called to stop the background cleanup when CleanupInterval is set
+func NewCache[K hashable, V any](config CacheConfig[K, V]) *Cache[K, V] {
+	c := &Cache[K, V]{
+		items:  New[K, *cacheEntry[V]](),
+		config: config,
+		calls:  make(map[K]*loadCall[V]),
+		done:   make(chan struct{}),
+	}
+	if config.Capacity > 0 {
+		c.policy = newEvictionPolicy[K](config.Policy, config.Capacity, c.policyHasher)
+	}
+	if config.CleanupInterval > 0 {
+		go c.cleanup(config.CleanupInterval)
+	}
+	return c
+}
+
+// Del deletes key/keys from the cache
+func (c *Cache[K, V]) Del(keys ...K) {
+	var evictions []eviction[K, V]
+	c.mu.Lock()
+	for _, key := range keys {
+		if e, ok := c.items.GetAndDel(key); ok {
+			c.untrack(key)
+			evictions = append(evictions, eviction[K, V]{key: key, value: e.value, reason: Removed})
+		}
+	}
+	c.mu.Unlock()
+	c.notify(evictions)
+}
+
+// Get retrieves an entry from the cache
+// returns `false“ if the entry is absent or expired
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	e, ok := c.items.Get(key)
+	if ok && e.expired(time.Now().UnixNano()) {
+		c.expire(key, e)
+		ok = false
+	}
+	if !ok {
+		c.misses.Add(1)
+		return
+	}
+	c.hits.Add(1)
+	if c.policy != nil {
+		c.mu.Lock()
+		c.policy.access(key)
+		c.mu.Unlock()
+	}
+	return e.value, true
+}
+
+// Set inserts or updates an entry with the default TTL
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.config.TTL)
+}
+
+// SetWithTTL inserts or updates an entry that expires after ttl, 0 means it never expires
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	evictions := c.set(key, value, ttl)
+	c.mu.Unlock()
+	c.notify(evictions)
+}
+
+// GetOrSet returns the existing value for the key if present
+// Otherwise, it stores and returns the given value
+// The loaded result is true if the value was loaded, false if stored
+func (c *Cache[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	if actual, loaded = c.Get(key); loaded {
+		return
+	}
+	c.mu.Lock()
+	if e, ok := c.items.Get(key); ok && !e.expired(time.Now().UnixNano()) {
+		c.mu.Unlock()
+		return e.value, true
+	}
+	evictions := c.set(key, value, c.config.TTL)
+	c.mu.Unlock()
+	c.notify(evictions)
+	return value, false
+}
+
+// GetOrCompute is similar to GetOrSet but the value to be set is obtained from a constructor
+// concurrent calls for the same missing key share a single call of the constructor,
+// loaded is false only for the call that ran it and stored its value
+func (c *Cache[K, V]) GetOrCompute(key K, valueFn func() V) (actual V, loaded bool) {
+	if actual, loaded = c.Get(key); loaded {
+		return
+	}
+	actual, err, loaded := c.load(key, func(K) (V, error) { return valueFn(), nil })
+	return actual, loaded && err == nil
+}
+
+// Load returns the value of key, a missing key is computed with CacheConfig.Loader and stored
+// concurrent loads of the same key share a single call of the loader
+func (c *Cache[K, V]) Load(key K) (V, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+	if c.config.Loader == nil {
+		var value V
+		return value, ErrNoLoader
+	}
+	value, err, _ := c.load(key, c.config.Loader)
+	return value, err
+}
+
+// GetAndDel deletes the key from the cache, returning the previous value if any.
+func (c *Cache[K, V]) GetAndDel(key K) (value V, ok bool) {
+	c.mu.Lock()
+	e, ok := c.items.GetAndDel(key)
+	if ok {
+		c.untrack(key)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	if e.expired(time.Now().UnixNano()) {
+		c.expirations.Add(1)
+		c.notify([]eviction[K, V]{{key: key, value: e.value, reason: Expired}})
+		return value, false
+	}
+	c.notify([]eviction[K, V]{{key: key, value: e.value, reason: Removed}})
+	return e.value, true
+}
+
+// CompareAndSwap updates an entry given its key by comparing current value to `oldValue`
+// and setting it to `newValue` if the above comparison is successful, the expiration is kept
+// It returns a boolean indicating whether the CompareAndSwap was successful or not
+func (c *Cache[K, V]) CompareAndSwap(key K, oldValue, newValue V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items.Get(key)
+	if !ok || e.expired(time.Now().UnixNano()) || !reflect.DeepEqual(e.value, oldValue) {
+		return false
+	}
+	c.items.Set(key, &cacheEntry[V]{value: newValue, expireAt: e.expireAt})
+	return true
+}
+
+// Swap swaps the value of an entry given its key, the expiration is kept
+// It returns the old value if swap was successful and a boolean `swapped` indicating whether the swap was successful or not
+func (c *Cache[K, V]) Swap(key K, newValue V) (oldValue V, swapped bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items.Get(key)
+	if !ok || e.expired(time.Now().UnixNano()) {
+		return
+	}
+	c.items.Set(key, &cacheEntry[V]{value: newValue, expireAt: e.expireAt})
+	return e.value, true
+}
+
+// ForEach iterates over the entries that have not expired and executes the lambda provided for each such pair
+// lambda must return `true` to continue iteration and `false` to break iteration
+func (c *Cache[K, V]) ForEach(lambda func(K, V) bool) {
+	now := time.Now().UnixNano()
+	c.items.ForEach(func(key K, e *cacheEntry[V]) bool {
+		if e.expired(now) {
+			return true
+		}
+		return lambda(key, e.value)
+	})
+}
+
+// All returns an iterator over the entries that have not expired
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return c.ForEach
+}
+
+// Keys returns an iterator over the keys of the entries that have not expired
+func (c *Cache[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.ForEach(func(key K, _ V) bool { return yield(key) })
+	}
+}
+
+// Values returns an iterator over the values of the entries that have not expired
+func (c *Cache[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		c.ForEach(func(_ K, value V) bool { return yield(value) })
+	}
+}
+
+// SetMany sets every entry of entries with the default TTL
+func (c *Cache[K, V]) SetMany(entries map[K]V) {
+	var evictions []eviction[K, V]
+	c.mu.Lock()
+	for key, value := range entries {
+		evictions = append(evictions, c.set(key, value, c.config.TTL)...)
+	}
+	c.mu.Unlock()
+	c.notify(evictions)
+}
+
+// DelIf deletes the entries for which pred returns true and returns the number of deleted entries
+func (c *Cache[K, V]) DelIf(pred func(K, V) bool) int {
+	var keys []K
+	c.ForEach(func(key K, value V) bool {
+		if pred(key, value) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	c.Del(keys...)
+	return len(keys)
+}
+
+// Clear deletes every entry, the stats are kept
+func (c *Cache[K, V]) Clear() {
+	now := time.Now().UnixNano()
+	var evictions []eviction[K, V]
+	c.mu.Lock()
+	for key, e := range c.items.Snapshot() {
+		reason := Removed
+		if e.expired(now) {
+			reason = Expired
+		}
+		evictions = append(evictions, eviction[K, V]{key: key, value: e.value, reason: reason})
+	}
+	c.items.Clear()
+	if c.policy != nil {
+		c.policy = newEvictionPolicy[K](c.config.Policy, c.config.Capacity, c.policyHasher)
+	}
+	c.mu.Unlock()
+	c.notify(evictions)
+}
+
+// Clone returns a new cache with the same configuration and the entries that have not expired
+// the entries keep their expiration, Close must be called on the clone when CleanupInterval is set
+func (c *Cache[K, V]) Clone() IMap[K, V] {
+	clone := NewCache[K, V](c.config)
+	clone.items.SetHasher(c.items.hasher)
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	for key, e := range c.items.Snapshot() {
+		if !e.expired(now) {
+			clone.store(key, e)
+		}
+	}
+	c.mu.Unlock()
+	return clone
+}
+
+// Snapshot returns a weakly consistent copy of the entries that have not expired, see Map.Snapshot
+func (c *Cache[K, V]) Snapshot() map[K]V {
+	now := time.Now().UnixNano()
+	entries := c.items.Snapshot()
+	gomap := make(map[K]V, len(entries))
+	for key, e := range entries {
+		if !e.expired(now) {
+			gomap[key] = e.value
+		}
+	}
+	return gomap
+}
+
+// Grow resizes the underlying map, see Map.Grow
+func (c *Cache[K, V]) Grow(newSize uintptr) {
+	c.items.Grow(newSize)
+}
+
+// SetHasher sets the hash function to the one provided by the user
+// it must be called before the cache is used
+func (c *Cache[K, V]) SetHasher(hs func(K) uintptr) {
+	c.items.SetHasher(hs)
+}
+
+// Len returns the number of entries within the cache, including expired entries not removed yet
+func (c *Cache[K, V]) Len() uintptr {
+	return c.items.Len()
+}
+
+// FillRate returns the percentage of the capacity in use, or the fill rate of the underlying map when unbounded
+func (c *Cache[K, V]) FillRate() uintptr {
+	if c.config.Capacity > 0 {
+		return c.items.Len() * 100 / uintptr(c.config.Capacity)
+	}
+	return c.items.FillRate()
+}
+
+// Stats returns the counters of the cache
+func (c *Cache[K, V]) Stats() CacheStats {
+	return CacheStats{
+		Hits:        uint64(c.hits.Load()),
+		Misses:      uint64(c.misses.Load()),
+		Evictions:   uint64(c.evictions.Load()),
+		Expirations: uint64(c.expirations.Load()),
+		Loads:       uint64(c.loads.Load()),
+		LoadErrors:  uint64(c.loadErrors.Load()),
+	}
+}
+
+// HitRatio returns the share of Get calls that found an entry
+func (s CacheStats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// Close stops the background cleanup
+func (c *Cache[K, V]) Close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (c *Cache[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Snapshot())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, entries get the default TTL
+func (c *Cache[K, V]) UnmarshalJSON(i []byte) error {
+	gomap := make(map[K]V)
+	err := json.Unmarshal(i, &gomap)
+	if err != nil {
+		return err
+	}
+	c.SetMany(gomap)
+	return nil
+}
+
+// set stores an entry and returns the evictions it caused, the lock must be held
+func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) []eviction[K, V] {
+	e := &cacheEntry[V]{value: value}
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl).UnixNano()
+	}
+	return c.store(key, e)
+}
+
+// store is set with the expiration already computed
+func (c *Cache[K, V]) store(key K, e *cacheEntry[V]) []eviction[K, V] {
+	_, exists := c.items.Get(key)
+	c.items.Set(key, e)
+	if c.policy == nil {
+		return nil
+	}
+	if exists {
+		c.policy.access(key)
+		return nil
+	}
+	var evictions []eviction[K, V]
+	for _, victim := range c.policy.add(key) {
+		if old, ok := c.items.GetAndDel(victim); ok {
+			c.evictions.Add(1)
+			evictions = append(evictions, eviction[K, V]{key: victim, value: old.value, reason: Evicted})
+		}
+	}
+	return evictions
+}
+
+// expire removes an expired entry unless it was replaced meanwhile
+func (c *Cache[K, V]) expire(key K, e *cacheEntry[V]) {
+	c.mu.Lock()
+	current, ok := c.items.Get(key)
+	if !ok || current != e {
+		c.mu.Unlock()
+		return
+	}
+	c.items.Del(key)
+	c.untrack(key)
+	c.mu.Unlock()
+	c.expirations.Add(1)
+	c.notify([]eviction[K, V]{{key: key, value: e.value, reason: Expired}})
+}
+
+// load calls loader once for all the concurrent loads of key and stores its value on success
+// A value stored by a Set while the loader ran is kept and returned instead of the loaded one
+// loaded is true when the value returned was not computed by this call, either because it waited on the call of another one
+// or because a concurrent Set won, loads that waited get ErrLoaderPanicked if the loader panics
+func (c *Cache[K, V]) load(key K, loader func(K) (V, error)) (value V, err error, loaded bool) {
+	c.callMu.Lock()
+	if call, ok := c.calls[key]; ok {
+		c.callMu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err, true
+	}
+	call := &loadCall[V]{err: ErrLoaderPanicked}
+	call.wg.Add(1)
+	c.calls[key] = call
+	c.callMu.Unlock()
+	defer func() {
+		c.callMu.Lock()
+		delete(c.calls, key)
+		c.callMu.Unlock()
+		call.wg.Done()
+	}()
+
+	c.loads.Add(1)
+	value, err = loader(key)
+	call.value, call.err = value, err
+	if err != nil {
+		c.loadErrors.Add(1)
+		return value, err, false
+	}
+	c.mu.Lock()
+	if e, ok := c.items.Get(key); ok && !e.expired(time.Now().UnixNano()) {
+		c.mu.Unlock()
+		call.value = e.value
+		return e.value, nil, true
+	}
+	evictions := c.set(key, value, c.config.TTL)
+	c.mu.Unlock()
+	c.notify(evictions)
+	return value, nil, false
+}
+
+// policyHasher hashes keys with the hasher of the underlying map, which SetHasher may replace
+func (c *Cache[K, V]) policyHasher(key K) uintptr {
+	return c.items.hasher(key)
+}
+
+func (c *Cache[K, V]) untrack(key K) {
+	if c.policy != nil {
+		c.policy.remove(key)
+	}
+}
+
+func (c *Cache[K, V]) notify(evictions []eviction[K, V]) {
+	if c.config.OnEvict == nil {
+		return
+	}
+	for _, e := range evictions {
+		c.config.OnEvict(e.key, e.value, e.reason)
+	}
+}
+
+// cleanup removes the expired entries every interval until the cache is closed
+func (c *Cache[K, V]) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			now := time.Now().UnixNano()
+			c.items.ForEach(func(key K, e *cacheEntry[V]) bool {
+				if e.expired(now) {
+					c.expire(key, e)
+				}
+				return true
+			})
+		}
+	}
+}
+
+func (e *cacheEntry[V]) expired(now int64) bool {
+	return e.expireAt > 0 && now >= e.expireAt
+}
@@ -0,0 +1,121 @@
+package maps
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheLoaderPanicReleasesKey(t *testing.T) {
+	fail := true
+	c := NewCache[string, int](CacheConfig[string, int]{Loader: func(string) (int, error) {
+		if fail {
+			panic("boom")
+		}
+		return 1, nil
+	}})
+	defer c.Close()
+	func() {
+		defer func() { recover() }()
+		c.Load("k")
+	}()
+	fail = false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if value, err := c.Load("k"); err != nil || value != 1 {
+			t.Errorf("Load() = %d, %v, want 1, nil", value, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Load blocked after the loader panicked")
+	}
+}
+
+func TestCacheLoaderPanicFailsWaiters(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	c := NewCache[string, int](CacheConfig[string, int]{Loader: func(string) (int, error) {
+		close(started)
+		<-release
+		panic("boom")
+	}})
+	defer c.Close()
+	go func() {
+		defer func() { recover() }()
+		c.Load("k")
+	}()
+	<-started
+	errs := make(chan error)
+	go func() {
+		_, err := c.Load("k")
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond) // let the second Load join the call
+	close(release)
+	if err := <-errs; !errors.Is(err, ErrLoaderPanicked) {
+		t.Fatalf("waiting Load error = %v, want ErrLoaderPanicked", err)
+	}
+}
+
+func TestCacheGetOrComputeShared(t *testing.T) {
+	c := NewCache[string, int](CacheConfig[string, int]{})
+	defer c.Close()
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	computed := 0
+	results := make([]bool, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, results[i] = c.GetOrCompute("k", func() int {
+				<-release
+				mu.Lock()
+				computed++
+				mu.Unlock()
+				return 1
+			})
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	fresh := 0
+	for _, loaded := range results {
+		if !loaded {
+			fresh++
+		}
+	}
+	if computed != 1 || fresh != 1 {
+		t.Fatalf("computed %d times with %d unloaded results, want 1 and 1", computed, fresh)
+	}
+}
+
+func TestCacheGetOrComputeKeepsConcurrentSet(t *testing.T) {
+	c := NewCache[string, int](CacheConfig[string, int]{})
+	defer c.Close()
+	actual, loaded := c.GetOrCompute("k", func() int {
+		// a Set racing with the constructor wins over the computed value
+		c.Set("k", 2)
+		return 1
+	})
+	if actual != 2 || !loaded {
+		t.Fatalf("GetOrCompute = %d, %v, want 2, true", actual, loaded)
+	}
+	if value, ok := c.Get("k"); !ok || value != 2 {
+		t.Fatalf("Get = %d, %v, want 2, true", value, ok)
+	}
+
+	c = NewCache[string, int](CacheConfig[string, int]{Loader: func(key string) (int, error) {
+		c.Set(key, 3)
+		return 1, nil
+	}})
+	defer c.Close()
+	if value, err := c.Load("k"); err != nil || value != 3 {
+		t.Fatalf("Load = %d, %v, want 3, nil", value, err)
+	}
+}
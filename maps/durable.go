@@ -0,0 +1,730 @@
+package maps
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// SyncPolicy tells when a Durable map flushes its log to stable storage
+type SyncPolicy int
+
+const (
+	// SyncInterval flushes the logs every DurableConfig.SyncInterval, a crash of the machine loses the writes of the last interval
+	SyncInterval SyncPolicy = iota
+	// SyncAlways flushes the log on every write
+	SyncAlways
+	// SyncNever leaves flushing to the operating system, writes survive a crash of the process but not of the machine
+	SyncNever
+)
+
+const (
+	walExtension = ".wal"
+	// compactExtension is appended to the path of a log while compaction writes its replacement
+	compactExtension = ".compact"
+
+	// record operations
+	opSet byte = 1
+	opDel byte = 2
+
+	// recordHeaderSize is the size of the length and the checksum preceding every record payload
+	recordHeaderSize = 8
+
+	// maxRecordSize bounds the payload allocated while replaying a damaged log
+	maxRecordSize = 1 << 30
+
+	// minCompactRecords is the number of stale records below which a log is never compacted
+	minCompactRecords = 1024
+
+	defaultShards          = 4
+	defaultSyncInterval    = time.Second
+	defaultCompactInterval = time.Minute
+	defaultCompactRatio    = 1
+)
+
+var (
+	ErrDurableClosed = errors.New("maps: durable map is closed")
+	ErrCorruptRecord = errors.New("maps: corrupt log record")
+
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+)
+
+type (
+	// DurableConfig configures a Durable map, only Dir is required
+	DurableConfig struct {
+		// Dir holds one log file per shard
+		Dir string
+		// Shards is the number of logs keys are spread over, writes to different shards do not wait for each other
+		Shards int
+		Sync   SyncPolicy
+		// SyncInterval is the flush period of SyncInterval, it defaults to a second
+		SyncInterval time.Duration
+		// CompactInterval is the period at which logs are checked for compaction, it defaults to a minute
+		CompactInterval time.Duration
+		// CompactRatio is the number of stale records per live key above which a log is rewritten, it defaults to 1
+		CompactRatio float64
+		// Codec encodes keys and values, it defaults to JSONCodec
+		Codec Codec
+	}
+
+	// Durable is a concurrent map whose writes are appended to checksummed write-ahead logs
+	// Reads are served from memory, the logs are replayed on open and compacted in the background
+	// IMap methods cannot return errors, the first write error is kept and returned by Err
+	Durable[K hashable, V any] struct {
+		items  *Map[K, V]
+		config DurableConfig
+		shards []*walShard
+		errMu  sync.Mutex
+		err    error
+		done   chan struct{}
+		wg     sync.WaitGroup
+		once   sync.Once
+	}
+
+	// walShard is the log of the keys hashing to one shard, the lock is held while a key of the shard is written
+	walShard struct {
+		mu      sync.Mutex
+		path    string
+		file    *os.File
+		records int // records in the log
+		live    int // keys of the shard in memory
+		dirty   bool
+	}
+)
+
+// OpenDurable opens the logs in config.Dir, creating it when needed, and replays them
+// A torn record at the end of a log, left by a crash during a write, is discarded,
+// a record whose checksum holds but which cannot be decoded fails with ErrCorruptRecord naming its offset
+// Close must be called to flush the logs and stop the background work
+func OpenDurable[K hashable, V any](config DurableConfig) (*Durable[K, V], error) {
+	if config.Dir == "" {
+		return nil, errors.New("maps: durable map requires a directory")
+	}
+	if config.Shards <= 0 {
+		config.Shards = defaultShards
+	}
+	if config.SyncInterval <= 0 {
+		config.SyncInterval = defaultSyncInterval
+	}
+	if config.CompactInterval <= 0 {
+		config.CompactInterval = defaultCompactInterval
+	}
+	if config.CompactRatio <= 0 {
+		config.CompactRatio = defaultCompactRatio
+	}
+	if config.Codec == nil {
+		config.Codec = JSONCodec{}
+	}
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, err
+	}
+	d := &Durable[K, V]{items: New[K, V](), config: config, done: make(chan struct{})}
+
+	// a compacted log only replaces its log once renamed, one left behind was cut short by a crash
+	leftovers, err := filepath.Glob(filepath.Join(config.Dir, "*"+walExtension+compactExtension))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range leftovers {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := filepath.Glob(filepath.Join(config.Dir, "*"+walExtension))
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]int, len(existing))
+	for _, path := range existing {
+		if records[path], err = d.replay(path); err != nil {
+			return nil, err
+		}
+	}
+
+	expected := make(map[string]bool, config.Shards)
+	for i := 0; i < config.Shards; i++ {
+		path := filepath.Join(config.Dir, fmt.Sprintf("%03d%s", i, walExtension))
+		expected[path] = true
+		d.shards = append(d.shards, &walShard{path: path, records: records[path]})
+	}
+	for _, s := range d.shards {
+		if s.file, err = openLog(s.path); err != nil {
+			d.closeFiles()
+			return nil, err
+		}
+	}
+	d.items.ForEach(func(key K, _ V) bool {
+		if s, _, err := d.locate(key); err == nil {
+			s.live++
+		}
+		return true
+	})
+
+	// keys are spread over the shards by hash, a different number of shards means every log is rewritten
+	changed := len(existing) > 0 && len(existing) != len(expected)
+	for _, path := range existing {
+		changed = changed || !expected[path]
+	}
+	if changed {
+		for _, s := range d.shards {
+			if err := d.compact(s); err != nil {
+				d.closeFiles()
+				return nil, err
+			}
+		}
+		for _, path := range existing {
+			if !expected[path] {
+				if err := os.Remove(path); err != nil {
+					d.closeFiles()
+					return nil, err
+				}
+			}
+		}
+	}
+
+	d.wg.Add(1)
+	go d.background()
+	return d, nil
+}
+
+// Del deletes key/keys from the map
+func (d *Durable[K, V]) Del(keys ...K) {
+	for _, key := range keys {
+		d.GetAndDel(key)
+	}
+}
+
+// Get retrieves an element from the map
+// returns `false“ if element is absent
+func (d *Durable[K, V]) Get(key K) (value V, ok bool) {
+	return d.items.Get(key)
+}
+
+// Set logs and stores an element, the element is not stored when it cannot be logged
+func (d *Durable[K, V]) Set(key K, value V) {
+	s, kb, err := d.locate(key)
+	if d.failed(err) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.set(s, key, kb, value)
+}
+
+// GetOrSet returns the existing value for the key if present
+// Otherwise, it logs, stores and returns the given value
+// The loaded result is true if the value was loaded, false if stored
+func (d *Durable[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	s, kb, err := d.locate(key)
+	if d.failed(err) {
+		return value, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if actual, loaded = d.items.Get(key); loaded {
+		return
+	}
+	d.set(s, key, kb, value)
+	return value, false
+}
+
+// GetOrCompute is similar to GetOrSet but the value to be set is obtained from a constructor
+// the value constructor is called without holding a lock, concurrent calls for the same missing key
+// may each call it, only the value stored first is logged and returned
+func (d *Durable[K, V]) GetOrCompute(key K, valueFn func() V) (actual V, loaded bool) {
+	if actual, loaded = d.items.Get(key); loaded {
+		return
+	}
+	return d.GetOrSet(key, valueFn())
+}
+
+// GetAndDel deletes the key from the map, returning the previous value if any.
+func (d *Durable[K, V]) GetAndDel(key K) (value V, ok bool) {
+	s, kb, err := d.locate(key)
+	if d.failed(err) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value, ok = d.items.Get(key); !ok {
+		return
+	}
+	if d.failed(d.append(s, opDel, kb, nil)) {
+		return value, false
+	}
+	d.items.Del(key)
+	s.live--
+	return value, true
+}
+
+// CompareAndSwap updates a map entry given its key by comparing current value to `oldValue`
+// and setting it to `newValue` if the above comparison is successful
+// It returns a boolean indicating whether the CompareAndSwap was successful or not
+func (d *Durable[K, V]) CompareAndSwap(key K, oldValue, newValue V) bool {
+	s, kb, err := d.locate(key)
+	if d.failed(err) {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := d.items.Get(key); !ok || !reflect.DeepEqual(current, oldValue) {
+		return false
+	}
+	return d.set(s, key, kb, newValue)
+}
+
+// Swap swaps the value of a map entry given its key
+// It returns the old value if swap was successful and a boolean `swapped` indicating whether the swap was successful or not
+func (d *Durable[K, V]) Swap(key K, newValue V) (oldValue V, swapped bool) {
+	s, kb, err := d.locate(key)
+	if d.failed(err) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if oldValue, swapped = d.items.Get(key); !swapped {
+		return
+	}
+	return oldValue, d.set(s, key, kb, newValue)
+}
+
+// ForEach iterates over key-value pairs and executes the lambda provided for each such pair
+// lambda must return `true` to continue iteration and `false` to break iteration
+func (d *Durable[K, V]) ForEach(lambda func(K, V) bool) {
+	d.items.ForEach(lambda)
+}
+
+// All returns an iterator over key-value pairs
+func (d *Durable[K, V]) All() iter.Seq2[K, V] {
+	return d.items.All()
+}
+
+// Keys returns an iterator over keys
+func (d *Durable[K, V]) Keys() iter.Seq[K] {
+	return d.items.Keys()
+}
+
+// Values returns an iterator over values
+func (d *Durable[K, V]) Values() iter.Seq[V] {
+	return d.items.Values()
+}
+
+// SetMany logs and stores every entry of entries
+func (d *Durable[K, V]) SetMany(entries map[K]V) {
+	for key, value := range entries {
+		d.Set(key, value)
+	}
+}
+
+// DelIf deletes the entries for which pred returns true and returns the number of deleted entries
+func (d *Durable[K, V]) DelIf(pred func(K, V) bool) int {
+	var keys []K
+	d.items.ForEach(func(key K, value V) bool {
+		if pred(key, value) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	deleted := 0
+	for _, key := range keys {
+		if _, ok := d.GetAndDel(key); ok {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Clear deletes every entry and truncates the logs
+func (d *Durable[K, V]) Clear() {
+	for _, s := range d.shards {
+		s.mu.Lock()
+	}
+	defer func() {
+		for _, s := range d.shards {
+			s.mu.Unlock()
+		}
+	}()
+	for _, s := range d.shards {
+		if s.file == nil {
+			d.failed(ErrDurableClosed)
+			return
+		}
+		if d.failed(s.file.Truncate(0)) || d.failed(s.file.Sync()) {
+			return
+		}
+		s.records, s.live, s.dirty = 0, 0, false
+	}
+	d.items.Clear()
+}
+
+// Clone returns an in-memory Map with the entries of a Snapshot, it is not persisted
+func (d *Durable[K, V]) Clone() IMap[K, V] {
+	return d.items.Clone()
+}
+
+// Snapshot returns a weakly consistent copy of the entries, see Map.Snapshot
+func (d *Durable[K, V]) Snapshot() map[K]V {
+	return d.items.Snapshot()
+}
+
+// Grow resizes the in-memory map, see Map.Grow
+func (d *Durable[K, V]) Grow(newSize uintptr) {
+	d.items.Grow(newSize)
+}
+
+// SetHasher sets the hash function of the in-memory map, shards are chosen from the encoded keys and do not depend on it
+func (d *Durable[K, V]) SetHasher(hs func(K) uintptr) {
+	d.items.SetHasher(hs)
+}
+
+// Len returns the number of key-value pairs within the map
+func (d *Durable[K, V]) Len() uintptr {
+	return d.items.Len()
+}
+
+// FillRate returns the fill rate of the in-memory map as an percentage integer
+func (d *Durable[K, V]) FillRate() uintptr {
+	return d.items.FillRate()
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d *Durable[K, V]) MarshalJSON() ([]byte, error) {
+	return d.items.MarshalJSON()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, the entries are logged
+func (d *Durable[K, V]) UnmarshalJSON(i []byte) error {
+	gomap := make(map[K]V)
+	err := json.Unmarshal(i, &gomap)
+	if err != nil {
+		return err
+	}
+	d.SetMany(gomap)
+	return d.Err()
+}
+
+// Err returns the first error met while writing the logs
+func (d *Durable[K, V]) Err() error {
+	d.errMu.Lock()
+	defer d.errMu.Unlock()
+	return d.err
+}
+
+// Sync flushes the logs written since the last flush to stable storage
+func (d *Durable[K, V]) Sync() error {
+	var first error
+	for _, s := range d.shards {
+		s.mu.Lock()
+		if s.file != nil && s.dirty {
+			if err := s.file.Sync(); err != nil && first == nil {
+				first = err
+			}
+			s.dirty = false
+		}
+		s.mu.Unlock()
+	}
+	return first
+}
+
+// Compact rewrites every log with the live entries only
+func (d *Durable[K, V]) Compact() error {
+	for _, s := range d.shards {
+		s.mu.Lock()
+		err := d.compact(s)
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the background work, flushes and closes the logs
+func (d *Durable[K, V]) Close() error {
+	d.once.Do(func() {
+		close(d.done)
+		d.wg.Wait()
+	})
+	err := d.Sync()
+	if closeErr := d.closeFiles(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// set logs and stores an element and reports whether it was stored, the shard lock must be held
+func (d *Durable[K, V]) set(s *walShard, key K, kb []byte, value V) bool {
+	vb, err := d.config.Codec.Marshal(value)
+	if d.failed(err) || d.failed(d.append(s, opSet, kb, vb)) {
+		return false
+	}
+	if _, ok := d.items.Get(key); !ok {
+		s.live++
+	}
+	d.items.Set(key, value)
+	return true
+}
+
+// append writes a record to the log of a shard, the shard lock must be held
+// a record is its payload length and checksum followed by the operation, the key length, the key and the value
+func (d *Durable[K, V]) append(s *walShard, op byte, key, value []byte) error {
+	if s.file == nil {
+		return ErrDurableClosed
+	}
+	if _, err := s.file.Write(encodeRecord(op, key, value)); err != nil {
+		return err
+	}
+	s.records++
+	if d.config.Sync == SyncAlways {
+		return s.file.Sync()
+	}
+	s.dirty = true
+	return nil
+}
+
+// locate returns the shard of key and its encoding
+func (d *Durable[K, V]) locate(key K) (*walShard, []byte, error) {
+	kb, err := d.config.Codec.Marshal(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d.shardOf(kb), kb, nil
+}
+
+func (d *Durable[K, V]) shardOf(kb []byte) *walShard {
+	return d.shards[crc32.Checksum(kb, castagnoli)%uint32(len(d.shards))]
+}
+
+// failed keeps the first error and reports whether err is not nil
+func (d *Durable[K, V]) failed(err error) bool {
+	if err == nil {
+		return false
+	}
+	d.errMu.Lock()
+	if d.err == nil {
+		d.err = err
+	}
+	d.errMu.Unlock()
+	return true
+}
+
+// replay applies the records of a log to the in-memory map and returns their count
+// the log is truncated after the last valid record
+func (d *Durable[K, V]) replay(path string) (int, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var (
+		r       = bufio.NewReader(f)
+		header  [recordHeaderSize]byte
+		offset  int64
+		records int
+		torn    bool
+	)
+	for {
+		if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+			break
+		} else if err == io.ErrUnexpectedEOF {
+			torn = true
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		length := binary.LittleEndian.Uint32(header[:4])
+		if length == 0 || length > maxRecordSize {
+			torn = true
+			break
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err == io.ErrUnexpectedEOF || err == io.EOF {
+			torn = true
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(header[4:]) {
+			torn = true
+			break
+		}
+		if err := d.apply(payload); err != nil {
+			if errors.Is(err, ErrCorruptRecord) {
+				return 0, fmt.Errorf("%w: %s at offset %d", err, path, offset)
+			}
+			return 0, fmt.Errorf("%w: %s at offset %d: %w", ErrCorruptRecord, path, offset, err)
+		}
+		offset += recordHeaderSize + int64(length)
+		records++
+	}
+	if torn {
+		if err := os.Truncate(path, offset); err != nil {
+			return 0, err
+		}
+	}
+	return records, nil
+}
+
+// apply decodes a record payload and applies it to the in-memory map
+func (d *Durable[K, V]) apply(payload []byte) error {
+	op := payload[0]
+	keyLength, n := binary.Uvarint(payload[1:])
+	if n <= 0 || keyLength > uint64(len(payload)-1-n) {
+		return ErrCorruptRecord
+	}
+	kb, vb := payload[1+n:1+n+int(keyLength)], payload[1+n+int(keyLength):]
+	var key K
+	if err := d.config.Codec.Unmarshal(kb, &key); err != nil {
+		return err
+	}
+	switch op {
+	case opSet:
+		var value V
+		if err := d.config.Codec.Unmarshal(vb, &value); err != nil {
+			return err
+		}
+		d.items.Set(key, value)
+	case opDel:
+		d.items.Del(key)
+	default:
+		return ErrCorruptRecord
+	}
+	return nil
+}
+
+// compact rewrites the log of a shard with a set record per live key, the shard lock must be held
+// the new log is written aside and renamed over the old one so a crash leaves either of them intact
+func (d *Durable[K, V]) compact(s *walShard) error {
+	if s.file == nil {
+		return ErrDurableClosed
+	}
+	tmp := s.path + compactExtension
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600) // #nosec G304
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	records := 0
+	d.items.ForEach(func(key K, value V) bool {
+		var kb, vb []byte
+		if kb, err = d.config.Codec.Marshal(key); err != nil {
+			return false
+		}
+		if d.shardOf(kb) != s {
+			return true
+		}
+		if vb, err = d.config.Codec.Marshal(value); err != nil {
+			return false
+		}
+		if _, err = w.Write(encodeRecord(opSet, kb, vb)); err != nil {
+			return false
+		}
+		records++
+		return true
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := syncDir(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+	_ = s.file.Close()
+	if s.file, err = openLog(s.path); err != nil {
+		return err
+	}
+	s.records, s.live, s.dirty = records, records, false
+	return nil
+}
+
+// background flushes and compacts the logs until the map is closed
+func (d *Durable[K, V]) background() {
+	defer d.wg.Done()
+	var flush <-chan time.Time
+	if d.config.Sync == SyncInterval {
+		ticker := time.NewTicker(d.config.SyncInterval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+	compaction := time.NewTicker(d.config.CompactInterval)
+	defer compaction.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-flush:
+			d.failed(d.Sync())
+		case <-compaction.C:
+			for _, s := range d.shards {
+				s.mu.Lock()
+				if stale := s.records - s.live; stale >= minCompactRecords && float64(stale) > d.config.CompactRatio*float64(s.live) {
+					d.failed(d.compact(s))
+				}
+				s.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (d *Durable[K, V]) closeFiles() error {
+	var first error
+	for _, s := range d.shards {
+		s.mu.Lock()
+		if s.file != nil {
+			if err := s.file.Close(); err != nil && first == nil {
+				first = err
+			}
+			s.file = nil
+		}
+		s.mu.Unlock()
+	}
+	return first
+}
+
+func encodeRecord(op byte, key, value []byte) []byte {
+	record := make([]byte, recordHeaderSize, recordHeaderSize+1+binary.MaxVarintLen64+len(key)+len(value))
+	record = append(record, op)
+	record = binary.AppendUvarint(record, uint64(len(key)))
+	record = append(record, key...)
+	record = append(record, value...)
+	payload := record[recordHeaderSize:]
+	binary.LittleEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(payload, castagnoli))
+	return record
+}
+
+func openLog(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600) // #nosec G304
+}
+
+func syncDir(dir string) error {
+	f, err := os.Open(dir) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,139 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestDurable(t *testing.T, dir string) *Durable[string, int] {
+	t.Helper()
+	d, err := OpenDurable[string, int](DurableConfig{Dir: dir, Shards: 1, Sync: SyncNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func closeTestDurable(t *testing.T, d *Durable[string, int]) {
+	t.Helper()
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func logPath(dir string) string {
+	return filepath.Join(dir, "000"+walExtension)
+}
+
+func TestDurableReplay(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestDurable(t, dir)
+	d.Set("a", 1)
+	d.Set("b", 2)
+	d.Set("b", 3)
+	d.Del("a")
+	closeTestDurable(t, d)
+
+	d = openTestDurable(t, dir)
+	defer closeTestDurable(t, d)
+	if got := d.Snapshot(); len(got) != 1 || got["b"] != 3 {
+		t.Fatalf("replayed %v, want map[b:3]", got)
+	}
+}
+
+func TestDurableCompact(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestDurable(t, dir)
+	for i := 0; i < 100; i++ {
+		d.Set(fmt.Sprint(i%10), i)
+	}
+	before, _ := os.Stat(logPath(dir))
+	if err := d.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(logPath(dir))
+	if after.Size() >= before.Size() {
+		t.Errorf("log grew from %d to %d bytes on compaction", before.Size(), after.Size())
+	}
+	d.Set("x", 1)
+	closeTestDurable(t, d)
+
+	d = openTestDurable(t, dir)
+	defer closeTestDurable(t, d)
+	got := d.Snapshot()
+	if len(got) != 11 || got["3"] != 93 || got["x"] != 1 {
+		t.Fatalf("replayed %v after compaction", got)
+	}
+}
+
+func TestDurableTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestDurable(t, dir)
+	d.Set("a", 1)
+	closeTestDurable(t, d)
+	info, _ := os.Stat(logPath(dir))
+	record := encodeRecord(opSet, []byte(`"b"`), []byte(`2`))
+	appendTo(t, logPath(dir), record[:len(record)-1])
+
+	d = openTestDurable(t, dir)
+	defer closeTestDurable(t, d)
+	if got := d.Snapshot(); len(got) != 1 || got["a"] != 1 {
+		t.Fatalf("replayed %v, want map[a:1]", got)
+	}
+	if truncated, _ := os.Stat(logPath(dir)); truncated.Size() != info.Size() {
+		t.Errorf("log is %d bytes, want the torn record cut back to %d", truncated.Size(), info.Size())
+	}
+}
+
+func TestDurableRemovesLeftoverCompaction(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestDurable(t, dir)
+	d.Set("a", 1)
+	closeTestDurable(t, d)
+	leftover := logPath(dir) + compactExtension
+	if err := os.WriteFile(leftover, []byte("partial"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d = openTestDurable(t, dir)
+	defer closeTestDurable(t, d)
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Errorf("leftover compaction file still exists: %v", err)
+	}
+	if got := d.Snapshot(); len(got) != 1 || got["a"] != 1 {
+		t.Fatalf("replayed %v, want map[a:1]", got)
+	}
+}
+
+func TestDurableUndecodableRecord(t *testing.T) {
+	dir := t.TempDir()
+	d := openTestDurable(t, dir)
+	d.Set("a", 1)
+	closeTestDurable(t, d)
+	info, _ := os.Stat(logPath(dir))
+	appendTo(t, logPath(dir), encodeRecord(opSet, []byte(`"b"`), []byte(`{`)))
+
+	_, err := OpenDurable[string, int](DurableConfig{Dir: dir, Shards: 1})
+	if !errors.Is(err, ErrCorruptRecord) {
+		t.Fatalf("OpenDurable error = %v, want ErrCorruptRecord", err)
+	}
+	if want := fmt.Sprintf("offset %d", info.Size()); !strings.Contains(err.Error(), want) {
+		t.Errorf("OpenDurable error = %v, want it to name %s", err, want)
+	}
+}
+
+func appendTo(t *testing.T, path string, data []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,5 +1,7 @@
 package maps
 
+import "iter"
+
 type IMap[K comparable, V any] interface {
 	Del(keys ...K)
 	Get(key K) (value V, ok bool)
@@ -10,6 +12,14 @@ type IMap[K comparable, V any] interface {
 	CompareAndSwap(key K, oldValue, newValue V) bool
 	Swap(key K, newValue V) (oldValue V, swapped bool)
 	ForEach(lambda func(K, V) bool)
+	All() iter.Seq2[K, V]
+	Keys() iter.Seq[K]
+	Values() iter.Seq[V]
+	SetMany(entries map[K]V)
+	DelIf(pred func(K, V) bool) int
+	Clear()
+	Clone() IMap[K, V]
+	Snapshot() map[K]V
 	Grow(newSize uintptr)
 	SetHasher(hs func(K) uintptr)
 	Len() uintptr
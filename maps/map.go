@@ -2,6 +2,7 @@ package maps
 
 import (
 	"encoding/json"
+	"iter"
 	"reflect"
 	"sort"
 	"strconv"
@@ -76,6 +77,10 @@ func New[K hashable, V any](size ...uintptr) *Map[K, V] {
 // Del deletes key/keys from the map
 // Bulk deletion is more efficient than deleting keys one by one
 func (m *Map[K, V]) Del(keys ...K) {
+	m.del(keys...)
+}
+
+func (m *Map[K, V]) del(keys ...K) {
 	size := len(keys)
 	switch {
 	case size == 0:
@@ -151,6 +156,10 @@ func (m *Map[K, V]) Get(key K) (value V, ok bool) {
 // If a resizing operation is happening concurrently while calling Set()
 // then the item might show up in the map only after the resize operation is finished
 func (m *Map[K, V]) Set(key K, value V) {
+	m.set(key, value)
+}
+
+func (m *Map[K, V]) set(key K, value V) {
 	var (
 		h        = m.hasher(key)
 		valPtr   = &value
@@ -340,6 +349,80 @@ func (m *Map[K, V]) ForEach(lambda func(K, V) bool) {
 	}
 }
 
+// All returns an iterator over key-value pairs, like ForEach it does not block writes
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return m.ForEach
+}
+
+// Keys returns an iterator over keys
+func (m *Map[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.ForEach(func(key K, _ V) bool { return yield(key) })
+	}
+}
+
+// Values returns an iterator over values
+func (m *Map[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.ForEach(func(_ K, value V) bool { return yield(value) })
+	}
+}
+
+// SetMany sets every entry of entries
+func (m *Map[K, V]) SetMany(entries map[K]V) {
+	for key, value := range entries {
+		m.set(key, value)
+	}
+}
+
+// DelIf deletes the entries for which pred returns true and returns the number of deleted entries
+// pred must not modify the map
+func (m *Map[K, V]) DelIf(pred func(K, V) bool) int {
+	var keys []K
+	m.ForEach(func(key K, value V) bool {
+		if pred(key, value) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	m.del(keys...)
+	return len(keys)
+}
+
+// Clear deletes the entries present when it walks the map, entries set while it runs may be kept
+func (m *Map[K, V]) Clear() {
+	var keys []K
+	m.ForEach(func(key K, _ V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	m.del(keys...)
+}
+
+// Clone returns a new map with the entries of a Snapshot and the same hasher
+func (m *Map[K, V]) Clone() IMap[K, V] {
+	entries := m.Snapshot()
+	size := uintptr(len(entries)) * 100 / maxFillRate
+	if size < defaultSize {
+		size = defaultSize
+	}
+	clone := New[K, V](size)
+	clone.hasher = m.hasher
+	clone.SetMany(entries)
+	return clone
+}
+
+// Snapshot returns a copy of the entries, it does not block writers
+// The copy is weakly consistent: writes running while it walks the map may or may not be part of it
+func (m *Map[K, V]) Snapshot() map[K]V {
+	gomap := make(map[K]V, m.Len())
+	m.ForEach(func(key K, value V) bool {
+		gomap[key] = value
+		return true
+	})
+	return gomap
+}
+
 // Grow resizes the hashmap to a new size, gets rounded up to next power of 2
 // To double the size of the hashmap use newSize 0
 // No resizing is done in case of another resize operation already being in progress
@@ -368,11 +451,7 @@ func (m *Map[K, V]) FillRate() uintptr {
 
 // MarshalJSON implements the json.Marshaler interface.
 func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
-	gomap := make(map[K]V)
-	for i := m.listHead.next(); i != nil; i = i.next() {
-		gomap[i.key] = *i.value.Load()
-	}
-	return json.Marshal(gomap)
+	return json.Marshal(m.Snapshot())
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
@@ -382,9 +461,7 @@ func (m *Map[K, V]) UnmarshalJSON(i []byte) error {
 	if err != nil {
 		return err
 	}
-	for k, v := range gomap {
-		m.Set(k, v)
-	}
+	m.SetMany(gomap)
 	return nil
 }
 
@@ -420,7 +497,7 @@ func (m *Map[K, V]) removeItemFromIndex(item *element[K, V]) {
 		ptr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(data.data) + index*intSizeBytes))
 
 		next := item.next()
-		if next != nil && item.keyHash>>data.keyshifts != index {
+		if next != nil && next.keyHash>>data.keyshifts != index {
 			next = nil // do not set index to next item if it's not the same slice index
 		}
 		atomic.CompareAndSwapPointer(ptr, unsafe.Pointer(item), unsafe.Pointer(next))
@@ -0,0 +1,31 @@
+package maps
+
+import (
+	"strconv"
+	"testing"
+)
+
+// TestDelKeepsIndexInBucket deletes the only key of a bucket whose next element lies in another bucket,
+// the index slot must not keep pointing at that element once it is deleted too
+func TestDelKeepsIndexInBucket(t *testing.T) {
+	m := New[int, int]()
+	// with the default 8 slots a key k lands in slot k/8
+	m.SetHasher(func(k int) uintptr { return uintptr(k) << (strconv.IntSize - 6) })
+	m.Set(8, 8)
+	m.Set(16, 16)
+	m.Del(8)
+	m.Del(16)
+	m.ForEach(func(int, int) bool { return true }) // unlinks the deleted elements
+	m.Set(17, 17)
+	if _, ok := m.Get(17); !ok {
+		t.Fatal("Get(17) = false after Set")
+	}
+	seen := make(map[int]int)
+	m.ForEach(func(k, v int) bool {
+		seen[k] = v
+		return true
+	})
+	if len(seen) != 1 || seen[17] != 17 {
+		t.Fatalf("ForEach visited %v, want map[17:17]", seen)
+	}
+}
@@ -0,0 +1,299 @@
+package maps
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// EvictionPolicy selects the entry a Cache evicts once it holds Capacity entries
+type EvictionPolicy int
+
+const (
+	// LRU evicts the least recently used entry
+	LRU EvictionPolicy = iota
+	// LFU evicts the least frequently used entry, the oldest one among equally used entries
+	LFU
+	// TinyLFU admits an entry into the main space only when it is estimated to be used more often than the entry it would evict
+	// See https://arxiv.org/abs/1512.00727
+	TinyLFU
+)
+
+// evictionPolicy tracks the keys of a bounded cache, it is not safe for concurrent use
+type evictionPolicy[K comparable] interface {
+	// add records a new key and returns the keys to evict, which may include the added key
+	add(key K) []K
+	access(key K)
+	remove(key K)
+}
+
+func newEvictionPolicy[K comparable](policy EvictionPolicy, capacity int, hasher func(K) uintptr) evictionPolicy[K] {
+	switch policy {
+	case LFU:
+		return newLFU[K](capacity)
+	case TinyLFU:
+		return newTinyLFU[K](capacity, hasher)
+	}
+	return newLRU[K](capacity)
+}
+
+// lruPolicy keeps keys from the most to the least recently used
+type lruPolicy[K comparable] struct {
+	capacity int
+	order    *list.List
+	elements map[K]*list.Element
+}
+
+func newLRU[K comparable](capacity int) *lruPolicy[K] {
+	return &lruPolicy[K]{capacity: capacity, order: list.New(), elements: make(map[K]*list.Element)}
+}
+
+func (p *lruPolicy[K]) add(key K) []K {
+	p.elements[key] = p.order.PushFront(key)
+	if p.order.Len() <= p.capacity {
+		return nil
+	}
+	victim := p.order.Remove(p.order.Back()).(K)
+	delete(p.elements, victim)
+	return []K{victim}
+}
+
+func (p *lruPolicy[K]) access(key K) {
+	if e, ok := p.elements[key]; ok {
+		p.order.MoveToFront(e)
+	}
+}
+
+func (p *lruPolicy[K]) remove(key K) {
+	if e, ok := p.elements[key]; ok {
+		p.order.Remove(e)
+		delete(p.elements, key)
+	}
+}
+
+// lfuPolicy keeps keys in a min heap of their use count
+type lfuPolicy[K comparable] struct {
+	capacity int
+	tick     uint64
+	entries  lfuHeap[K]
+	elements map[K]*lfuEntry[K]
+}
+
+type lfuEntry[K comparable] struct {
+	key   K
+	count uint64
+	tick  uint64 // last use, breaks ties between equal counts
+	index int
+}
+
+type lfuHeap[K comparable] []*lfuEntry[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].tick < h[j].tick
+}
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+func (h *lfuHeap[K]) Push(x any) {
+	e := x.(*lfuEntry[K])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *lfuHeap[K]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+func newLFU[K comparable](capacity int) *lfuPolicy[K] {
+	return &lfuPolicy[K]{capacity: capacity, elements: make(map[K]*lfuEntry[K])}
+}
+
+// add evicts before inserting so the new key, used once, is not its own victim
+func (p *lfuPolicy[K]) add(key K) []K {
+	var victims []K
+	for len(p.entries) >= p.capacity && len(p.entries) > 0 {
+		victim := heap.Pop(&p.entries).(*lfuEntry[K])
+		delete(p.elements, victim.key)
+		victims = append(victims, victim.key)
+	}
+	p.tick++
+	e := &lfuEntry[K]{key: key, count: 1, tick: p.tick}
+	heap.Push(&p.entries, e)
+	p.elements[key] = e
+	return victims
+}
+
+func (p *lfuPolicy[K]) access(key K) {
+	if e, ok := p.elements[key]; ok {
+		p.tick++
+		e.count++
+		e.tick = p.tick
+		heap.Fix(&p.entries, e.index)
+	}
+}
+
+func (p *lfuPolicy[K]) remove(key K) {
+	if e, ok := p.elements[key]; ok {
+		heap.Remove(&p.entries, e.index)
+		delete(p.elements, key)
+	}
+}
+
+// tinyLFUPolicy is W-TinyLFU, a small LRU window in front of a segmented LRU main space guarded by a frequency sketch
+type tinyLFUPolicy[K comparable] struct {
+	window, probation, protected   *list.List
+	windowCap, mainCap, protectCap int
+	elements                       map[K]*list.Element
+	segments                       map[K]*list.List
+	sketch                         *countMinSketch
+	hasher                         func(K) uintptr
+}
+
+func newTinyLFU[K comparable](capacity int, hasher func(K) uintptr) *tinyLFUPolicy[K] {
+	windowCap := capacity / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	mainCap := capacity - windowCap
+	return &tinyLFUPolicy[K]{
+		window:     list.New(),
+		probation:  list.New(),
+		protected:  list.New(),
+		windowCap:  windowCap,
+		mainCap:    mainCap,
+		protectCap: mainCap * 8 / 10,
+		elements:   make(map[K]*list.Element),
+		segments:   make(map[K]*list.List),
+		sketch:     newCountMinSketch(capacity),
+		hasher:     hasher,
+	}
+}
+
+func (p *tinyLFUPolicy[K]) add(key K) []K {
+	p.sketch.increment(uint64(p.hasher(key)))
+	p.push(p.window, key)
+	if p.window.Len() <= p.windowCap {
+		return nil
+	}
+	candidate := p.window.Back().Value.(K)
+	p.unlink(candidate)
+	if p.probation.Len()+p.protected.Len() < p.mainCap {
+		p.push(p.probation, candidate)
+		return nil
+	}
+	victims := p.probation
+	if victims.Len() == 0 {
+		victims = p.protected
+	}
+	if victims.Len() == 0 {
+		return []K{candidate}
+	}
+	victim := victims.Back().Value.(K)
+	if p.sketch.estimate(uint64(p.hasher(candidate))) <= p.sketch.estimate(uint64(p.hasher(victim))) {
+		return []K{candidate}
+	}
+	p.unlink(victim)
+	p.push(p.probation, candidate)
+	return []K{victim}
+}
+
+func (p *tinyLFUPolicy[K]) access(key K) {
+	e, ok := p.elements[key]
+	if !ok {
+		return
+	}
+	p.sketch.increment(uint64(p.hasher(key)))
+	switch p.segments[key] {
+	case p.probation:
+		// a second use promotes the key, the least recent protected key is demoted to make room
+		p.unlink(key)
+		p.push(p.protected, key)
+		if p.protected.Len() > p.protectCap {
+			demoted := p.protected.Back().Value.(K)
+			p.unlink(demoted)
+			p.push(p.probation, demoted)
+		}
+	default:
+		p.segments[key].MoveToFront(e)
+	}
+}
+
+func (p *tinyLFUPolicy[K]) remove(key K) {
+	if _, ok := p.elements[key]; ok {
+		p.unlink(key)
+	}
+}
+
+func (p *tinyLFUPolicy[K]) push(segment *list.List, key K) {
+	p.elements[key] = segment.PushFront(key)
+	p.segments[key] = segment
+}
+
+func (p *tinyLFUPolicy[K]) unlink(key K) {
+	p.segments[key].Remove(p.elements[key])
+	delete(p.elements, key)
+	delete(p.segments, key)
+}
+
+// countMinSketch estimates key frequencies with saturating counters that are halved periodically so old popularity fades
+type countMinSketch struct {
+	rows    [sketchDepth][]uint8
+	mask    uint64
+	samples int
+	limit   int
+}
+
+const (
+	sketchDepth   = 4
+	sketchMaximum = 15
+)
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := roundUpPower2(uintptr(capacity))
+	if width < 16 {
+		width = 16
+	}
+	s := &countMinSketch{mask: uint64(width - 1), limit: 10 * capacity}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index spreads a hash over a row with double hashing
+func (s *countMinSketch) index(h uint64, row int) uint64 {
+	return (h + uint64(row)*(h>>32|1)) & s.mask
+}
+
+func (s *countMinSketch) increment(h uint64) {
+	for i := range s.rows {
+		if idx := s.index(h, i); s.rows[i][idx] < sketchMaximum {
+			s.rows[i][idx]++
+		}
+	}
+	if s.samples++; s.samples >= s.limit {
+		for i := range s.rows {
+			for j := range s.rows[i] {
+				s.rows[i][j] >>= 1
+			}
+		}
+		s.samples /= 2
+	}
+}
+
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	least := uint8(sketchMaximum)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(h, i)]; v < least {
+			least = v
+		}
+	}
+	return least
+}
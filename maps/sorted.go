@@ -0,0 +1,419 @@
+package maps
+
+import (
+	"encoding/json"
+	"iter"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+const (
+	// maxLevel bounds the height of the skiplist, enough for 4^32 keys
+	maxLevel = 32
+
+	// levelFactor is the inverse probability of a node being promoted to the next level
+	levelFactor = 4
+)
+
+type (
+	// SortedMap implements a concurrent ordered map on a skiplist
+	// Iteration and range scans visit keys in ascending order
+	SortedMap[K constraints.Ordered, V any] struct {
+		mu     sync.RWMutex
+		head   *skipNode[K, V]
+		level  int
+		length uintptr
+		random *rand.Rand
+	}
+
+	// a single node of the skiplist, next[i] is the following node on level i
+	skipNode[K constraints.Ordered, V any] struct {
+		key     K
+		value   V
+		next    []*skipNode[K, V]
+		deleted bool
+	}
+)
+
+// NewSorted returns a new SortedMap instance
+func NewSorted[K constraints.Ordered, V any]() *SortedMap[K, V] {
+	return &SortedMap[K, V]{
+		head:   &skipNode[K, V]{next: make([]*skipNode[K, V], maxLevel)},
+		level:  1,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())), // #nosec G404
+	}
+}
+
+// Del deletes key/keys from the map
+func (m *SortedMap[K, V]) Del(keys ...K) {
+	if len(keys) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		m.remove(key)
+	}
+}
+
+// Get retrieves an element from the map
+// returns `false“ if element is absent
+func (m *SortedMap[K, V]) Get(key K) (value V, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if node := m.find(key); node != nil {
+		return node.value, true
+	}
+	return
+}
+
+// Set tries to update an element if key is present else it inserts a new element
+func (m *SortedMap[K, V]) Set(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.insert(key, value)
+}
+
+// GetOrSet returns the existing value for the key if present
+// Otherwise, it stores and returns the given value
+// The loaded result is true if the value was loaded, false if stored
+func (m *SortedMap[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if node := m.find(key); node != nil {
+		return node.value, true
+	}
+	m.insert(key, value)
+	return value, false
+}
+
+// GetOrCompute is similar to GetOrSet but the value to be set is obtained from a constructor
+// the value constructor is called without holding the lock, so it may use the map, and concurrent calls
+// for the same missing key may each call it, only the value stored first is kept and returned
+func (m *SortedMap[K, V]) GetOrCompute(key K, valueFn func() V) (actual V, loaded bool) {
+	if actual, loaded = m.Get(key); loaded {
+		return
+	}
+	return m.GetOrSet(key, valueFn())
+}
+
+// GetAndDel deletes the key from the map, returning the previous value if any.
+func (m *SortedMap[K, V]) GetAndDel(key K) (value V, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if node := m.remove(key); node != nil {
+		return node.value, true
+	}
+	return
+}
+
+// CompareAndSwap atomically updates a map entry given its key by comparing current value to `oldValue`
+// and setting it to `newValue` if the above comparison is successful
+// It returns a boolean indicating whether the CompareAndSwap was successful or not
+func (m *SortedMap[K, V]) CompareAndSwap(key K, oldValue, newValue V) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if node := m.find(key); node != nil && reflect.DeepEqual(node.value, oldValue) {
+		node.value = newValue
+		return true
+	}
+	return false
+}
+
+// Swap atomically swaps the value of a map entry given its key
+// It returns the old value if swap was successful and a boolean `swapped` indicating whether the swap was successful or not
+func (m *SortedMap[K, V]) Swap(key K, newValue V) (oldValue V, swapped bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if node := m.find(key); node != nil {
+		oldValue, node.value = node.value, newValue
+		return oldValue, true
+	}
+	return
+}
+
+// ForEach iterates over key-value pairs in ascending key order and executes the lambda provided for each such pair
+// lambda must return `true` to continue iteration and `false` to break iteration
+// The lock is not held while lambda runs, so it may modify the map
+func (m *SortedMap[K, V]) ForEach(lambda func(K, V) bool) {
+	m.mu.RLock()
+	node := m.head.next[0]
+	m.mu.RUnlock()
+	m.iterate(node, func(K) bool { return true }, lambda)
+}
+
+// All returns an iterator over key-value pairs in ascending key order
+func (m *SortedMap[K, V]) All() iter.Seq2[K, V] {
+	return m.ForEach
+}
+
+// Keys returns an iterator over keys in ascending order
+func (m *SortedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.ForEach(func(key K, _ V) bool { return yield(key) })
+	}
+}
+
+// Values returns an iterator over values in ascending key order
+func (m *SortedMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.ForEach(func(_ K, value V) bool { return yield(value) })
+	}
+}
+
+// SetMany sets every entry of entries
+func (m *SortedMap[K, V]) SetMany(entries map[K]V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, value := range entries {
+		m.insert(key, value)
+	}
+}
+
+// DelIf deletes the entries for which pred returns true and returns the number of deleted entries
+// pred must not use the map
+func (m *SortedMap[K, V]) DelIf(pred func(K, V) bool) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []K
+	for node := m.head.next[0]; node != nil; node = node.next[0] {
+		if pred(node.key, node.value) {
+			keys = append(keys, node.key)
+		}
+	}
+	for _, key := range keys {
+		m.remove(key)
+	}
+	return len(keys)
+}
+
+// Clear deletes every entry
+func (m *SortedMap[K, V]) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for node := m.head.next[0]; node != nil; node = node.next[0] {
+		node.deleted = true
+	}
+	m.head.next = make([]*skipNode[K, V], maxLevel)
+	m.level, m.length = 1, 0
+}
+
+// Clone returns a new map with the entries of a Snapshot
+func (m *SortedMap[K, V]) Clone() IMap[K, V] {
+	clone := NewSorted[K, V]()
+	clone.SetMany(m.Snapshot())
+	return clone
+}
+
+// Snapshot returns a copy of the entries at a single point in time
+func (m *SortedMap[K, V]) Snapshot() map[K]V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	gomap := make(map[K]V, m.length)
+	for node := m.head.next[0]; node != nil; node = node.next[0] {
+		gomap[node.key] = node.value
+	}
+	return gomap
+}
+
+// Range iterates in ascending order over the key-value pairs with from <= key < to
+// lambda must return `true` to continue iteration and `false` to break iteration
+func (m *SortedMap[K, V]) Range(from, to K, lambda func(K, V) bool) {
+	m.mu.RLock()
+	node := m.ceil(from, true)
+	m.mu.RUnlock()
+	m.iterate(node, func(key K) bool { return key < to }, lambda)
+}
+
+// Min returns the smallest key and its value, ok is false when the map is empty
+func (m *SortedMap[K, V]) Min() (key K, value V, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return entry(m.head.next[0])
+}
+
+// Max returns the largest key and its value, ok is false when the map is empty
+func (m *SortedMap[K, V]) Max() (key K, value V, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	node := m.head
+	for level := m.level - 1; level >= 0; level-- {
+		for node.next[level] != nil {
+			node = node.next[level]
+		}
+	}
+	if node == m.head {
+		return
+	}
+	return entry(node)
+}
+
+// Floor returns the largest key less than or equal to key, ok is false when there is none
+func (m *SortedMap[K, V]) Floor(key K) (floor K, value V, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	node := m.head
+	for level := m.level - 1; level >= 0; level-- {
+		for next := node.next[level]; next != nil && next.key <= key; next = node.next[level] {
+			node = next
+		}
+	}
+	if node == m.head {
+		return
+	}
+	return entry(node)
+}
+
+// Ceil returns the smallest key greater than or equal to key, ok is false when there is none
+func (m *SortedMap[K, V]) Ceil(key K) (ceil K, value V, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return entry(m.ceil(key, true))
+}
+
+// Grow is a no-op, a skiplist allocates its nodes on insertion
+func (m *SortedMap[K, V]) Grow(uintptr) {}
+
+// SetHasher is a no-op, keys are ordered by value and never hashed
+func (m *SortedMap[K, V]) SetHasher(func(K) uintptr) {}
+
+// Len returns the number of key-value pairs within the map
+func (m *SortedMap[K, V]) Len() uintptr {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.length
+}
+
+// FillRate always returns 100, a skiplist has no empty buckets
+func (m *SortedMap[K, V]) FillRate() uintptr {
+	return 100
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (m *SortedMap[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Snapshot())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *SortedMap[K, V]) UnmarshalJSON(i []byte) error {
+	gomap := make(map[K]V)
+	err := json.Unmarshal(i, &gomap)
+	if err != nil {
+		return err
+	}
+	m.SetMany(gomap)
+	return nil
+}
+
+// iterate calls lambda on node and the nodes following it while keep accepts their key
+// the read lock is taken for each step only, a node removed meanwhile is skipped by seeking past its key
+func (m *SortedMap[K, V]) iterate(node *skipNode[K, V], keep func(K) bool, lambda func(K, V) bool) {
+	for node != nil {
+		m.mu.RLock()
+		key, value, deleted := node.key, node.value, node.deleted
+		m.mu.RUnlock()
+		if !deleted {
+			if !keep(key) || !lambda(key, value) {
+				return
+			}
+		}
+		m.mu.RLock()
+		if node.deleted {
+			node = m.ceil(key, false)
+		} else {
+			node = node.next[0]
+		}
+		m.mu.RUnlock()
+	}
+}
+
+// predecessors returns the last node before key on every level
+func (m *SortedMap[K, V]) predecessors(key K) []*skipNode[K, V] {
+	update := make([]*skipNode[K, V], maxLevel)
+	node := m.head
+	for level := m.level - 1; level >= 0; level-- {
+		for next := node.next[level]; next != nil && next.key < key; next = node.next[level] {
+			node = next
+		}
+		update[level] = node
+	}
+	return update
+}
+
+// find returns the node of key or nil
+func (m *SortedMap[K, V]) find(key K) *skipNode[K, V] {
+	node := m.head
+	for level := m.level - 1; level >= 0; level-- {
+		for next := node.next[level]; next != nil && next.key < key; next = node.next[level] {
+			node = next
+		}
+	}
+	if node = node.next[0]; node != nil && node.key == key {
+		return node
+	}
+	return nil
+}
+
+// ceil returns the first node with a key greater than key, or equal to it when inclusive is set
+func (m *SortedMap[K, V]) ceil(key K, inclusive bool) *skipNode[K, V] {
+	node := m.head
+	for level := m.level - 1; level >= 0; level-- {
+		for next := node.next[level]; next != nil && (next.key < key || !inclusive && next.key == key); next = node.next[level] {
+			node = next
+		}
+	}
+	return node.next[0]
+}
+
+func (m *SortedMap[K, V]) insert(key K, value V) {
+	update := m.predecessors(key)
+	if node := update[0].next[0]; node != nil && node.key == key {
+		node.value = value
+		return
+	}
+	level := 1
+	for level < maxLevel && m.random.Intn(levelFactor) == 0 {
+		level++
+	}
+	if level > m.level {
+		for i := m.level; i < level; i++ {
+			update[i] = m.head
+		}
+		m.level = level
+	}
+	node := &skipNode[K, V]{key: key, value: value, next: make([]*skipNode[K, V], level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+	m.length++
+}
+
+// remove unlinks the node of key and returns it, its next pointers are kept for iterations positioned on it
+func (m *SortedMap[K, V]) remove(key K) *skipNode[K, V] {
+	update := m.predecessors(key)
+	node := update[0].next[0]
+	if node == nil || node.key != key {
+		return nil
+	}
+	for i := range node.next {
+		update[i].next[i] = node.next[i]
+	}
+	for m.level > 1 && m.head.next[m.level-1] == nil {
+		m.level--
+	}
+	node.deleted = true
+	m.length--
+	return node
+}
+
+func entry[K constraints.Ordered, V any](node *skipNode[K, V]) (key K, value V, ok bool) {
+	if node == nil {
+		return
+	}
+	return node.key, node.value, true
+}
@@ -0,0 +1,209 @@
+package maps
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newTestSorted(keys ...int) *SortedMap[int, string] {
+	m := NewSorted[int, string]()
+	for _, key := range keys {
+		m.Set(key, "v")
+	}
+	return m
+}
+
+func sortedKeys(m *SortedMap[int, string]) []int {
+	var keys []int
+	m.ForEach(func(key int, _ string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestSortedMapOrderedIteration(t *testing.T) {
+	m := NewSorted[int, string]()
+	for _, key := range rand.New(rand.NewSource(1)).Perm(200) {
+		m.Set(key, "v")
+	}
+	m.Set(10, "updated")
+	m.Del(0, 199, 1000)
+	if m.Len() != 198 {
+		t.Fatalf("Len = %d, want 198", m.Len())
+	}
+	keys := sortedKeys(m)
+	for i, key := range keys {
+		if key != i+1 {
+			t.Fatalf("ForEach visited %d at %d, want %d", key, i, i+1)
+		}
+	}
+	if value, ok := m.Get(10); !ok || value != "updated" {
+		t.Errorf("Get(10) = %q, %v", value, ok)
+	}
+
+	var visited []int
+	m.ForEach(func(key int, _ string) bool {
+		visited = append(visited, key)
+		return len(visited) < 3
+	})
+	if !reflect.DeepEqual(visited, []int{1, 2, 3}) {
+		t.Errorf("ForEach stopped after %v, want [1 2 3]", visited)
+	}
+}
+
+func TestSortedMapForEachWhileModifying(t *testing.T) {
+	m := newTestSorted(1, 2, 3, 4, 5, 6)
+	var visited []int
+	m.ForEach(func(key int, _ string) bool {
+		visited = append(visited, key)
+		// deleting the current and the next key and inserting ahead must not break the walk
+		if key == 2 {
+			m.Del(2, 3)
+			m.Set(7, "v")
+		}
+		return true
+	})
+	if !reflect.DeepEqual(visited, []int{1, 2, 4, 5, 6, 7}) {
+		t.Errorf("ForEach visited %v, want [1 2 4 5 6 7]", visited)
+	}
+}
+
+func TestSortedMapRange(t *testing.T) {
+	m := newTestSorted(10, 20, 30, 40, 50)
+	tests := []struct {
+		from, to int
+		want     []int
+	}{
+		{from: 20, to: 40, want: []int{20, 30}},
+		{from: 15, to: 45, want: []int{20, 30, 40}},
+		{from: 0, to: 100, want: []int{10, 20, 30, 40, 50}},
+		{from: 50, to: 50, want: nil},
+		{from: 60, to: 100, want: nil},
+		{from: 40, to: 20, want: nil},
+	}
+	for _, test := range tests {
+		var got []int
+		m.Range(test.from, test.to, func(key int, _ string) bool {
+			got = append(got, key)
+			return true
+		})
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Range(%d, %d) = %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+	var got []int
+	m.Range(0, 100, func(key int, _ string) bool {
+		got = append(got, key)
+		return key < 30
+	})
+	if !reflect.DeepEqual(got, []int{10, 20, 30}) {
+		t.Errorf("Range stopped after %v, want [10 20 30]", got)
+	}
+}
+
+func TestSortedMapFloorCeil(t *testing.T) {
+	m := newTestSorted(10, 20, 30)
+	tests := []struct {
+		key               int
+		floor, ceil       int
+		hasFloor, hasCeil bool
+	}{
+		{key: 5, ceil: 10, hasCeil: true},
+		{key: 10, floor: 10, ceil: 10, hasFloor: true, hasCeil: true},
+		{key: 15, floor: 10, ceil: 20, hasFloor: true, hasCeil: true},
+		{key: 30, floor: 30, ceil: 30, hasFloor: true, hasCeil: true},
+		{key: 35, floor: 30, hasFloor: true},
+	}
+	for _, test := range tests {
+		if floor, _, ok := m.Floor(test.key); ok != test.hasFloor || floor != test.floor {
+			t.Errorf("Floor(%d) = %d, %v, want %d, %v", test.key, floor, ok, test.floor, test.hasFloor)
+		}
+		if ceil, _, ok := m.Ceil(test.key); ok != test.hasCeil || ceil != test.ceil {
+			t.Errorf("Ceil(%d) = %d, %v, want %d, %v", test.key, ceil, ok, test.ceil, test.hasCeil)
+		}
+	}
+	if key, _, ok := m.Min(); !ok || key != 10 {
+		t.Errorf("Min = %d, %v, want 10, true", key, ok)
+	}
+	if key, _, ok := m.Max(); !ok || key != 30 {
+		t.Errorf("Max = %d, %v, want 30, true", key, ok)
+	}
+
+	empty := NewSorted[int, string]()
+	if _, _, ok := empty.Min(); ok {
+		t.Error("Min of an empty map returned ok")
+	}
+	if _, _, ok := empty.Max(); ok {
+		t.Error("Max of an empty map returned ok")
+	}
+	if _, _, ok := empty.Floor(1); ok {
+		t.Error("Floor of an empty map returned ok")
+	}
+	if _, _, ok := empty.Ceil(1); ok {
+		t.Error("Ceil of an empty map returned ok")
+	}
+}
+
+func TestSortedMapUpdates(t *testing.T) {
+	m := NewSorted[string, int]()
+	if actual, loaded := m.GetOrSet("a", 1); loaded || actual != 1 {
+		t.Errorf("GetOrSet = %d, %v, want 1, false", actual, loaded)
+	}
+	if actual, loaded := m.GetOrCompute("a", func() int { return 2 }); !loaded || actual != 1 {
+		t.Errorf("GetOrCompute = %d, %v, want 1, true", actual, loaded)
+	}
+	if m.CompareAndSwap("a", 2, 3) || !m.CompareAndSwap("a", 1, 3) {
+		t.Error("CompareAndSwap did not compare the current value")
+	}
+	if old, swapped := m.Swap("a", 4); !swapped || old != 3 {
+		t.Errorf("Swap = %d, %v, want 3, true", old, swapped)
+	}
+	if _, swapped := m.Swap("b", 1); swapped {
+		t.Error("Swap of a missing key succeeded")
+	}
+	if value, ok := m.GetAndDel("a"); !ok || value != 4 || m.Len() != 0 {
+		t.Errorf("GetAndDel = %d, %v with %d entries left", value, ok, m.Len())
+	}
+}
+
+func TestSortedMapGetOrComputeConcurrent(t *testing.T) {
+	m := NewSorted[string, int]()
+	var wg sync.WaitGroup
+	results := make([]int, 16)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = m.GetOrCompute("k", func() int { return i })
+		}(i)
+	}
+	wg.Wait()
+	stored, _ := m.Get("k")
+	for i, result := range results {
+		if result != stored {
+			t.Fatalf("call %d got %d, want the stored value %d", i, result, stored)
+		}
+	}
+}
+
+func TestSortedMapJSON(t *testing.T) {
+	m := newTestSorted(3, 1, 2)
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"1":"v","2":"v","3":"v"}` {
+		t.Errorf("MarshalJSON = %s", data)
+	}
+	decoded := NewSorted[int, string]()
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if keys := sortedKeys(decoded); !reflect.DeepEqual(keys, []int{1, 2, 3}) {
+		t.Errorf("UnmarshalJSON keys = %v", keys)
+	}
+}
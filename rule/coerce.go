@@ -0,0 +1,320 @@
+package rule
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sujit-baniya/pkg/timeutil"
+)
+
+// rational is implemented by decimal types such as money.Decimal
+type rational interface {
+	Rat() *big.Rat
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// scalar reduces named, pointer and sized types to nil, bool, int64, float64, string, time.Time or *big.Rat,
+// other values are returned as they are
+func scalar(value any) any {
+	switch v := value.(type) {
+	case nil, bool, int64, float64, string, time.Time, *big.Rat:
+		return v
+	case int:
+		return int64(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if r, ok := new(big.Rat).SetString(string(v)); ok {
+			return r
+		}
+		return string(v)
+	case rational:
+		if r := v.Rat(); r != nil {
+			return r
+		}
+		return nil
+	case *big.Int:
+		if v == nil {
+			return nil
+		}
+		return new(big.Rat).SetInt(v)
+	case []byte:
+		return string(v)
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+		if rv.CanInterface() {
+			if _, ok := rv.Interface().(rational); ok {
+				return scalar(rv.Interface())
+			}
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return int64(u)
+		} else {
+			return new(big.Rat).SetUint64(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Struct:
+		if rv.Type().ConvertibleTo(timeType) {
+			return rv.Convert(timeType).Interface()
+		}
+	}
+	if rv.IsValid() && rv.CanInterface() {
+		return rv.Interface()
+	}
+	return value
+}
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int64, float64, *big.Rat:
+		return true
+	}
+	return false
+}
+
+// asNumber returns a scalar as int64, float64 or *big.Rat, strings holding a number are parsed
+func asNumber(v any) (any, bool) {
+	switch v := v.(type) {
+	case int64, float64, *big.Rat:
+		return v, true
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, false
+		}
+		if r, ok := new(big.Rat).SetString(s); ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// compareNumbers compares ints and floats directly and mixed or decimal values exactly
+func compareNumbers(a, b any) (int, bool) {
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			return compareOrdered(a, b), true
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			if math.IsNaN(a) || math.IsNaN(b) {
+				return 0, false
+			}
+			return compareOrdered(a, b), true
+		}
+	}
+	ra, ok := toRat(a)
+	if !ok {
+		return compareFloats(a, b)
+	}
+	rb, ok := toRat(b)
+	if !ok {
+		return compareFloats(a, b)
+	}
+	return ra.Cmp(rb), true
+}
+
+// compareFloats compares infinities, which have no exact value
+func compareFloats(a, b any) (int, bool) {
+	fa, fb := toFloat(a), toFloat(b)
+	if math.IsNaN(fa) || math.IsNaN(fb) {
+		return 0, false
+	}
+	return compareOrdered(fa, fb), true
+}
+
+func toRat(v any) (*big.Rat, bool) {
+	switch v := v.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(v), true
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(v), true
+	case *big.Rat:
+		return v, true
+	}
+	return nil, false
+}
+
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f
+	}
+	return math.NaN()
+}
+
+func compareOrdered[T int64 | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// asTime returns a scalar as a time, strings are parsed with timeutil.ParseTime or as RFC 3339 and integers are unix seconds
+func asTime(v any) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		if t, err := timeutil.ParseTime(v); err == nil {
+			return t, true
+		}
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, true
+		}
+	case int64:
+		return time.Unix(v, 0), true
+	}
+	return time.Time{}, false
+}
+
+// compareValues orders two values, it is false when they cannot be compared
+// A time compares with times, date strings and unix seconds, a number with numbers and numeric strings,
+// and strings compare as times when both are dates and lexically otherwise
+func compareValues(a, b any) (int, bool) {
+	a, b = scalar(a), scalar(b)
+	if a == nil || b == nil {
+		return 0, false
+	}
+	_, aTime := a.(time.Time)
+	_, bTime := b.(time.Time)
+	if aTime || bTime {
+		ta, ok := asTime(a)
+		if !ok {
+			return 0, false
+		}
+		tb, ok := asTime(b)
+		if !ok {
+			return 0, false
+		}
+		return ta.Compare(tb), true
+	}
+	if isNumber(a) || isNumber(b) {
+		na, ok := asNumber(a)
+		if !ok {
+			return 0, false
+		}
+		nb, ok := asNumber(b)
+		if !ok {
+			return 0, false
+		}
+		return compareNumbers(na, nb)
+	}
+	sa, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	sb, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	if ta, ok := asTime(sa); ok {
+		if tb, ok := asTime(sb); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	return compareOrdered(sa, sb), true
+}
+
+// equalValues tells whether two values are equal, comparable is false when their types cannot be compared
+// Strings are equal regardless of case, numbers and times by value
+func equalValues(a, b any) (equal, comparable bool) {
+	a, b = scalar(a), scalar(b)
+	switch {
+	case a == nil || b == nil:
+		return a == nil && b == nil, true
+	}
+	_, aTime := a.(time.Time)
+	_, bTime := b.(time.Time)
+	if aTime || bTime || isNumber(a) || isNumber(b) {
+		c, ok := compareValues(a, b)
+		return ok && c == 0, ok
+	}
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.EqualFold(a, b), true
+		}
+		return false, false
+	case bool:
+		if b, ok := b.(bool); ok {
+			return a == b, true
+		}
+		return false, false
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false, false
+	}
+	return reflect.DeepEqual(a, b), true
+}
+
+// asString returns the text of string kinds and byte slices
+func asString(v any) (string, bool) {
+	s, ok := scalar(v).(string)
+	return s, ok
+}
+
+// asList returns the items of a slice or array, byte slices are strings rather than lists
+func asList(v any) ([]any, bool) {
+	switch v := v.(type) {
+	case []any:
+		return v, true
+	case []byte, string, nil:
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
@@ -0,0 +1,415 @@
+package rule
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// CompiledRule is a rule whose field paths, operators and values are resolved once into closures
+// It is a snapshot of the rule, changing the rule or registering operators afterwards does not affect it,
+// and it is safe for concurrent use
+type CompiledRule struct {
+	ID      string
+	Handler CallbackFn
+	match   func(Data) bool
+}
+
+// Compile returns the rule compiled for repeated evaluation
+// Plain ints, floats, strings and bools are compared without allocating, other values go through Validate's coercion
+func (r *Rule) Compile() *CompiledRule {
+	return &CompiledRule{ID: r.ID, Handler: r.Handler, match: compileExpr(r.Expression())}
+}
+
+// Match tells whether data satisfies the rule
+func (c *CompiledRule) Match(data Data) bool {
+	return c.match(data)
+}
+
+// Apply behaves as Rule.Apply
+func (c *CompiledRule) Apply(data Data, callback ...CallbackFn) any {
+	if !c.match(data) {
+		if len(callback) > 0 {
+			return callback[0](nil)
+		}
+		return nil
+	}
+	if c.Handler != nil {
+		return c.Handler(data)
+	}
+	if len(callback) > 0 {
+		return callback[0](data)
+	}
+	return data
+}
+
+// CompiledGroup is a GroupRule whose rules are compiled and sorted once, it is safe for concurrent use
+type CompiledGroup struct {
+	rules []*CompiledRule
+	mode  Mode
+}
+
+// Compile returns the rules of the group compiled in the priority order and mode of its config
+func (r *GroupRule) Compile() *CompiledGroup {
+	direction := "ASC"
+	if r.config.Priority == HighestPriority {
+		direction = "DESC"
+	}
+	sorted := r.sortByPriority(direction)
+	group := &CompiledGroup{rules: make([]*CompiledRule, len(sorted)), mode: r.config.Mode}
+	for i, rule := range sorted {
+		group.rules[i] = rule.Compile()
+	}
+	return group
+}
+
+// Apply behaves as GroupRule.Apply
+func (g *CompiledGroup) Apply(data Data, fn ...CallbackFn) any {
+	if g.mode == AllMatch {
+		if responses := g.ApplyAll(data, fn...); len(responses) > 0 {
+			return responses
+		}
+		return nil
+	}
+	for _, rule := range g.rules {
+		if response := rule.Apply(data, fn...); response != nil {
+			return response
+		}
+	}
+	return nil
+}
+
+// ApplyAll behaves as GroupRule.ApplyAll
+func (g *CompiledGroup) ApplyAll(data Data, fn ...CallbackFn) []any {
+	var responses []any
+	for _, rule := range g.rules {
+		if response := rule.Apply(data, fn...); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	return responses
+}
+
+func compileExpr(e *Expr) func(Data) bool {
+	switch {
+	case e == nil:
+		return func(Data) bool { return true }
+	case e.Condition != nil:
+		return compileCondition(e.Condition)
+	case e.Not != nil:
+		inner := compileExpr(e.Not)
+		return func(data Data) bool { return !inner(data) }
+	case e.Or != nil:
+		exprs := compileExprs(e.Or)
+		if len(exprs) == 1 {
+			return exprs[0]
+		}
+		return func(data Data) bool {
+			for _, expr := range exprs {
+				if expr(data) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	exprs := compileExprs(e.And)
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return func(data Data) bool {
+		for _, expr := range exprs {
+			if !expr(data) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func compileExprs(exprs []*Expr) []func(Data) bool {
+	compiled := make([]func(Data) bool, len(exprs))
+	for i, expr := range exprs {
+		compiled[i] = compileExpr(expr)
+	}
+	return compiled
+}
+
+func compileCondition(condition *Condition) func(Data) bool {
+	op, ok := lookupOperator(condition.Operator)
+	if !ok {
+		return func(Data) bool { return false }
+	}
+	get := compileLookup(condition.Field)
+	test := compileTest(condition.Operator, op, condition.Value)
+	missing := op.missing
+	return func(data Data) bool {
+		value, found := get(data)
+		if !found && !missing {
+			return false
+		}
+		return test(value)
+	}
+}
+
+// compileLookup returns lookup for field with its path parsed once
+func compileLookup(field string) func(any) (any, bool) {
+	if field == "$" {
+		return func(data any) (any, bool) { return data, true }
+	}
+	segments, ok := splitPath(field)
+	if !ok {
+		return func(data any) (any, bool) {
+			if m, ok := data.(map[string]any); ok {
+				value, ok := m[field]
+				return value, ok
+			}
+			return nil, false
+		}
+	}
+	if len(segments) == 1 && segments[0].key == field {
+		segment := segments[0]
+		return func(data any) (any, bool) {
+			if m, ok := data.(map[string]any); ok {
+				value, ok := m[field]
+				return value, ok
+			}
+			return step(data, segment)
+		}
+	}
+	return func(data any) (any, bool) {
+		if m, ok := data.(map[string]any); ok {
+			if value, ok := m[field]; ok {
+				return value, true
+			}
+		}
+		current, ok := data, true
+		for _, segment := range segments {
+			if current, ok = step(current, segment); !ok {
+				return nil, false
+			}
+		}
+		return current, true
+	}
+}
+
+// compileTest returns the operator applied to operand, with fast paths for the plain values of built-in operators
+func compileTest(operator ConditionOperator, op operator, raw any) func(any) bool {
+	fallback := func(value any) bool { return op.fn(value, raw) }
+	if _, builtin := builtinOperators[operator]; !builtin {
+		return fallback
+	}
+	operand := scalar(raw)
+	switch operator {
+	case EQ, NEQ:
+		if !plainOperand(operand) {
+			return fallback
+		}
+		negate := operator == NEQ
+		return func(value any) bool {
+			equal, comparable, handled := fastEqual(value, operand)
+			if !handled {
+				return fallback(value)
+			}
+			if negate {
+				return comparable && !equal
+			}
+			return equal
+		}
+	case GT, GTE, LT, LTE:
+		if !orderedOperand(operand) {
+			return fallback
+		}
+		accept := orderAccept(operator)
+		return func(value any) bool {
+			c, ok, handled := fastCompare(value, operand)
+			if !handled {
+				return fallback(value)
+			}
+			return ok && accept(c)
+		}
+	case BETWEEN:
+		bounds, ok := asList(raw)
+		if !ok || len(bounds) != 2 {
+			return fallback
+		}
+		low, high := scalar(bounds[0]), scalar(bounds[1])
+		if !orderedOperand(low) || !orderedOperand(high) {
+			return fallback
+		}
+		return func(value any) bool {
+			c, ok, handled := fastCompare(value, low)
+			if !handled {
+				return fallback(value)
+			}
+			if !ok || c < 0 {
+				return false
+			}
+			c, ok, handled = fastCompare(value, high)
+			if !handled {
+				return fallback(value)
+			}
+			return ok && c <= 0
+		}
+	case IN, NotIn:
+		items, ok := asList(raw)
+		if !ok {
+			return fallback
+		}
+		operands := make([]any, len(items))
+		for i, item := range items {
+			if operands[i] = scalar(item); !plainOperand(operands[i]) {
+				return fallback
+			}
+		}
+		exclude := operator == NotIn
+		return func(value any) bool {
+			for _, operand := range operands {
+				equal, comparable, handled := fastEqual(value, operand)
+				switch {
+				case !handled:
+					return fallback(value)
+				case exclude && (equal || !comparable):
+					return false
+				case !exclude && equal:
+					return true
+				}
+			}
+			return exclude
+		}
+	case CONTAINS, NotContains, StartsWith, EndsWith, MATCHES:
+		text, ok := operand.(string)
+		if !ok {
+			return fallback
+		}
+		var match func(string) bool
+		switch operator {
+		case CONTAINS:
+			match = func(s string) bool { return strings.Contains(s, text) }
+		case NotContains:
+			match = func(s string) bool { return !strings.Contains(s, text) }
+		case StartsWith:
+			match = func(s string) bool { return strings.HasPrefix(s, text) }
+		case EndsWith:
+			match = func(s string) bool { return strings.HasSuffix(s, text) }
+		case MATCHES:
+			re, err := regexp.Compile(text)
+			if err != nil {
+				// an invalid pattern matches nothing
+				return func(any) bool { return false }
+			}
+			match = re.MatchString
+		}
+		return func(value any) bool {
+			if s, ok := value.(string); ok {
+				return match(s)
+			}
+			return fallback(value)
+		}
+	}
+	return fallback
+}
+
+func orderAccept(operator ConditionOperator) func(int) bool {
+	switch operator {
+	case GT:
+		return func(c int) bool { return c > 0 }
+	case GTE:
+		return func(c int) bool { return c >= 0 }
+	case LT:
+		return func(c int) bool { return c < 0 }
+	}
+	return func(c int) bool { return c <= 0 }
+}
+
+// plainOperand tells whether fastEqual handles operand
+func plainOperand(operand any) bool {
+	switch operand.(type) {
+	case int64, float64, string, bool:
+		return true
+	}
+	return false
+}
+
+// orderedOperand tells whether fastCompare handles operand, strings holding a date compare as times and are left to compareValues
+func orderedOperand(operand any) bool {
+	switch operand := operand.(type) {
+	case int64:
+		return true
+	case float64:
+		return !math.IsNaN(operand)
+	case string:
+		_, isTime := asTime(operand)
+		return !isTime
+	}
+	return false
+}
+
+// fastEqual is equalValues for plain values, handled is false when value or operand needs coercion
+func fastEqual(value, operand any) (equal, comparable, handled bool) {
+	switch v := value.(type) {
+	case string:
+		if s, ok := operand.(string); ok {
+			return strings.EqualFold(v, s), true, true
+		}
+	case bool:
+		if b, ok := operand.(bool); ok {
+			return v == b, true, true
+		}
+	case int, int64, float64:
+		switch operand.(type) {
+		case int64, float64:
+			c, ok, handled := fastCompare(value, operand)
+			return ok && c == 0, ok, handled
+		}
+	}
+	return false, false, false
+}
+
+// maxExact is the largest magnitude up to which every integer is a float64
+const maxExact = 1 << 53
+
+// fastCompare is compareValues for plain numbers and for strings against a string that is not a date
+func fastCompare(value, operand any) (c int, ok, handled bool) {
+	switch v := value.(type) {
+	case int:
+		return compareInt(int64(v), operand)
+	case int64:
+		return compareInt(v, operand)
+	case float64:
+		switch o := operand.(type) {
+		case float64:
+			if math.IsNaN(v) || math.IsNaN(o) {
+				return 0, false, true
+			}
+			return compareOrdered(v, o), true, true
+		case int64:
+			if o >= -maxExact && o <= maxExact {
+				if math.IsNaN(v) {
+					return 0, false, true
+				}
+				return compareOrdered(v, float64(o)), true, true
+			}
+		}
+	case string:
+		if o, ok := operand.(string); ok {
+			return compareOrdered(v, o), true, true
+		}
+	}
+	return 0, false, false
+}
+
+func compareInt(v int64, operand any) (int, bool, bool) {
+	switch o := operand.(type) {
+	case int64:
+		return compareOrdered(v, o), true, true
+	case float64:
+		if math.IsNaN(o) {
+			return 0, false, true
+		}
+		if v >= -maxExact && v <= maxExact {
+			return compareOrdered(float64(v), o), true, true
+		}
+	}
+	return 0, false, false
+}
@@ -0,0 +1,223 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+var benchmarkData = map[string]any{
+	"age":     34,
+	"country": "CA",
+	"vip":     false,
+	"score":   712.5,
+	"name":    "Alice",
+	"order":   map[string]any{"total": 129.99, "items": []any{map[string]any{"sku": "A-1", "price": 99.99}}},
+}
+
+func benchmarkRule(b *testing.B) *Rule {
+	rule, err := Parse(`age >= 18 && country in ["US", "CA", "GB"] && (vip eq true || score gt 700) && name starts_with "A" && order.total between [100, 500]`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return rule
+}
+
+func benchmarkGroup(b *testing.B) *GroupRule {
+	group := NewRuleGroup(Config{Priority: HighestPriority})
+	for i, expression := range []string{
+		`country eq "US" && age >= 21`,
+		`order.items[0].price gt 500`,
+		`name ends_with "z" || vip eq true`,
+		`age >= 18 && country in ["US", "CA", "GB"] && score gt 700`,
+	} {
+		rule, err := Parse(expression)
+		if err != nil {
+			b.Fatal(err)
+		}
+		group.AddRule(rule, 10-i)
+	}
+	legacy := New()
+	legacy.And(NewCondition("age", LT, 18), NewCondition("country", EQ, "CA"))
+	group.AddRule(legacy, 0)
+	return group
+}
+
+func BenchmarkRuleApply(b *testing.B) {
+	rule := benchmarkRule(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if rule.Apply(benchmarkData) == nil {
+			b.Fatal("rule did not match")
+		}
+	}
+}
+
+func BenchmarkCompiledRuleApply(b *testing.B) {
+	rule := benchmarkRule(b).Compile()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if rule.Apply(benchmarkData) == nil {
+			b.Fatal("rule did not match")
+		}
+	}
+}
+
+func BenchmarkCompiledRuleApplyParallel(b *testing.B) {
+	rule := benchmarkRule(b).Compile()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if rule.Apply(benchmarkData) == nil {
+				b.Error("rule did not match")
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkGroupApply(b *testing.B) {
+	group := benchmarkGroup(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if group.Apply(benchmarkData) == nil {
+			b.Fatal("group did not match")
+		}
+	}
+}
+
+func BenchmarkCompiledGroupApply(b *testing.B) {
+	group := benchmarkGroup(b).Compile()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if group.Apply(benchmarkData) == nil {
+			b.Fatal("group did not match")
+		}
+	}
+}
+
+func TestCompiledGroupAllMatch(t *testing.T) {
+	group := NewRuleGroup(Config{Priority: HighestPriority, Mode: AllMatch})
+	for i, expression := range []string{`age >= 18`, `age >= 21`, `country eq "US"`} {
+		rule, err := Parse(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := expression
+		rule.Handler = func(Data) any { return response }
+		group.AddRule(rule, i)
+	}
+	compiled := group.Compile()
+	for _, data := range []map[string]any{{"age": 30, "country": "CA"}, {"age": 30, "country": "US"}, {"age": 10}} {
+		got, want := compiled.Apply(data), group.Apply(data)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("compiled Apply(%v) = %#v, want %#v", data, got, want)
+		}
+	}
+	if got := compiled.Apply(map[string]any{"age": 10}); got != nil {
+		t.Errorf("compiled Apply without a match = %#v, want nil", got)
+	}
+}
+
+func TestCompiledMatchesValidate(t *testing.T) {
+	current := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+
+	values := []any{
+		nil, true, false,
+		0, 5, -5, int8(5), int32(5), int64(5), uint(5), uint64(1<<53 + 1), int64(1<<53 + 1),
+		5.0, 5.5, -0.5, float32(5), float32(5.5), 9007199254740992.0, math.NaN(),
+		json.Number("5"), json.Number("5.5"),
+		"", "5", "5.0", "05", "abc", "Alice", "alice", "a5",
+		"2024-03-10", "2024-03-10T00:00:00Z", "2024-03-20T08:30:00Z", "2023-01-01",
+		current.Add(-48 * time.Hour), current.Add(48 * time.Hour), current.Unix(),
+		[]any{}, []any{5, "a", 5.5}, []string{"a", "5"}, []int{5, 6}, []float64{5.5},
+		[]any{map[string]any{"v": 5}, map[string]any{"v": "abc"}},
+		map[string]any{}, map[string]any{"v": 5},
+	}
+	operands := append([]any{
+		"now", "7d", "1d12h", "^a", "5$",
+		[]any{1, 10}, []any{1.5, "9"}, []any{"2024-01-01", "2024-12-31"}, []any{"a", "z"},
+		[]any{5, "a"}, []string{"a", "abc"}, []int{5}, []float64{5, 5.5}, []any{},
+		Match(NewCondition("v", EQ, 5)), Match(NewCondition("$", GT, 1)), None(Match(NewCondition("v", EXISTS, nil))),
+	}, values...)
+
+	names := make([]string, 0, len(builtinOperators))
+	for name := range builtinOperators {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	checked := 0
+	for _, name := range names {
+		operator := ConditionOperator(name)
+		for _, operand := range operands {
+			if checkOperand(operator, operand) != nil {
+				continue
+			}
+			for _, field := range []string{"v", "nested.v"} {
+				condition := NewCondition(field, operator, operand)
+				compiled := New().SetExpression(Match(condition)).Compile()
+				for _, value := range append(values, missingValue) {
+					data := map[string]any{"nested": map[string]any{}}
+					if value != missingValue {
+						data["v"] = value
+						data["nested"] = map[string]any{"v": value}
+					}
+					checked++
+					if got, want := compiled.Match(data), condition.Validate(data); got != want {
+						t.Errorf("%s %s %s on %#v: compiled %v, Validate %v", field, operator, fmt.Sprint(operand), value, got, want)
+					}
+				}
+			}
+		}
+	}
+	if checked < 10000 {
+		t.Errorf("compared %d evaluations, the operators or operands are not all covered", checked)
+	}
+}
+
+// missingValue stands for a field absent from the data in TestCompiledMatchesValidate
+var missingValue = &struct{ missing bool }{true}
+
+func TestCompiledRuleMatchesApply(t *testing.T) {
+	legacy := New("legacy")
+	adult := legacy.And(NewCondition("age", GTE, 18))
+	local := legacy.Or(NewCondition("country", EQ, "US"), NewCondition("country", IN, []string{"CA", "MX"}))
+	vip := legacy.Not(NewCondition("score", LT, 700.5))
+	legacy.Join(legacy.Group(adult, AND, local), OR, legacy.Group(vip, AND, vip))
+
+	parsed, err := Parse(`age gte 18 && !(country in ["US", "CA"]) || score between [600, 700.5] && name starts_with "A"`, "parsed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []map[string]any{
+		{"age": 20, "country": "US", "score": 650, "name": "Alice"},
+		{"age": 20.5, "country": "FR", "score": 800},
+		{"age": "17", "country": "MX", "score": "700.5", "name": "Ann"},
+		{"age": int64(16), "score": 701},
+		{"age": nil, "country": nil},
+		{},
+	}
+	for _, r := range []*Rule{legacy, parsed} {
+		compiled := r.Compile()
+		for _, d := range data {
+			if got, want := compiled.Match(d), r.Apply(d) != nil; got != want {
+				t.Errorf("%s: compiled Match(%v) = %v, Apply %v", r.ID, d, got, want)
+			}
+			if got, want := compiled.Apply(d), r.Apply(d); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: compiled Apply(%v) = %v, want %v", r.ID, d, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package rule
+
+import "strconv"
+
+// TraceKind is the kind of step of an evaluation trace
+type TraceKind string
+
+const (
+	TraceRule      TraceKind = "rule"
+	TraceNode      TraceKind = "node"
+	TraceGroup     TraceKind = "group"
+	TraceJoin      TraceKind = "join"
+	TraceAnd       TraceKind = "and"
+	TraceOr        TraceKind = "or"
+	TraceNot       TraceKind = "not"
+	TraceCondition TraceKind = "condition"
+	TraceItem      TraceKind = "item"
+)
+
+// Trace is a step of the evaluation of a rule and the steps it is made of
+// A condition records the value found at its field, or Missing when data has no such field,
+// and the item steps of any, all and none record the expression applied to each item
+type Trace struct {
+	Kind     TraceKind `json:"kind"`
+	ID       string    `json:"id,omitempty"`
+	Operator string    `json:"operator,omitempty"`
+	Field    string    `json:"field,omitempty"`
+	Expected any       `json:"expected,omitempty"`
+	Actual   any       `json:"actual,omitempty"`
+	Missing  bool      `json:"missing,omitempty"`
+	Result   bool      `json:"result"`
+	Children []Trace   `json:"children,omitempty"`
+}
+
+// Explain evaluates the rule on data like Apply and returns how each of its parts evaluated
+// Rules built from nodes, groups and joins are traced by them, the others by their expression
+func (r *Rule) Explain(data Data) Trace {
+	if r.expr != nil {
+		trace := explainExpr(r.expr, data)
+		return Trace{Kind: TraceRule, ID: r.ID, Result: trace.Result, Children: []Trace{trace}}
+	}
+	nodes := make(map[*Node]Trace, len(r.nodes))
+	explainNode := func(node *Node) Trace {
+		if trace, ok := nodes[node]; ok {
+			return trace
+		}
+		trace := nodeTrace(node, data)
+		nodes[node] = trace
+		return trace
+	}
+	explainGroup := func(group *Group) Trace {
+		trace := Trace{Kind: TraceGroup}
+		if group == nil {
+			return trace
+		}
+		left, right := explainNode(group.Left), explainNode(group.Right)
+		trace.ID, trace.Operator, trace.Children = group.ID, string(group.Operator), []Trace{left, right}
+		trace.Result = joinResults(group.Operator, left.Result, right.Result)
+		return trace
+	}
+	rule := Trace{Kind: TraceRule, ID: r.ID}
+	switch {
+	case len(r.joins) > 0:
+		for _, join := range r.joins {
+			left, right := explainGroup(join.Left), explainGroup(join.Right)
+			trace := Trace{Kind: TraceJoin, ID: join.ID, Operator: string(join.Operator), Children: []Trace{left, right}}
+			trace.Result = joinResults(join.Operator, left.Result, right.Result)
+			rule.Children = append(rule.Children, trace)
+			rule.Result = trace.Result
+		}
+	case len(r.groups) > 0:
+		var result bool
+		for i, group := range r.groups {
+			trace := explainGroup(group)
+			if group.Operator == AND || group.Operator == OR {
+				result = chainResult(result, i, group.Operator, trace.Result)
+			}
+			rule.Children = append(rule.Children, trace)
+		}
+		rule.Result = result
+	default:
+		var result bool
+		for i, node := range r.nodes {
+			if len(node.Condition) == 0 {
+				continue
+			}
+			trace := explainNode(node)
+			result = chainResult(result, i, node.Operator, trace.Result)
+			rule.Children = append(rule.Children, trace)
+		}
+		rule.Result = result
+	}
+	return rule
+}
+
+// chainResult folds the result of the item i of a rule into result as Apply does
+func chainResult(result bool, i int, operator JoinOperator, item bool) bool {
+	if i == 0 {
+		result = operator == AND || operator == NOT
+	}
+	switch operator {
+	case AND, NOT:
+		return result && item
+	case OR:
+		return result || item
+	}
+	return result
+}
+
+func nodeTrace(node *Node, data Data) Trace {
+	trace := Trace{Kind: TraceNode}
+	if node == nil {
+		return trace
+	}
+	trace.ID, trace.Operator = node.ID, string(node.Operator)
+	trace.Result = node.Operator == AND || node.Operator == NOT
+	for _, condition := range node.Condition {
+		child := conditionTrace(condition, data)
+		switch node.Operator {
+		case AND:
+			trace.Result = trace.Result && child.Result
+		case NOT:
+			trace.Result = trace.Result && !child.Result
+		case OR:
+			trace.Result = trace.Result || child.Result
+		}
+		trace.Children = append(trace.Children, child)
+	}
+	if len(node.Condition) == 0 {
+		trace.Result = false
+	}
+	return trace
+}
+
+func explainExpr(e *Expr, data Data) Trace {
+	switch {
+	case e == nil:
+		return Trace{Kind: TraceAnd, Result: true}
+	case e.Condition != nil:
+		return conditionTrace(e.Condition, data)
+	case e.Not != nil:
+		child := explainExpr(e.Not, data)
+		return Trace{Kind: TraceNot, Result: !child.Result, Children: []Trace{child}}
+	case e.Or != nil:
+		trace := Trace{Kind: TraceOr, Children: make([]Trace, len(e.Or))}
+		for i, expr := range e.Or {
+			trace.Children[i] = explainExpr(expr, data)
+			trace.Result = trace.Result || trace.Children[i].Result
+		}
+		return trace
+	}
+	trace := Trace{Kind: TraceAnd, Result: true, Children: make([]Trace, len(e.And))}
+	for i, expr := range e.And {
+		trace.Children[i] = explainExpr(expr, data)
+		trace.Result = trace.Result && trace.Children[i].Result
+	}
+	return trace
+}
+
+func conditionTrace(condition *Condition, data Data) Trace {
+	value, found, ok := condition.check(data)
+	trace := Trace{
+		Kind:     TraceCondition,
+		Operator: string(condition.Operator),
+		Field:    condition.Field,
+		Expected: condition.Value,
+		Actual:   value,
+		Missing:  !found,
+		Result:   ok,
+	}
+	if expr, isExpr := condition.Value.(*Expr); isExpr && found {
+		if items, isList := asList(value); isList {
+			trace.Actual = nil
+			trace.Children = make([]Trace, len(items))
+			for i, item := range items {
+				child := explainExpr(expr, item)
+				trace.Children[i] = Trace{Kind: TraceItem, Field: "[" + strconv.Itoa(i) + "]", Result: child.Result, Children: []Trace{child}}
+			}
+		}
+	}
+	return trace
+}
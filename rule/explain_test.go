@@ -0,0 +1,202 @@
+package rule
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func traceJSON(t *testing.T, trace Trace) string {
+	t.Helper()
+	data, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExplainNodes(t *testing.T) {
+	r := New("adult")
+	r.And(NewCondition("age", GTE, 18), NewCondition("country", EQ, "US")).ID = "profile"
+	r.Or(NewCondition("vip", EQ, true))
+	r.Not(NewCondition("status", EQ, "blocked"))
+
+	tests := []struct {
+		data Data
+		want string
+	}{
+		{
+			data: map[string]any{"age": 20, "country": "FR", "status": "active"},
+			want: `{"kind":"rule","id":"adult","result":false,"children":[` +
+				`{"kind":"node","id":"profile","operator":"\u0026\u0026","result":false,"children":[` +
+				`{"kind":"condition","operator":"gte","field":"age","expected":18,"actual":20,"result":true},` +
+				`{"kind":"condition","operator":"eq","field":"country","expected":"US","actual":"FR","result":false}]},` +
+				`{"kind":"node","operator":"||","result":false,"children":[` +
+				`{"kind":"condition","operator":"eq","field":"vip","expected":true,"missing":true,"result":false}]},` +
+				`{"kind":"node","operator":"!","result":true,"children":[` +
+				`{"kind":"condition","operator":"eq","field":"status","expected":"blocked","actual":"active","result":false}]}]}`,
+		},
+		{
+			data: map[string]any{"age": 20, "country": "US", "vip": true, "status": "blocked"},
+			want: `{"kind":"rule","id":"adult","result":false,"children":[` +
+				`{"kind":"node","id":"profile","operator":"\u0026\u0026","result":true,"children":[` +
+				`{"kind":"condition","operator":"gte","field":"age","expected":18,"actual":20,"result":true},` +
+				`{"kind":"condition","operator":"eq","field":"country","expected":"US","actual":"US","result":true}]},` +
+				`{"kind":"node","operator":"||","result":true,"children":[` +
+				`{"kind":"condition","operator":"eq","field":"vip","expected":true,"actual":true,"result":true}]},` +
+				`{"kind":"node","operator":"!","result":false,"children":[` +
+				`{"kind":"condition","operator":"eq","field":"status","expected":"blocked","actual":"blocked","result":true}]}]}`,
+		},
+	}
+	for _, test := range tests {
+		trace := r.Explain(test.data)
+		if got := traceJSON(t, trace); got != test.want {
+			t.Errorf("Explain(%v) =\n%s\nwant\n%s", test.data, got, test.want)
+		}
+		if applied := r.Apply(test.data) != nil; trace.Result != applied {
+			t.Errorf("Explain(%v) returned %v, Apply %v", test.data, trace.Result, applied)
+		}
+	}
+}
+
+func TestExplainGroupsAndJoins(t *testing.T) {
+	groups := New("groups")
+	adult := groups.And(NewCondition("age", GTE, 18))
+	adult.ID = "adult"
+	local := groups.Or(NewCondition("country", EQ, "US"), NewCondition("country", EQ, "CA"))
+	local.ID = "local"
+	groups.Group(adult, AND, local).ID = "adult-local"
+	groups.Group(adult, OR, adult)
+
+	data := map[string]any{"age": 20, "country": "FR"}
+	trace := groups.Explain(data)
+	want := `{"kind":"rule","id":"groups","result":true,"children":[` +
+		`{"kind":"group","id":"adult-local","operator":"\u0026\u0026","result":false,"children":[` +
+		`{"kind":"node","id":"adult","operator":"\u0026\u0026","result":true,"children":[{"kind":"condition","operator":"gte","field":"age","expected":18,"actual":20,"result":true}]},` +
+		`{"kind":"node","id":"local","operator":"||","result":false,"children":[` +
+		`{"kind":"condition","operator":"eq","field":"country","expected":"US","actual":"FR","result":false},` +
+		`{"kind":"condition","operator":"eq","field":"country","expected":"CA","actual":"FR","result":false}]}]},` +
+		`{"kind":"group","operator":"||","result":true,"children":[` +
+		`{"kind":"node","id":"adult","operator":"\u0026\u0026","result":true,"children":[{"kind":"condition","operator":"gte","field":"age","expected":18,"actual":20,"result":true}]},` +
+		`{"kind":"node","id":"adult","operator":"\u0026\u0026","result":true,"children":[{"kind":"condition","operator":"gte","field":"age","expected":18,"actual":20,"result":true}]}]}]}`
+	if got := traceJSON(t, trace); got != want {
+		t.Errorf("Explain of groups =\n%s\nwant\n%s", got, want)
+	}
+
+	joins := New("joins")
+	adult = joins.And(NewCondition("age", GTE, 18))
+	local = joins.And(NewCondition("country", EQ, "CA"))
+	vip := joins.Or(NewCondition("vip", EQ, true))
+	join := joins.Join(joins.Group(adult, AND, local), OR, joins.Group(vip, AND, vip))
+	join.ID = "either"
+
+	for _, test := range []struct {
+		data Data
+		want []bool
+	}{
+		{data: map[string]any{"age": 30, "country": "CA"}, want: []bool{true, true, false}},
+		{data: map[string]any{"age": 10, "vip": true}, want: []bool{true, false, true}},
+		{data: map[string]any{"age": 10}, want: []bool{false, false, false}},
+	} {
+		trace := joins.Explain(test.data)
+		if len(trace.Children) != 1 {
+			t.Fatalf("Explain of a join returned %d children", len(trace.Children))
+		}
+		step := trace.Children[0]
+		if step.Kind != TraceJoin || step.ID != "either" || step.Operator != "||" || len(step.Children) != 2 {
+			t.Fatalf("Explain returned the join step %+v", step)
+		}
+		got := []bool{trace.Result, step.Children[0].Result, step.Children[1].Result}
+		if got[0] != test.want[0] || got[1] != test.want[1] || got[2] != test.want[2] {
+			t.Errorf("Explain(%v) results = %v, want %v", test.data, got, test.want)
+		}
+		if step.Children[0].Kind != TraceGroup || step.Children[0].Children[1].Children[0].Field != "country" {
+			t.Errorf("Explain(%v) traced the left group as %+v", test.data, step.Children[0])
+		}
+		if applied := joins.Apply(test.data) != nil; trace.Result != applied {
+			t.Errorf("Explain(%v) returned %v, Apply %v", test.data, trace.Result, applied)
+		}
+	}
+}
+
+func TestExplainExpression(t *testing.T) {
+	r, err := Parse(`!(email exists) || nickname is_empty && phone eq null`, "contact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]any{"email": "bob@example.com", "phone": nil}
+	want := `{"kind":"rule","id":"contact","result":true,"children":[` +
+		`{"kind":"or","result":true,"children":[` +
+		`{"kind":"not","result":false,"children":[{"kind":"condition","operator":"exists","field":"email","actual":"bob@example.com","result":true}]},` +
+		`{"kind":"and","result":true,"children":[` +
+		`{"kind":"condition","operator":"is_empty","field":"nickname","missing":true,"result":true},` +
+		`{"kind":"condition","operator":"eq","field":"phone","result":true}]}]}]}`
+	if got := traceJSON(t, r.Explain(data)); got != want {
+		t.Errorf("Explain =\n%s\nwant\n%s", got, want)
+	}
+
+	empty := New("empty").SetExpression(All())
+	if got := traceJSON(t, empty.Explain(nil)); got != `{"kind":"rule","id":"empty","result":true,"children":[{"kind":"and","result":true}]}` {
+		t.Errorf("Explain of an empty and = %s", got)
+	}
+}
+
+func TestExplainItems(t *testing.T) {
+	r, err := Parse(`items any (price gt 100) && items all (sku exists)`, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]any{"items": []any{
+		map[string]any{"sku": "A", "price": 150},
+		map[string]any{"price": 20},
+	}}
+	trace := r.Explain(data)
+	want := `{"kind":"rule","id":"order","result":false,"children":[{"kind":"and","result":false,"children":[` +
+		`{"kind":"condition","operator":"any","field":"items","expected":{"field":"price","operator":"gt","value":100},"result":true,"children":[` +
+		`{"kind":"item","field":"[0]","result":true,"children":[{"kind":"condition","operator":"gt","field":"price","expected":100,"actual":150,"result":true}]},` +
+		`{"kind":"item","field":"[1]","result":false,"children":[{"kind":"condition","operator":"gt","field":"price","expected":100,"actual":20,"result":false}]}]},` +
+		`{"kind":"condition","operator":"all","field":"items","expected":{"field":"sku","operator":"exists","value":null},"result":false,"children":[` +
+		`{"kind":"item","field":"[0]","result":true,"children":[{"kind":"condition","operator":"exists","field":"sku","actual":"A","result":true}]},` +
+		`{"kind":"item","field":"[1]","result":false,"children":[{"kind":"condition","operator":"exists","field":"sku","missing":true,"result":false}]}]}]}]}`
+	if got := traceJSON(t, trace); got != want {
+		t.Errorf("Explain =\n%s\nwant\n%s", got, want)
+	}
+
+	for _, data := range []Data{map[string]any{}, map[string]any{"items": "none"}} {
+		trace := r.Explain(data)
+		for _, step := range trace.Children[0].Children {
+			if step.Result || len(step.Children) != 0 {
+				t.Errorf("Explain(%v) traced %s with result %v and %d items", data, step.Operator, step.Result, len(step.Children))
+			}
+		}
+	}
+
+	r, err = Parse(`tags any ["sale", "new"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace = r.Explain(map[string]any{"tags": []string{"new"}})
+	if step := trace.Children[0]; !step.Result || len(step.Children) != 0 || step.Actual == nil {
+		t.Errorf("Explain of a quantifier with a list operand = %+v, want the value and no item steps", step)
+	}
+}
+
+func TestExplainUnmarshal(t *testing.T) {
+	r, err := Parse(`age gte 18 && country in ["US", "CA"]`, "adult")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(r.Explain(map[string]any{"age": 20, "country": "MX"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace Trace
+	if err := json.Unmarshal(data, &trace); err != nil {
+		t.Fatal(err)
+	}
+	if trace.Kind != TraceRule || trace.Result || len(trace.Children) != 1 || len(trace.Children[0].Children) != 2 {
+		t.Fatalf("Unmarshal(%s) = %+v", data, trace)
+	}
+	if step := trace.Children[0].Children[1]; step.Field != "country" || step.Actual != "MX" || step.Result {
+		t.Errorf("Unmarshal(%s) read the country step as %+v", data, step)
+	}
+}
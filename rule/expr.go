@@ -0,0 +1,318 @@
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Expr is a boolean expression over data, either a condition or one of and, or and not of other expressions
+// Its json form is the filter format of db.Filter, so one document drives the database query and the rule:
+//
+//	{"and":[{"field":"age","operator":"gte","value":18},{"not":{"field":"country","operator":"in","value":["US"]}}]}
+//
+// An and of no expression is true and an or of no expression is false
+type Expr struct {
+	And       []*Expr
+	Or        []*Expr
+	Not       *Expr
+	Condition *Condition
+}
+
+var ErrInvalidExpr = errors.New("rule: invalid expression")
+
+func All(exprs ...*Expr) *Expr {
+	return &Expr{And: append([]*Expr{}, exprs...)}
+}
+
+func Any(exprs ...*Expr) *Expr {
+	return &Expr{Or: append([]*Expr{}, exprs...)}
+}
+
+func None(expr *Expr) *Expr {
+	return &Expr{Not: expr}
+}
+
+func Match(condition *Condition) *Expr {
+	return &Expr{Condition: condition}
+}
+
+// Evaluate tells whether data satisfies the expression
+func (e *Expr) Evaluate(data Data) bool {
+	switch {
+	case e == nil:
+		return true
+	case e.Condition != nil:
+		return e.Condition.Validate(data)
+	case e.Not != nil:
+		return !e.Not.Evaluate(data)
+	case e.Or != nil:
+		for _, expr := range e.Or {
+			if expr.Evaluate(data) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, expr := range e.And {
+		if !expr.Evaluate(data) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expr) MarshalJSON() ([]byte, error) {
+	switch {
+	case e.Condition != nil:
+		return json.Marshal(e.Condition)
+	case e.Not != nil:
+		return json.Marshal(map[string]*Expr{"not": e.Not})
+	case e.Or != nil:
+		return json.Marshal(map[string][]*Expr{"or": e.Or})
+	}
+	and := e.And
+	if and == nil {
+		and = []*Expr{}
+	}
+	return json.Marshal(map[string][]*Expr{"and": and})
+}
+
+// UnmarshalJSON reads a filter document, "op" is read as "operator" and unknown keys are rejected
+func (e *Expr) UnmarshalJSON(data []byte) error {
+	var node struct {
+		And      []*Expr           `json:"and"`
+		Or       []*Expr           `json:"or"`
+		Not      *Expr             `json:"not"`
+		Field    string            `json:"field"`
+		Operator ConditionOperator `json:"operator"`
+		Op       ConditionOperator `json:"op"`
+		Value    json.RawMessage   `json:"value"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&node); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidExpr, err)
+	}
+	if node.Operator == "" {
+		node.Operator = node.Op
+	}
+	kinds := 0
+	for _, set := range []bool{node.And != nil, node.Or != nil, node.Not != nil, node.Field != "" || node.Operator != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return fmt.Errorf("%w: a node has one of and, or, not and field", ErrInvalidExpr)
+	}
+	*e = Expr{And: node.And, Or: node.Or, Not: node.Not}
+	if node.Field != "" || node.Operator != "" {
+		if node.Field == "" || node.Operator == "" {
+			return fmt.Errorf("%w: a condition has a field and an operator", ErrInvalidExpr)
+		}
+		value, err := decodeValue(node.Operator, node.Value)
+		if err != nil {
+			return err
+		}
+		if err := checkOperand(node.Operator, value); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidExpr, err)
+		}
+		e.Condition = NewCondition(node.Field, node.Operator, value)
+	} else if node.Or == nil && node.Not == nil && node.And == nil {
+		e.And = []*Expr{}
+	}
+	return nil
+}
+
+// normalizeValue turns json numbers into int when integral and float64 otherwise
+func normalizeValue(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil && i >= math.MinInt && i <= math.MaxInt {
+			return int(i)
+		}
+		f, _ := value.Float64()
+		return f
+	case []any:
+		for i := range value {
+			value[i] = normalizeValue(value[i])
+		}
+	}
+	return value
+}
+
+// decodeValue reads the value of a condition, the value of any, all and none may be an expression applied to each item
+func decodeValue(operator ConditionOperator, data json.RawMessage) (any, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if data[0] == '{' && (operator == ANY || operator == ALL || operator == NONE) {
+		expr := &Expr{}
+		if err := json.Unmarshal(data, expr); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExpr, err)
+	}
+	return normalizeValue(value), nil
+}
+
+func falseExpr() *Expr {
+	return &Expr{Or: []*Expr{}}
+}
+
+// Expression returns the expression of the rule, the one it was parsed from or the one its nodes, groups and joins evaluate as in Apply
+func (r *Rule) Expression() *Expr {
+	if r.expr != nil {
+		return r.expr
+	}
+	switch {
+	case len(r.joins) > 0:
+		return joinExpr(r.joins[len(r.joins)-1])
+	case len(r.groups) > 0:
+		var result *Expr
+		for i, group := range r.groups {
+			switch group.Operator {
+			case AND:
+				result = chainExpr(result, i, AND, groupExpr(group))
+			case OR:
+				result = chainExpr(result, i, OR, groupExpr(group))
+			}
+		}
+		if result == nil {
+			return falseExpr()
+		}
+		return result
+	}
+	var result *Expr
+	for i, node := range r.nodes {
+		if len(node.Condition) == 0 {
+			continue
+		}
+		operator := node.Operator
+		if operator == NOT {
+			operator = AND
+		}
+		result = chainExpr(result, i, operator, nodeExpr(node))
+	}
+	if result == nil {
+		return falseExpr()
+	}
+	return result
+}
+
+// chainExpr folds expr into result like Apply does, which starts from the first item when it is the first of the rule and from false otherwise
+func chainExpr(result *Expr, i int, operator JoinOperator, expr *Expr) *Expr {
+	if result == nil {
+		if i == 0 {
+			return expr
+		}
+		result = falseExpr()
+	}
+	if operator == OR {
+		return Any(result, expr)
+	}
+	return All(result, expr)
+}
+
+func nodeExpr(node *Node) *Expr {
+	if node == nil || len(node.Condition) == 0 {
+		return falseExpr()
+	}
+	exprs := make([]*Expr, 0, len(node.Condition))
+	for _, condition := range node.Condition {
+		expr := Match(condition)
+		if node.Operator == NOT {
+			expr = None(expr)
+		}
+		exprs = append(exprs, expr)
+	}
+	switch node.Operator {
+	case AND, NOT:
+		return All(exprs...)
+	case OR:
+		return Any(exprs...)
+	}
+	return falseExpr()
+}
+
+func groupExpr(group *Group) *Expr {
+	if group == nil {
+		return falseExpr()
+	}
+	switch group.Operator {
+	case AND:
+		return All(nodeExpr(group.Left), nodeExpr(group.Right))
+	case OR:
+		return Any(nodeExpr(group.Left), nodeExpr(group.Right))
+	}
+	return falseExpr()
+}
+
+func joinExpr(join *Join) *Expr {
+	switch join.Operator {
+	case AND:
+		return All(groupExpr(join.Left), groupExpr(join.Right))
+	case OR:
+		return Any(groupExpr(join.Left), groupExpr(join.Right))
+	}
+	return falseExpr()
+}
+
+// SetExpression replaces the conditions of the rule with expr
+func (r *Rule) SetExpression(expr *Expr) *Rule {
+	r.expr = expr
+	r.nodes, r.groups, r.joins = nil, nil, nil
+	return r
+}
+
+type ruleJSON struct {
+	ID         string          `json:"id"`
+	Expression json.RawMessage `json:"expression"`
+}
+
+// MarshalJSON writes the id and the expression of the rule, the Handler is not serialized
+func (r *Rule) MarshalJSON() ([]byte, error) {
+	expression, err := json.Marshal(r.Expression())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ruleJSON{ID: r.ID, Expression: expression})
+}
+
+// UnmarshalJSON reads a rule written by MarshalJSON, the expression may also be a string in the syntax of Parse
+// The Handler of the rule is kept so a rule can be reloaded in place
+func (r *Rule) UnmarshalJSON(data []byte) error {
+	var rule ruleJSON
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return err
+	}
+	if len(rule.Expression) == 0 || string(rule.Expression) == "null" {
+		return fmt.Errorf("%w: rule %q has no expression", ErrInvalidExpr, rule.ID)
+	}
+	var expr *Expr
+	var source string
+	if err := json.Unmarshal(rule.Expression, &source); err == nil {
+		if expr, err = ParseExpr(source); err != nil {
+			return err
+		}
+	} else {
+		expr = &Expr{}
+		if err := json.Unmarshal(rule.Expression, expr); err != nil {
+			return err
+		}
+	}
+	r.ID = rule.ID
+	r.SetExpression(expr)
+	return nil
+}
@@ -0,0 +1,479 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sujit-baniya/pkg/maps"
+)
+
+const (
+	MATCHES    ConditionOperator = "matches"
+	IsEmpty    ConditionOperator = "is_empty"
+	EXISTS     ConditionOperator = "exists"
+	BEFORE     ConditionOperator = "before"
+	AFTER      ConditionOperator = "after"
+	WithinLast ConditionOperator = "within_last"
+	WithinNext ConditionOperator = "within_next"
+	ANY        ConditionOperator = "any"
+	ALL        ConditionOperator = "all"
+	NONE       ConditionOperator = "none"
+	LenEQ      ConditionOperator = "len_eq"
+	LenNEQ     ConditionOperator = "len_neq"
+	LenGT      ConditionOperator = "len_gt"
+	LenGTE     ConditionOperator = "len_gte"
+	LenLT      ConditionOperator = "len_lt"
+	LenLTE     ConditionOperator = "len_lte"
+)
+
+var (
+	ErrUnknownOperator = errors.New("rule: unknown operator")
+	ErrInvalidOperand  = errors.New("rule: invalid operand")
+)
+
+// OperatorFunc tells whether the value of a field satisfies an operator applied with the condition value as operand
+type OperatorFunc func(value, operand any) bool
+
+type operator struct {
+	fn OperatorFunc
+	// missing operators are called with a nil value when data has no such field, the others are false then
+	missing bool
+	// check validates the operand when a rule is parsed or read from json
+	check func(operand any) error
+}
+
+var (
+	operatorsMu      sync.RWMutex
+	operators        = map[ConditionOperator]operator{}
+	operatorNameRule = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// now is the clock of the date operators
+	now = time.Now
+)
+
+// RegisterOperator adds an operator to the conditions, Parse and json rules, such as ip_in_cidr or is_disposable_email
+// fn is not called for a missing field, registering a name again replaces the operator unless it is a built-in one
+func RegisterOperator(name ConditionOperator, fn OperatorFunc) error {
+	if !operatorNameRule.MatchString(string(name)) || fn == nil {
+		return fmt.Errorf("%w %q", ErrUnknownOperator, name)
+	}
+	operatorsMu.Lock()
+	defer operatorsMu.Unlock()
+	if _, ok := builtinOperators[name]; ok {
+		return fmt.Errorf("rule: operator %q is built in", name)
+	}
+	operators[name] = operator{fn: fn}
+	return nil
+}
+
+func lookupOperator(name ConditionOperator) (operator, bool) {
+	if op, ok := builtinOperators[name]; ok {
+		return op, true
+	}
+	operatorsMu.RLock()
+	op, ok := operators[name]
+	operatorsMu.RUnlock()
+	return op, ok
+}
+
+// checkOperand returns an error when the operator is unknown or cannot take operand
+func checkOperand(name ConditionOperator, operand any) error {
+	op, ok := lookupOperator(name)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownOperator, name)
+	}
+	if op.check == nil {
+		return nil
+	}
+	if err := op.check(operand); err != nil {
+		return fmt.Errorf("%w for %s: %v", ErrInvalidOperand, name, err)
+	}
+	return nil
+}
+
+var builtinOperators map[ConditionOperator]operator
+
+func init() {
+	compare := func(accept func(int) bool) OperatorFunc {
+		return func(value, operand any) bool {
+			c, ok := compareValues(value, operand)
+			return ok && accept(c)
+		}
+	}
+	length := func(accept func(int) bool) operator {
+		return operator{fn: func(value, operand any) bool {
+			n, ok := lengthOf(value)
+			if !ok {
+				return false
+			}
+			c, ok := compareValues(int64(n), operand)
+			return ok && accept(c)
+		}, check: checkNumber}
+	}
+	builtinOperators = map[ConditionOperator]operator{
+		EQ:          {fn: eq},
+		NEQ:         {fn: neq},
+		GT:          {fn: compare(func(c int) bool { return c > 0 })},
+		GTE:         {fn: compare(func(c int) bool { return c >= 0 })},
+		LT:          {fn: compare(func(c int) bool { return c < 0 })},
+		LTE:         {fn: compare(func(c int) bool { return c <= 0 })},
+		BETWEEN:     {fn: between, check: checkBounds},
+		IN:          {fn: in, check: checkList},
+		NotIn:       {fn: notIn, check: checkList},
+		CONTAINS:    {fn: contains},
+		NotContains: {fn: notContains},
+		StartsWith:  {fn: startsWith, check: checkString},
+		EndsWith:    {fn: endsWith, check: checkString},
+		MATCHES:     {fn: matches, check: checkPattern},
+		IsEmpty:     {fn: isEmpty, missing: true},
+		EXISTS:      {fn: func(value, _ any) bool { return value != nil }, missing: true},
+		BEFORE:      {fn: before, check: checkTime},
+		AFTER:       {fn: after, check: checkTime},
+		WithinLast:  {fn: withinLast, check: checkDuration},
+		WithinNext:  {fn: withinNext, check: checkDuration},
+		ANY:         {fn: quantifier(func(matched, total int) bool { return matched > 0 }), check: checkQuantified},
+		ALL:         {fn: quantifier(func(matched, total int) bool { return matched == total }), check: checkQuantified},
+		NONE:        {fn: quantifier(func(matched, total int) bool { return matched == 0 }), check: checkQuantified},
+		LenEQ:       length(func(c int) bool { return c == 0 }),
+		LenNEQ:      length(func(c int) bool { return c != 0 }),
+		LenGT:       length(func(c int) bool { return c > 0 }),
+		LenGTE:      length(func(c int) bool { return c >= 0 }),
+		LenLT:       length(func(c int) bool { return c < 0 }),
+		LenLTE:      length(func(c int) bool { return c <= 0 }),
+	}
+}
+
+func eq(value, operand any) bool {
+	equal, _ := equalValues(value, operand)
+	return equal
+}
+
+func neq(value, operand any) bool {
+	equal, comparable := equalValues(value, operand)
+	return comparable && !equal
+}
+
+func between(value, operand any) bool {
+	bounds, ok := asList(operand)
+	if !ok || len(bounds) != 2 {
+		return false
+	}
+	low, ok := compareValues(value, bounds[0])
+	if !ok || low < 0 {
+		return false
+	}
+	high, ok := compareValues(value, bounds[1])
+	return ok && high <= 0
+}
+
+func in(value, operand any) bool {
+	items, ok := asList(operand)
+	return ok && containsValue(items, value)
+}
+
+func notIn(value, operand any) bool {
+	items, ok := asList(operand)
+	return ok && excludesValue(items, value)
+}
+
+// contains tells whether a string value contains the operand or a list value holds it
+func contains(value, operand any) bool {
+	if items, ok := asList(value); ok {
+		return containsValue(items, operand)
+	}
+	text, ok := asString(value)
+	if !ok {
+		return false
+	}
+	part, ok := asString(operand)
+	return ok && strings.Contains(text, part)
+}
+
+func notContains(value, operand any) bool {
+	if items, ok := asList(value); ok {
+		return excludesValue(items, operand)
+	}
+	text, ok := asString(value)
+	if !ok {
+		return false
+	}
+	part, ok := asString(operand)
+	return ok && !strings.Contains(text, part)
+}
+
+func startsWith(value, operand any) bool {
+	text, ok := asString(value)
+	if !ok {
+		return false
+	}
+	prefix, ok := asString(operand)
+	return ok && strings.HasPrefix(text, prefix)
+}
+
+func endsWith(value, operand any) bool {
+	text, ok := asString(value)
+	if !ok {
+		return false
+	}
+	suffix, ok := asString(operand)
+	return ok && strings.HasSuffix(text, suffix)
+}
+
+// containsValue tells whether one of items equals value
+func containsValue(items []any, value any) bool {
+	for _, item := range items {
+		if equal, _ := equalValues(value, item); equal {
+			return true
+		}
+	}
+	return false
+}
+
+// excludesValue tells whether value compares with every item and equals none
+func excludesValue(items []any, value any) bool {
+	for _, item := range items {
+		if equal, comparable := equalValues(value, item); equal || !comparable {
+			return false
+		}
+	}
+	return true
+}
+
+// maxCachedPatterns bounds the regular expressions kept compiled for rules evaluated without Compile
+const maxCachedPatterns = 256
+
+var patterns = maps.NewCache[string, *regexp.Regexp](maps.CacheConfig[string, *regexp.Regexp]{Capacity: maxCachedPatterns, Policy: maps.LRU})
+
+// compilePattern returns the regular expression of a pattern, the most recently used valid patterns are kept compiled
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Get(pattern); ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Set(pattern, re)
+	return re, nil
+}
+
+// matches tells whether a string value matches the regular expression of the operand
+func matches(value, operand any) bool {
+	text, ok := asString(value)
+	if !ok {
+		return false
+	}
+	pattern, ok := asString(operand)
+	if !ok {
+		return false
+	}
+	re, err := compilePattern(pattern)
+	return err == nil && re.MatchString(text)
+}
+
+// isEmpty is true for a missing or nil value, an empty string and an empty list or map
+func isEmpty(value, _ any) bool {
+	if value == nil || scalar(value) == nil {
+		return true
+	}
+	n, ok := lengthOf(value)
+	return ok && n == 0
+}
+
+// lengthOf returns the number of characters of a string or of items of a list or map
+func lengthOf(value any) (int, bool) {
+	if text, ok := asString(value); ok {
+		return utf8.RuneCountInString(text), true
+	}
+	if items, ok := asList(value); ok {
+		return len(items), true
+	}
+	if m, ok := value.(map[string]any); ok {
+		return len(m), true
+	}
+	return 0, false
+}
+
+// operandTime returns the time of a before or after operand, "now" is the current time
+func operandTime(operand any) (time.Time, bool) {
+	if s, ok := operand.(string); ok && strings.EqualFold(s, "now") {
+		return now(), true
+	}
+	return asTime(scalar(operand))
+}
+
+func before(value, operand any) bool {
+	t, ok := asTime(scalar(value))
+	if !ok {
+		return false
+	}
+	limit, ok := operandTime(operand)
+	return ok && t.Before(limit)
+}
+
+func after(value, operand any) bool {
+	t, ok := asTime(scalar(value))
+	if !ok {
+		return false
+	}
+	limit, ok := operandTime(operand)
+	return ok && t.After(limit)
+}
+
+// withinLast tells whether a time value is between the operand duration ago and now
+func withinLast(value, operand any) bool {
+	t, ok := asTime(scalar(value))
+	if !ok {
+		return false
+	}
+	d, err := parseDuration(operand)
+	if err != nil {
+		return false
+	}
+	current := now()
+	return !t.Before(current.Add(-d)) && !t.After(current)
+}
+
+// withinNext tells whether a time value is between now and the operand duration from now
+func withinNext(value, operand any) bool {
+	t, ok := asTime(scalar(value))
+	if !ok {
+		return false
+	}
+	d, err := parseDuration(operand)
+	if err != nil {
+		return false
+	}
+	current := now()
+	return !t.Before(current) && !t.After(current.Add(d))
+}
+
+// parseDuration reads durations such as 7d, 2w or 1d12h, d and w are days and weeks and the other units those of time.ParseDuration
+func parseDuration(operand any) (time.Duration, error) {
+	s, ok := operand.(string)
+	if !ok {
+		return 0, fmt.Errorf("%v is not a duration", operand)
+	}
+	var total time.Duration
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		end := strings.IndexAny(rest, "dw")
+		if end < 0 {
+			d, err := time.ParseDuration(rest)
+			if err != nil {
+				return 0, err
+			}
+			return total + d, nil
+		}
+		n, err := strconv.ParseFloat(rest[:end], 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		unit := 24 * time.Hour
+		if rest[end] == 'w' {
+			unit *= 7
+		}
+		total += time.Duration(n * float64(unit))
+		rest = rest[end+1:]
+	}
+	if total <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return total, nil
+}
+
+// quantifier applies an expression to each item of a list value, or with a list operand checks which of its items the value holds
+//
+//	items any (price gt 100)       some item has a price above 100
+//	tags all ["new", "sale"]       tags hold both new and sale
+func quantifier(accept func(matched, total int) bool) OperatorFunc {
+	return func(value, operand any) bool {
+		items, ok := asList(value)
+		if !ok {
+			return false
+		}
+		matched, total := 0, 0
+		switch operand := operand.(type) {
+		case *Expr:
+			total = len(items)
+			for _, item := range items {
+				if operand.Evaluate(item) {
+					matched++
+				}
+			}
+		default:
+			wanted, ok := asList(operand)
+			if !ok {
+				return false
+			}
+			total = len(wanted)
+			for _, want := range wanted {
+				if containsValue(items, want) {
+					matched++
+				}
+			}
+		}
+		return accept(matched, total)
+	}
+}
+
+func checkNumber(operand any) error {
+	if !isNumber(scalar(operand)) {
+		return fmt.Errorf("%v is not a number", operand)
+	}
+	return nil
+}
+
+func checkString(operand any) error {
+	if _, ok := asString(operand); !ok {
+		return fmt.Errorf("%v is not a string", operand)
+	}
+	return nil
+}
+
+func checkList(operand any) error {
+	if _, ok := asList(operand); !ok {
+		return fmt.Errorf("%v is not a list", operand)
+	}
+	return nil
+}
+
+func checkBounds(operand any) error {
+	if bounds, ok := asList(operand); !ok || len(bounds) != 2 {
+		return fmt.Errorf("%v is not a list of two bounds", operand)
+	}
+	return nil
+}
+
+func checkPattern(operand any) error {
+	pattern, ok := asString(operand)
+	if !ok {
+		return fmt.Errorf("%v is not a string", operand)
+	}
+	_, err := regexp.Compile(pattern)
+	return err
+}
+
+func checkTime(operand any) error {
+	if _, ok := operandTime(operand); !ok {
+		return fmt.Errorf("%v is not a time", operand)
+	}
+	return nil
+}
+
+func checkDuration(operand any) error {
+	_, err := parseDuration(operand)
+	return err
+}
+
+func checkQuantified(operand any) error {
+	if _, ok := operand.(*Expr); ok {
+		return nil
+	}
+	return checkList(operand)
+}
@@ -0,0 +1,265 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCompilePatternCache(t *testing.T) {
+	patterns.Clear()
+	if _, err := compilePattern("("); err == nil {
+		t.Fatal("compiled the invalid pattern (")
+	}
+	if _, cached := patterns.Get("("); cached {
+		t.Error("cached the invalid pattern (")
+	}
+	for i := 0; i < 2*maxCachedPatterns; i++ {
+		compilePattern(fmt.Sprintf("^a{%d}$", i))
+	}
+	if n := patterns.Len(); n > maxCachedPatterns {
+		t.Errorf("cached %d patterns, want at most %d", n, maxCachedPatterns)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		operand any
+		want    time.Duration
+	}{
+		{operand: "7d", want: 7 * 24 * time.Hour},
+		{operand: "1d12h", want: 36 * time.Hour},
+		{operand: "2w", want: 14 * 24 * time.Hour},
+		{operand: "1w2d", want: 9 * 24 * time.Hour},
+		{operand: "1.5d", want: 36 * time.Hour},
+		{operand: "90m", want: 90 * time.Minute},
+	}
+	for _, test := range tests {
+		got, err := parseDuration(test.operand)
+		if err != nil || got != test.want {
+			t.Errorf("parseDuration(%v) = %v, %v, want %v", test.operand, got, err, test.want)
+		}
+	}
+	for _, operand := range []any{"", "0d", "-1d", "xd", "1dx", "7", 7} {
+		if got, err := parseDuration(operand); err == nil {
+			t.Errorf("parseDuration(%v) = %v, want an error", operand, got)
+		}
+	}
+}
+
+// evaluate parses expression and applies it to data
+func evaluate(t *testing.T, expression string, data Data) bool {
+	t.Helper()
+	expr, err := ParseExpr(expression)
+	if err != nil {
+		t.Fatalf("ParseExpr(%q): %v", expression, err)
+	}
+	return expr.Evaluate(data)
+}
+
+func TestDateOperators(t *testing.T) {
+	current := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+
+	data := map[string]any{
+		"seen":     current.Add(-3 * 24 * time.Hour),
+		"expires":  current.Add(36 * time.Hour).Format(time.RFC3339),
+		"created":  current.Add(-15 * 24 * time.Hour).Format(time.RFC3339),
+		"renewal":  current.Unix() + 20*24*3600,
+		"birthday": "1990-05-01T00:00:00Z",
+		"name":     "bob",
+	}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `seen within_last "7d"`, want: true},
+		{expression: `seen within_last "2d"`, want: false},
+		{expression: `seen within_next "7d"`, want: false},
+		{expression: `expires within_next "1d12h"`, want: true},
+		{expression: `expires within_next "1d"`, want: false},
+		{expression: `expires within_last "7d"`, want: false},
+		{expression: `created within_last "2w"`, want: false},
+		{expression: `created within_last "3w"`, want: true},
+		{expression: `renewal within_next "3w"`, want: true},
+		{expression: `renewal within_next "2w"`, want: false},
+		{expression: `name within_last "7d"`, want: false},
+		{expression: `missing within_last "7d"`, want: false},
+		{expression: `seen before "now"`, want: true},
+		{expression: `expires after "now"`, want: true},
+		{expression: `birthday before "2000-01-01T00:00:00Z"`, want: true},
+		{expression: `birthday after "2000-01-01T00:00:00Z"`, want: false},
+		{expression: `name before "now"`, want: false},
+	}
+	for _, test := range tests {
+		if got := evaluate(t, test.expression, data); got != test.want {
+			t.Errorf("%s = %v, want %v", test.expression, got, test.want)
+		}
+	}
+	for _, expression := range []string{`seen within_last "soon"`, `seen within_last 7`, `seen before "someday"`} {
+		if _, err := ParseExpr(expression); !errors.Is(err, ErrInvalidOperand) {
+			t.Errorf("ParseExpr(%q) returned %v, want ErrInvalidOperand", expression, err)
+		}
+	}
+}
+
+func TestQuantifiers(t *testing.T) {
+	data := map[string]any{
+		"tags": []string{"new", "sale"},
+		"items": []any{
+			map[string]any{"sku": "A", "price": 150},
+			map[string]any{"sku": "B", "price": 20},
+		},
+		"scores": []int{3, 7},
+		"empty":  []any{},
+		"name":   "bob",
+	}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `tags any ["sale", "clearance"]`, want: true},
+		{expression: `tags any ["clearance"]`, want: false},
+		{expression: `tags all ["new", "sale"]`, want: true},
+		{expression: `tags all ["new", "clearance"]`, want: false},
+		{expression: `tags none ["clearance", "used"]`, want: true},
+		{expression: `tags none ["sale"]`, want: false},
+		{expression: `items any (price gt 100)`, want: true},
+		{expression: `items all (price gt 100)`, want: false},
+		{expression: `items all (price gt 10 && sku in ["A", "B"])`, want: true},
+		{expression: `items none (price gt 200)`, want: true},
+		{expression: `items none (price gt 100)`, want: false},
+		{expression: `scores any ($ gt 5)`, want: true},
+		{expression: `scores all ($ gt 5)`, want: false},
+		{expression: `empty any ($ gt 5)`, want: false},
+		{expression: `empty all ($ gt 5)`, want: true},
+		{expression: `empty none ($ gt 5)`, want: true},
+		{expression: `name any ["bob"]`, want: false},
+		{expression: `missing none ["bob"]`, want: false},
+	}
+	for _, test := range tests {
+		if got := evaluate(t, test.expression, data); got != test.want {
+			t.Errorf("%s = %v, want %v", test.expression, got, test.want)
+		}
+	}
+	if _, err := ParseExpr(`tags any "sale"`); !errors.Is(err, ErrInvalidOperand) {
+		t.Errorf("a quantifier with a string operand returned %v, want ErrInvalidOperand", err)
+	}
+}
+
+func TestMissingFieldOperators(t *testing.T) {
+	data := map[string]any{
+		"email":   "bob@example.com",
+		"phone":   nil,
+		"note":    "",
+		"tags":    []string{},
+		"profile": map[string]any{},
+	}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `email exists`, want: true},
+		{expression: `phone exists`, want: false},
+		{expression: `fax exists`, want: false},
+		{expression: `!fax exists`, want: true},
+		{expression: `email is_empty`, want: false},
+		{expression: `phone is_empty`, want: true},
+		{expression: `fax is_empty`, want: true},
+		{expression: `note is_empty`, want: true},
+		{expression: `tags is_empty`, want: true},
+		{expression: `profile is_empty`, want: true},
+		{expression: `fax is_empty && email exists`, want: true},
+		{expression: `fax eq null`, want: false},
+		{expression: `fax neq "x"`, want: false},
+	}
+	for _, test := range tests {
+		if got := evaluate(t, test.expression, data); got != test.want {
+			t.Errorf("%s = %v, want %v", test.expression, got, test.want)
+		}
+	}
+}
+
+func TestLengthOperators(t *testing.T) {
+	data := map[string]any{
+		"name":    "été",
+		"tags":    []string{"new", "sale"},
+		"profile": map[string]any{"a": 1},
+		"age":     30,
+	}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `name len_eq 3`, want: true},
+		{expression: `name len_neq 3`, want: false},
+		{expression: `tags len_gt 1`, want: true},
+		{expression: `tags len_gte 3`, want: false},
+		{expression: `tags len_lt 3`, want: true},
+		{expression: `tags len_lte 1`, want: false},
+		{expression: `profile len_eq 1`, want: true},
+		{expression: `age len_gt 0`, want: false},
+		{expression: `missing len_lt 1`, want: false},
+	}
+	for _, test := range tests {
+		if got := evaluate(t, test.expression, data); got != test.want {
+			t.Errorf("%s = %v, want %v", test.expression, got, test.want)
+		}
+	}
+	if _, err := ParseExpr(`tags len_gt "two"`); !errors.Is(err, ErrInvalidOperand) {
+		t.Errorf("len_gt with a string operand returned %v, want ErrInvalidOperand", err)
+	}
+}
+
+func TestRegisterOperator(t *testing.T) {
+	for _, name := range []ConditionOperator{EQ, IN, MATCHES, ANY, LenGT} {
+		if err := RegisterOperator(name, func(value, operand any) bool { return true }); err == nil {
+			t.Errorf("RegisterOperator(%s) replaced a built-in operator", name)
+		}
+	}
+	if got := evaluate(t, `age eq 1`, map[string]any{"age": 2}); got {
+		t.Error("a rejected registration changed eq")
+	}
+	for _, name := range []ConditionOperator{"", "1st", "has space", "a-b"} {
+		if err := RegisterOperator(name, func(value, operand any) bool { return true }); !errors.Is(err, ErrUnknownOperator) {
+			t.Errorf("RegisterOperator(%q) returned %v, want ErrUnknownOperator", name, err)
+		}
+	}
+	if err := RegisterOperator("divisible_by", nil); !errors.Is(err, ErrUnknownOperator) {
+		t.Errorf("RegisterOperator without a function returned %v, want ErrUnknownOperator", err)
+	}
+
+	t.Cleanup(func() {
+		operatorsMu.Lock()
+		delete(operators, "divisible_by")
+		operatorsMu.Unlock()
+	})
+	if _, err := ParseExpr(`age divisible_by 5`); err == nil {
+		t.Fatal("parsed an operator before it was registered")
+	}
+	divisible := func(value, operand any) bool {
+		n, ok := value.(int)
+		d, _ := operand.(int)
+		return ok && d != 0 && n%d == 0
+	}
+	if err := RegisterOperator("divisible_by", divisible); err != nil {
+		t.Fatal(err)
+	}
+	if !evaluate(t, `age divisible_by 5`, map[string]any{"age": 30}) {
+		t.Error("age 30 is not divisible_by 5")
+	}
+	if evaluate(t, `age divisible_by 7`, map[string]any{"age": 30}) {
+		t.Error("age 30 is divisible_by 7")
+	}
+	if NewCondition("age", "divisible_by", 5).Validate(map[string]any{}) {
+		t.Error("a registered operator matched a missing field")
+	}
+	if err := RegisterOperator("divisible_by", func(value, operand any) bool { return false }); err != nil {
+		t.Fatal(err)
+	}
+	if evaluate(t, `age divisible_by 5`, map[string]any{"age": 30}) {
+		t.Error("registering divisible_by again did not replace it")
+	}
+}
@@ -0,0 +1,303 @@
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrSyntax = errors.New("rule: syntax error")
+
+// operatorSymbols are the symbols accepted in place of the comparison operators
+var operatorSymbols = map[string]ConditionOperator{
+	"==": EQ,
+	"!=": NEQ,
+	">":  GT,
+	">=": GTE,
+	"<":  LT,
+	"<=": LTE,
+}
+
+// exprParser parses the rule language
+//
+//	age gte 18                         a condition is a field, an operator and a value
+//	order.items[0].price gt 10         fields are paths into nested maps, structs and lists
+//	age >= 18                          ==, !=, >, >=, < and <= stand for eq, neq, gt, gte, lt and lte
+//	country in ["US", "CA"]            values are json strings, numbers, booleans, null and lists of them
+//	a && (b || c)                      && binds tighter than ||, parentheses group expressions
+//	!(vip eq true)                     ! negates an expression
+//	email exists                       exists and is_empty take no value
+//	items any (price gt 100)           any, all and none take a list or an expression applied to each item, $ is the item itself
+type exprParser struct {
+	input []rune
+	pos   int
+}
+
+// Parse returns a rule evaluating an expression such as age gte 18 && (country in ["US","CA"] || vip eq true)
+func Parse(expression string, id ...string) (*Rule, error) {
+	expr, err := ParseExpr(expression)
+	if err != nil {
+		return nil, err
+	}
+	return New(id...).SetExpression(expr), nil
+}
+
+// ParseExpr parses an expression in the syntax of Parse
+func ParseExpr(expression string) (*Expr, error) {
+	p := &exprParser{input: []rune(expression)}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", string(p.input[p.pos]))
+	}
+	return expr, nil
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at %d: %s", ErrSyntax, p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// consume skips token when the input continues with it
+func (p *exprParser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(string(p.input[p.pos:]), token) {
+		p.pos += len([]rune(token))
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (*Expr, error) {
+	expr, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []*Expr{expr}
+	for p.consume(string(OR)) {
+		if expr, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return Any(exprs...), nil
+}
+
+func (p *exprParser) parseAnd() (*Expr, error) {
+	expr, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []*Expr{expr}
+	for p.consume(string(AND)) {
+		if expr, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return All(exprs...), nil
+}
+
+func (p *exprParser) parseUnary() (*Expr, error) {
+	if p.consume(string(NOT)) {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return None(expr), nil
+	}
+	if p.consume("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return expr, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *exprParser) parseCondition() (*Expr, error) {
+	field := p.scan(isFieldRune)
+	if field == "" {
+		if p.pos == len(p.input) {
+			return nil, p.errorf("expected a condition")
+		}
+		return nil, p.errorf("expected a field, got %q", string(p.input[p.pos]))
+	}
+	return p.parseTest(field)
+}
+
+// parseTest parses the operator and value applied to field
+func (p *exprParser) parseTest(field string) (*Expr, error) {
+	p.skipSpaces()
+	var operator ConditionOperator
+	if symbol := p.scan(isSymbolRune); symbol != "" {
+		op, ok := operatorSymbols[symbol]
+		if !ok {
+			p.pos -= len([]rune(symbol))
+			return nil, p.errorf("unknown operator %q", symbol)
+		}
+		operator = op
+	} else if operator = ConditionOperator(p.scan(isOperatorRune)); operator == "" {
+		return nil, p.errorf("expected an operator after %s", field)
+	}
+	op, ok := lookupOperator(operator)
+	if !ok {
+		p.pos -= len([]rune(operator))
+		return nil, p.errorf("unknown operator %s", operator)
+	}
+	start := p.pos
+	var value any
+	var err error
+	switch {
+	case op.missing && p.atEndOfCondition():
+	case p.consume("(") && (operator == ANY || operator == ALL || operator == NONE):
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		value = expr
+	default:
+		p.pos = start
+		if value, err = p.parseValue(); err != nil {
+			return nil, err
+		}
+		value = normalizeValue(value)
+	}
+	if err := checkOperand(operator, value); err != nil {
+		return nil, fmt.Errorf("%w at %d: %w", ErrSyntax, start+1, err)
+	}
+	return Match(NewCondition(field, operator, value)), nil
+}
+
+// atEndOfCondition tells whether the input continues with the end of a condition rather than a value
+func (p *exprParser) atEndOfCondition() bool {
+	p.skipSpaces()
+	if p.pos == len(p.input) {
+		return true
+	}
+	switch p.input[p.pos] {
+	case ')', '&', '|':
+		return true
+	}
+	return false
+}
+
+// parseValue returns a json value, numbers are returned as json numbers
+func (p *exprParser) parseValue() (any, error) {
+	p.skipSpaces()
+	if p.pos == len(p.input) {
+		return nil, p.errorf("expected a value")
+	}
+	switch r := p.input[p.pos]; {
+	case r == '"':
+		return p.parseString()
+	case r == '[':
+		p.pos++
+		values := []any{}
+		if p.consume("]") {
+			return values, nil
+		}
+		for {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if p.consume("]") {
+				return values, nil
+			}
+			if !p.consume(",") {
+				return nil, p.errorf("expected , or ]")
+			}
+		}
+	case r == '-' || r >= '0' && r <= '9':
+		start := p.pos
+		number := p.scan(isNumberRune)
+		if _, err := strconv.ParseFloat(number, 64); err != nil {
+			p.pos = start
+			return nil, p.errorf("invalid number %q", number)
+		}
+		return json.Number(number), nil
+	}
+	start := p.pos
+	switch word := p.scan(isOperatorRune); word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		return nil, p.errorf("expected a value, got %q", string(p.input[p.pos]))
+	default:
+		p.pos = start
+		return nil, p.errorf("expected a value, got %s, strings are quoted", word)
+	}
+}
+
+func (p *exprParser) parseString() (string, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.input); p.pos++ {
+		switch p.input[p.pos] {
+		case '\\':
+			p.pos++
+		case '"':
+			p.pos++
+			value, err := strconv.Unquote(string(p.input[start:p.pos]))
+			if err != nil {
+				p.pos = start
+				return "", p.errorf("invalid string")
+			}
+			return value, nil
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+func (p *exprParser) scan(accept func(rune) bool) string {
+	start := p.pos
+	for p.pos < len(p.input) && accept(p.input[p.pos]) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+func isFieldRune(r rune) bool {
+	return r == '_' || r == '.' || r == '[' || r == ']' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isOperatorRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isSymbolRune(r rune) bool {
+	return r == '=' || r == '!' || r == '<' || r == '>'
+}
+
+func isNumberRune(r rune) bool {
+	return r == '-' || r == '+' || r == '.' || r == 'e' || r == 'E' || r >= '0' && r <= '9'
+}
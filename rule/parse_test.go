@@ -0,0 +1,212 @@
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func exprJSON(t *testing.T, expr *Expr) string {
+	t.Helper()
+	data, err := json.Marshal(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{
+			expression: `age gte 18`,
+			want:       `{"field":"age","operator":"gte","value":18}`,
+		},
+		{
+			expression: `a eq 1 || b eq 2 && c eq 3`,
+			want:       `{"or":[{"field":"a","operator":"eq","value":1},{"and":[{"field":"b","operator":"eq","value":2},{"field":"c","operator":"eq","value":3}]}]}`,
+		},
+		{
+			expression: `(a eq 1 || b eq 2) && c eq 3`,
+			want:       `{"and":[{"or":[{"field":"a","operator":"eq","value":1},{"field":"b","operator":"eq","value":2}]},{"field":"c","operator":"eq","value":3}]}`,
+		},
+		{
+			expression: `a eq 1 && b eq 2 && c eq 3`,
+			want:       `{"and":[{"field":"a","operator":"eq","value":1},{"field":"b","operator":"eq","value":2},{"field":"c","operator":"eq","value":3}]}`,
+		},
+		{
+			expression: `!vip eq true`,
+			want:       `{"not":{"field":"vip","operator":"eq","value":true}}`,
+		},
+		{
+			expression: `!!vip eq true`,
+			want:       `{"not":{"not":{"field":"vip","operator":"eq","value":true}}}`,
+		},
+		{
+			expression: `!(a eq 1 || b eq 2) && c eq 3`,
+			want:       `{"and":[{"not":{"or":[{"field":"a","operator":"eq","value":1},{"field":"b","operator":"eq","value":2}]}},{"field":"c","operator":"eq","value":3}]}`,
+		},
+		{
+			expression: `age>=18&&age<65`,
+			want:       `{"and":[{"field":"age","operator":"gte","value":18},{"field":"age","operator":"lt","value":65}]}`,
+		},
+		{
+			expression: `a == 1 || a != 2 || a > 3 || a <= 4`,
+			want:       `{"or":[{"field":"a","operator":"eq","value":1},{"field":"a","operator":"neq","value":2},{"field":"a","operator":"gt","value":3},{"field":"a","operator":"lte","value":4}]}`,
+		},
+		{
+			expression: `price lt -1.5e2`,
+			want:       `{"field":"price","operator":"lt","value":-150}`,
+		},
+		{
+			expression: `name eq "say \"hi\" && \\ (ok) || été"`,
+			want:       `{"field":"name","operator":"eq","value":"say \"hi\" \u0026\u0026 \\ (ok) || été"}`,
+		},
+		{
+			expression: `country in ["US", "CA"] && note eq null`,
+			want:       `{"and":[{"field":"country","operator":"in","value":["US","CA"]},{"field":"note","operator":"eq","value":null}]}`,
+		},
+		{
+			expression: `score in [1, 2.5] || tags in []`,
+			want:       `{"or":[{"field":"score","operator":"in","value":[1,2.5]},{"field":"tags","operator":"in","value":[]}]}`,
+		},
+	}
+	for _, test := range tests {
+		expr, err := ParseExpr(test.expression)
+		if err != nil {
+			t.Errorf("ParseExpr(%q) failed: %v", test.expression, err)
+			continue
+		}
+		if got := exprJSON(t, expr); got != test.want {
+			t.Errorf("ParseExpr(%q) = %s, want %s", test.expression, got, test.want)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{expression: ``, want: "at 1: expected a condition"},
+		{expression: `&& a eq 1`, want: `at 1: expected a field, got "&"`},
+		{expression: `age`, want: "at 4: expected an operator after age"},
+		{expression: `age gte`, want: "at 8: expected a value"},
+		{expression: `age foo 1`, want: "at 5: unknown operator foo"},
+		{expression: `age === 1`, want: `at 5: unknown operator "==="`},
+		{expression: `(age gte 1`, want: "at 11: expected )"},
+		{expression: `age gte 1 )`, want: `at 11: unexpected ")"`},
+		{expression: `name eq "abc`, want: "at 9: unterminated string"},
+		{expression: `name eq "\q"`, want: "at 9: invalid string"},
+		{expression: `name eq abc`, want: "at 9: expected a value, got abc, strings are quoted"},
+		{expression: `age in [1, 2`, want: "at 13: expected , or ]"},
+		{expression: `age gte 1x`, want: `at 10: unexpected "x"`},
+		{expression: `age gte --1`, want: `at 9: invalid number "--1"`},
+	}
+	for _, test := range tests {
+		_, err := ParseExpr(test.expression)
+		if !errors.Is(err, ErrSyntax) {
+			t.Errorf("ParseExpr(%q) returned %v, want ErrSyntax", test.expression, err)
+			continue
+		}
+		if !strings.HasSuffix(err.Error(), test.want) {
+			t.Errorf("ParseExpr(%q) returned %q, want %q", test.expression, err, test.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse(`age gte 18 && (country in ["US", "CA"] || plan eq "gold")`, "adult")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != "adult" {
+		t.Errorf("Parse set id %q, want adult", r.ID)
+	}
+	tests := []struct {
+		data Data
+		want bool
+	}{
+		{data: map[string]any{"age": 20, "country": "US"}, want: true},
+		{data: map[string]any{"age": 20, "country": "FR", "plan": "gold"}, want: true},
+		{data: map[string]any{"age": 20, "country": "FR", "plan": "free"}, want: false},
+		{data: map[string]any{"age": 16, "country": "US"}, want: false},
+	}
+	for _, test := range tests {
+		if got := r.Apply(test.data) != nil; got != test.want {
+			t.Errorf("Apply(%v) matched %v, want %v", test.data, got, test.want)
+		}
+	}
+}
+
+func TestRuleJSONRoundTrip(t *testing.T) {
+	parsed, err := Parse(`age gte 18 && !(country in ["US", "CA"]) || score lt 2.5`, "parsed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := New("nodes")
+	nodes.And(NewCondition("age", GTE, 18), NewCondition("age", LT, 65))
+	nodes.Or(NewCondition("vip", EQ, true))
+
+	groups := New("groups")
+	adult := groups.And(NewCondition("age", GTE, 18))
+	local := groups.Or(NewCondition("country", EQ, "US"), NewCondition("country", EQ, "CA"))
+	vip := groups.And(NewCondition("vip", EQ, true))
+	blocked := groups.Not(NewCondition("status", EQ, "blocked"))
+	groups.Group(adult, AND, local)
+	groups.Group(vip, AND, blocked)
+
+	joins := New("joins")
+	adult = joins.And(NewCondition("age", GTE, 18))
+	local = joins.Or(NewCondition("country", EQ, "US"), NewCondition("country", EQ, "CA"))
+	vip = joins.And(NewCondition("vip", EQ, true))
+	blocked = joins.Not(NewCondition("status", EQ, "blocked"))
+	joins.Join(joins.Group(adult, AND, local), OR, joins.Group(vip, AND, blocked))
+
+	data := []Data{
+		map[string]any{"age": 20, "country": "US", "score": 3},
+		map[string]any{"age": 20, "country": "FR", "score": 3},
+		map[string]any{"age": 16, "country": "CA", "score": 1.5},
+		map[string]any{"age": 70, "vip": true, "status": "active"},
+		map[string]any{"age": 30, "vip": true, "status": "blocked", "country": "FR"},
+		map[string]any{},
+	}
+	for _, r := range []*Rule{parsed, nodes, groups, joins} {
+		encoded, err := json.Marshal(r)
+		if err != nil {
+			t.Fatalf("%s: %v", r.ID, err)
+		}
+		loaded := &Rule{}
+		if err := json.Unmarshal(encoded, loaded); err != nil {
+			t.Fatalf("%s: unmarshal %s: %v", r.ID, encoded, err)
+		}
+		if loaded.ID != r.ID {
+			t.Errorf("%s: loaded id %q", r.ID, loaded.ID)
+		}
+		if got, want := exprJSON(t, loaded.Expression()), exprJSON(t, r.Expression()); got != want {
+			t.Errorf("%s: loaded expression %s, want %s", r.ID, got, want)
+		}
+		for _, d := range data {
+			if got, want := loaded.Apply(d) != nil, r.Apply(d) != nil; got != want {
+				t.Errorf("%s: loaded rule matched %v on %v, want %v", r.ID, got, d, want)
+			}
+		}
+	}
+
+	loaded := &Rule{}
+	if err := json.Unmarshal([]byte(`{"id":"source","expression":"age gte 18 && vip eq true"}`), loaded); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := exprJSON(t, loaded.Expression()), `{"and":[{"field":"age","operator":"gte","value":18},{"field":"vip","operator":"eq","value":true}]}`; got != want {
+		t.Errorf("rule with a string expression loaded %s, want %s", got, want)
+	}
+	for _, input := range []string{`{"id":"none"}`, `{"id":"bad","expression":"age gte"}`} {
+		if err := json.Unmarshal([]byte(input), &Rule{}); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", input)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package rule
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sujit-baniya/pkg/maps"
+)
+
+// pathSegment is a map key or struct field name, or a list index when index is set
+type pathSegment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// maxCachedPaths bounds the paths kept parsed for rules evaluated without Compile
+const maxCachedPaths = 1024
+
+var (
+	paths        = maps.NewCache[string, []pathSegment](maps.CacheConfig[string, []pathSegment]{Capacity: maxCachedPaths, Policy: maps.LRU})
+	structFields sync.Map // reflect.Type to map[string][]int
+)
+
+// parsePath returns the segments of a field path, the most recently used valid paths are kept parsed
+func parsePath(path string) ([]pathSegment, bool) {
+	if segments, ok := paths.Get(path); ok {
+		return segments, true
+	}
+	segments, ok := splitPath(path)
+	if ok {
+		paths.Set(path, segments)
+	}
+	return segments, ok
+}
+
+// splitPath splits a field path such as order.items[0].price or $.order["line items"][0] into segments
+func splitPath(path string) ([]pathSegment, bool) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	var segments []pathSegment
+	for rest != "" {
+		switch rest[0] {
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, false
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, pathSegment{key: inner[1 : len(inner)-1]})
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				segments = append(segments, pathSegment{index: index, isIdx: true})
+			} else {
+				return nil, false
+			}
+			rest = rest[end+1:]
+		case '.':
+			rest = rest[1:]
+			if rest == "" || rest[0] == '.' {
+				return nil, false
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if index, err := strconv.Atoi(key); err == nil && index >= 0 {
+				segments = append(segments, pathSegment{key: key, index: index, isIdx: true})
+			} else {
+				segments = append(segments, pathSegment{key: key})
+			}
+			rest = rest[end:]
+		}
+	}
+	return segments, len(segments) > 0
+}
+
+// lookup returns the value at a field path of data, which may be nested maps, structs, slices and pointers to them
+// A map key equal to the whole path is used first so flat keys containing dots still match, $ alone is data itself
+func lookup(data any, field string) (any, bool) {
+	if field == "$" {
+		return data, true
+	}
+	if m, ok := data.(map[string]any); ok {
+		if value, ok := m[field]; ok {
+			return value, true
+		}
+	}
+	segments, ok := parsePath(field)
+	if !ok {
+		return nil, false
+	}
+	current := data
+	for _, segment := range segments {
+		if current, ok = step(current, segment); !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// step returns the value of one path segment, without reflection for the types decoded from json
+func step(current any, segment pathSegment) (any, bool) {
+	switch current := current.(type) {
+	case map[string]any:
+		value, ok := current[segment.key]
+		if !ok && segment.isIdx && segment.key == "" {
+			value, ok = current[strconv.Itoa(segment.index)]
+		}
+		return value, ok
+	case []any:
+		if !segment.isIdx || segment.index >= len(current) {
+			return nil, false
+		}
+		return current[segment.index], true
+	}
+	v := reflect.ValueOf(current)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		key := segment.key
+		if segment.isIdx && key == "" {
+			key = strconv.Itoa(segment.index)
+		}
+		value := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return nil, false
+		}
+		return value.Interface(), true
+	case reflect.Slice, reflect.Array:
+		if !segment.isIdx || segment.index >= v.Len() {
+			return nil, false
+		}
+		return v.Index(segment.index).Interface(), true
+	case reflect.Struct:
+		index, ok := fieldIndex(v.Type(), segment.key)
+		if !ok {
+			return nil, false
+		}
+		field, err := v.FieldByIndexErr(index)
+		if err != nil || !field.CanInterface() {
+			return nil, false
+		}
+		return field.Interface(), true
+	}
+	return nil, false
+}
+
+// fieldIndex returns the index of the exported struct field named by a rule or json tag, by its name or by its name in any case
+func fieldIndex(t reflect.Type, name string) ([]int, bool) {
+	cached, ok := structFields.Load(t)
+	if !ok {
+		fields := make(map[string][]int)
+		folded := make(map[string][]int)
+		for _, field := range reflect.VisibleFields(t) {
+			if !field.IsExported() || field.Anonymous && field.Type.Kind() == reflect.Struct {
+				continue
+			}
+			names := []string{field.Name}
+			for _, tag := range []string{"json", "rule"} {
+				if tagName, _, _ := strings.Cut(field.Tag.Get(tag), ","); tagName != "" && tagName != "-" {
+					names = append(names, tagName)
+				}
+			}
+			for _, n := range names {
+				fields[n] = field.Index
+				if _, ok := folded[strings.ToLower(n)]; !ok {
+					folded[strings.ToLower(n)] = field.Index
+				}
+			}
+		}
+		for n, index := range folded {
+			if _, ok := fields[n]; !ok {
+				fields[n] = index
+			}
+		}
+		cached, _ = structFields.LoadOrStore(t, fields)
+	}
+	fields := cached.(map[string][]int)
+	if index, ok := fields[name]; ok {
+		return index, true
+	}
+	index, ok := fields[strings.ToLower(name)]
+	return index, ok
+}
@@ -0,0 +1,54 @@
+package rule
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	type line struct {
+		SKU   string `json:"sku"`
+		Price float64
+	}
+	data := map[string]any{
+		"a.b":   1,
+		"order": map[string]any{"line items": []any{map[string]any{"sku": "A-1"}}, "lines": []line{{SKU: "B-2", Price: 9.5}}},
+	}
+	tests := []struct {
+		path  string
+		value any
+		found bool
+	}{
+		{"a.b", 1, true},
+		{`order["line items"][0].sku`, "A-1", true},
+		{`$.order["line items"][0].sku`, "A-1", true},
+		{"order.lines[0].sku", "B-2", true},
+		{"order.lines.0.Price", 9.5, true},
+		{"order.lines[1].sku", nil, false},
+		{"order.missing", nil, false},
+		{"order[", nil, false},
+		{"order..lines", nil, false},
+	}
+	for _, test := range tests {
+		value, found := lookup(data, test.path)
+		if value != test.value || found != test.found {
+			t.Errorf("lookup(%s) = %v, %v, want %v, %v", test.path, value, found, test.value, test.found)
+		}
+	}
+}
+
+func TestParsePathCache(t *testing.T) {
+	paths.Clear()
+	if _, ok := parsePath("order["); ok {
+		t.Fatal("parsed the invalid path order[")
+	}
+	if _, cached := paths.Get("order["); cached {
+		t.Error("cached the invalid path order[")
+	}
+	for i := 0; i < 2*maxCachedPaths; i++ {
+		parsePath(fmt.Sprintf("order.items[%d]", i))
+	}
+	if n := paths.Len(); n > maxCachedPaths {
+		t.Errorf("cached %d paths, want at most %d", n, maxCachedPaths)
+	}
+}
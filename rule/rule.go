@@ -2,11 +2,9 @@ package rule
 
 import (
 	"sort"
-	"strings"
 	"sync"
 
 	"github.com/sujit-baniya/frame/pkg/common/xid"
-	"github.com/sujit-baniya/pkg/timeutil"
 )
 
 // Creating rule to work with data of type map[string]any
@@ -44,370 +42,26 @@ type Condition struct {
 	Value    any               `json:"value"`
 }
 
+// Validate tells whether the value at the field path of data satisfies the condition
+// Data may be a map, a struct or a pointer to them and Field a path such as order.items[0].price,
+// values are compared by compareValues and equalValues so numbers, decimals, times and strings of any type compare alike
+// Operators added with RegisterOperator are applied like the built-in ones
 func (condition *Condition) Validate(data Data) bool {
-	switch data := data.(type) {
-	case map[string]any:
-		val, ok := data[condition.Field]
-		if !ok {
-			return false
-		}
-		switch condition.Operator {
-		case EQ:
-			switch val := val.(type) {
-			case string:
-				switch gtVal := condition.Value.(type) {
-				case string:
-					return strings.EqualFold(val, gtVal)
-				}
-				return false
-			case int:
-				switch gtVal := condition.Value.(type) {
-				case int:
-					return val == gtVal
-				case uint:
-					return val == int(gtVal)
-				case float64:
-					return float64(val) == gtVal
-				}
-				return false
-			case float64:
-				switch gtVal := condition.Value.(type) {
-				case int:
-					return val == float64(gtVal)
-				case uint:
-					return val == float64(gtVal)
-				case float64:
-					return val == gtVal
-				}
-				return false
-			}
-		case NEQ:
-			switch val := val.(type) {
-			case string:
-				switch gtVal := condition.Value.(type) {
-				case string:
-					return !strings.EqualFold(val, gtVal)
-				}
-				return false
-			case int:
-				switch gtVal := condition.Value.(type) {
-				case int:
-					return val != gtVal
-				case float64:
-					return float64(val) != gtVal
-				}
-				return false
-			case float64:
-				switch gtVal := condition.Value.(type) {
-				case int:
-					return val != float64(gtVal)
-				case float64:
-					return val != gtVal
-				}
-				return false
-			}
-
-			return false
-		case GT:
-			switch val := data[condition.Field].(type) {
-			case string:
-				from, err := timeutil.ParseTime(val)
-				if err != nil {
-					return false
-				}
-				switch gtVal := condition.Value.(type) {
-				case string:
-					smaller, err := timeutil.ParseTime(gtVal)
-					if err != nil {
-						return false
-					}
-					return from.After(smaller)
-				}
-				return false
-			case int:
-				switch gtVal := condition.Value.(type) {
-				case int:
-					return val > gtVal
-				case float64:
-					return float64(val) > gtVal
-				}
-				return false
-			case float64:
-				switch gtVal := condition.Value.(type) {
-				case int:
-					return val > float64(gtVal)
-				case float64:
-					return val > gtVal
-				}
-				return false
-			}
-
-			return false
-		case LT:
-			switch val := data[condition.Field].(type) {
-			case string:
-				from, err := timeutil.ParseTime(val)
-				if err != nil {
-					return false
-				}
-				switch gtVal := condition.Value.(type) {
-				case string:
-					smaller, err := timeutil.ParseTime(gtVal)
-					if err != nil {
-						return false
-					}
-					return from.Before(smaller)
-				}
-				return false
-			case int:
-				switch ltVal := condition.Value.(type) {
-				case int:
-					return val < ltVal
-				case uint:
-					return val < int(ltVal)
-				case float64:
-					return float64(val) < ltVal
-				}
-				return false
-			case float64:
-				switch ltVal := condition.Value.(type) {
-				case int:
-					return val < float64(ltVal)
-				case float64:
-					return val < ltVal
-				}
-				return false
-			}
-
-			return false
-		case GTE:
-			switch val := data[condition.Field].(type) {
-			case string:
-				from, err := timeutil.ParseTime(val)
-				if err != nil {
-					return false
-				}
-				switch gtVal := condition.Value.(type) {
-				case string:
-					smaller, err := timeutil.ParseTime(gtVal)
-					if err != nil {
-						return false
-					}
-					return from.After(smaller) || from.Equal(smaller)
-				}
-				return false
-			case int:
-				switch gtVal := condition.Value.(type) {
-				case int:
-					return val >= gtVal
-				case float64:
-					return float64(val) >= gtVal
-				}
-				return false
-			case float64:
-				switch gtVal := condition.Value.(type) {
-				case int:
-					return val >= float64(gtVal)
-				case float64:
-					return val >= gtVal
-				}
-				return false
-			}
-			return false
-		case LTE:
-			switch val := data[condition.Field].(type) {
-			case string:
-				from, err := timeutil.ParseTime(val)
-				if err != nil {
-					return false
-				}
-				switch gtVal := condition.Value.(type) {
-				case string:
-					smaller, err := timeutil.ParseTime(gtVal)
-					if err != nil {
-						return false
-					}
-					return from.Before(smaller) || from.Equal(smaller)
-				}
-				return false
-			case int:
-				switch ltVal := condition.Value.(type) {
-				case int:
-					return val <= ltVal
-				case float64:
-					return float64(val) <= ltVal
-				}
-				return false
-			case float64:
-				switch ltVal := condition.Value.(type) {
-				case int:
-					return val <= float64(ltVal)
-				case float64:
-					return val <= ltVal
-				}
-				return false
-			}
-
-			return false
-		case BETWEEN:
-			switch val := data[condition.Field].(type) {
-			case string:
-				switch gtVal := condition.Value.(type) {
-				case []string:
-					from, err := timeutil.ParseTime(val)
-					if err != nil {
-						return false
-					}
-					start, err := timeutil.ParseTime(gtVal[0])
-					if err != nil {
-						return false
-					}
-					last, err := timeutil.ParseTime(gtVal[1])
-					if err != nil {
-						return false
-					}
-					return (from.After(start) || from.Equal(start)) && (from.Before(last) || from.Equal(last))
-				}
-				return false
-			case int:
-				switch ltVal := condition.Value.(type) {
-				case []int:
-					return val >= ltVal[0] && val <= ltVal[1]
-				case []float64:
-					return float64(val) >= ltVal[0] && float64(val) <= ltVal[1]
-				}
-				return false
-			case float64:
-				switch ltVal := condition.Value.(type) {
-				case []int:
-					return val >= float64(ltVal[0]) && val <= float64(ltVal[1])
-				case []float64:
-					return val >= ltVal[0] && val <= ltVal[1]
-				}
-				return false
-			}
-
-			return false
-		case IN:
-			switch val := data[condition.Field].(type) {
-			case string:
-				switch gtVal := condition.Value.(type) {
-				case []string:
-					for _, v := range gtVal {
-						if strings.EqualFold(val, v) {
-							return true
-						}
-					}
-					return false
-				}
-				return false
-			case int:
-				switch ltVal := condition.Value.(type) {
-				case []int:
-					for _, v := range ltVal {
-						if val == v {
-							return true
-						}
-					}
-					return false
-				}
-				return false
-			case float64:
-				switch ltVal := condition.Value.(type) {
-				case []float64:
-					for _, v := range ltVal {
-						if val == v {
-							return true
-						}
-					}
-					return false
-				}
-				return false
-			}
-
-			return false
-		case NotIn:
-			switch val := data[condition.Field].(type) {
-			case string:
-				switch gtVal := condition.Value.(type) {
-				case []string:
-					for _, v := range gtVal {
-						if strings.EqualFold(val, v) {
-							return false
-						}
-					}
-					return true
-				}
-				return false
-			case int:
-				switch ltVal := condition.Value.(type) {
-				case []int:
-					for _, v := range ltVal {
-						if val == v {
-							return false
-						}
-					}
-					return true
-				}
-				return false
-			case float64:
-				switch ltVal := condition.Value.(type) {
-				case []float64:
-					for _, v := range ltVal {
-						if val == v {
-							return false
-						}
-					}
-					return true
-				}
-				return false
-			}
-
-			return false
-		case CONTAINS:
-			switch val := data[condition.Field].(type) {
-			case string:
-				switch gtVal := condition.Value.(type) {
-				case string:
-					return strings.Contains(val, gtVal)
-				}
-				return false
-			}
+	_, _, ok := condition.check(data)
+	return ok
+}
 
-			return false
-		case NotContains:
-			switch val := data[condition.Field].(type) {
-			case string:
-				switch gtVal := condition.Value.(type) {
-				case string:
-					return !strings.Contains(val, gtVal)
-				}
-				return false
-			}
-			return false
-		case StartsWith:
-			switch val := data[condition.Field].(type) {
-			case string:
-				switch gtVal := condition.Value.(type) {
-				case string:
-					return strings.HasPrefix(val, gtVal)
-				}
-				return false
-			}
-			return false
-		case EndsWith:
-			switch val := data[condition.Field].(type) {
-			case string:
-				switch gtVal := condition.Value.(type) {
-				case string:
-					return strings.HasSuffix(val, gtVal)
-				}
-				return false
-			}
-			return false
-		}
+// check returns the value of the field, whether data has it, and whether the condition holds
+func (condition *Condition) check(data Data) (value any, found, ok bool) {
+	op, known := lookupOperator(condition.Operator)
+	if !known {
+		return nil, false, false
 	}
-	return false
+	value, found = lookup(data, condition.Field)
+	if !found && !op.missing {
+		return nil, false, false
+	}
+	return value, found, op.fn(value, condition.Value)
 }
 
 func NewCondition(field string, operator ConditionOperator, value any) *Condition {
@@ -423,22 +77,25 @@ type CallbackFn func(data Data) any
 type Node struct {
 	Condition []*Condition
 	Operator  JoinOperator
-	Result    bool
-	ID        string
+	// Deprecated: Result is no longer set, rules are applied without being modified, use Rule.Explain
+	Result bool
+	ID     string
 }
 type Join struct {
 	Left     *Group
 	Operator JoinOperator
 	Right    *Group
-	Result   bool
-	ID       string
+	// Deprecated: Result is no longer set, rules are applied without being modified, use Rule.Explain
+	Result bool
+	ID     string
 }
 type Group struct {
 	Left     *Node
 	Operator JoinOperator
 	Right    *Node
-	Result   bool
-	ID       string
+	// Deprecated: Result is no longer set, rules are applied without being modified, use Rule.Explain
+	Result bool
+	ID     string
 }
 type Rule struct {
 	ID      string
@@ -446,6 +103,7 @@ type Rule struct {
 	nodes   []*Node
 	groups  []*Group
 	joins   []*Join
+	expr    *Expr
 }
 
 func New(id ...string) *Rule {
@@ -493,7 +151,6 @@ func (r *Rule) Join(left *Group, operator JoinOperator, right *Group) *Join {
 	return join
 }
 func (r *Rule) Apply(d Data, callback ...CallbackFn) any {
-	var result, n, g bool
 	var defaultCallbackFn = func(data Data) any {
 		if data == nil {
 			return nil
@@ -504,79 +161,92 @@ func (r *Rule) Apply(d Data, callback ...CallbackFn) any {
 	if len(callback) > 0 {
 		defaultCallbackFn = callback[0]
 	}
-	for i, node := range r.nodes {
-		if len(node.Condition) == 0 {
-			continue
+	var result bool
+	if r.expr != nil {
+		result = r.expr.Evaluate(d)
+	} else {
+		result = r.evaluate(d)
+	}
+	if !result {
+		return defaultCallbackFn(nil)
+	}
+	if r.Handler != nil {
+		return r.Handler(d)
+	}
+	return defaultCallbackFn(d)
+}
+
+// evaluate applies the nodes, groups and joins of the rule as Explain does
+// Nothing of the rule is modified so a rule may be applied concurrently
+func (r *Rule) evaluate(d Data) bool {
+	if len(r.groups) == 0 {
+		var result bool
+		for i, node := range r.nodes {
+			if len(node.Condition) > 0 {
+				result = chainResult(result, i, node.Operator, evaluateNode(node, d))
+			}
 		}
-		if i == 0 && node.Operator == AND {
-			n = true
-		} else if i == 0 && node.Operator == OR {
-			n = false
+		return result
+	}
+	nodes := make(map[*Node]bool, len(r.nodes))
+	evaluateGroup := func(group *Group) bool {
+		if group == nil {
+			return false
 		}
-		var nodeResult bool
-		switch node.Operator {
-		case AND:
-			nodeResult = true
-			for _, condition := range node.Condition {
-				nodeResult = nodeResult && condition.Validate(d)
-			}
-			n = n && nodeResult
-			break
-		case OR:
-			nodeResult = false
-			for _, condition := range node.Condition {
-				nodeResult = nodeResult || condition.Validate(d)
+		results := [2]bool{}
+		for i, node := range [2]*Node{group.Left, group.Right} {
+			result, ok := nodes[node]
+			if !ok {
+				result = evaluateNode(node, d)
+				nodes[node] = result
 			}
-			n = n || nodeResult
-			break
+			results[i] = result
 		}
-		node.Result = nodeResult
+		return joinResults(group.Operator, results[0], results[1])
 	}
-	if len(r.groups) == 0 {
-		result = n
+	var result bool
+	if len(r.joins) > 0 {
+		for _, join := range r.joins {
+			result = joinResults(join.Operator, evaluateGroup(join.Left), evaluateGroup(join.Right))
+		}
+		return result
 	}
 	for i, group := range r.groups {
-		if i == 0 && group.Operator == AND {
-			g = true
-		} else if i == 0 && group.Operator == OR {
-			g = false
+		if group.Operator == AND || group.Operator == OR {
+			result = chainResult(result, i, group.Operator, evaluateGroup(group))
 		}
-		var groupResult bool
-		switch group.Operator {
-		case AND:
-			groupResult = group.Left.Result && group.Right.Result
-			g = g && groupResult
-			break
-		case OR:
-			groupResult = group.Left.Result || group.Right.Result
-			g = g || groupResult
-			break
-		}
-		group.Result = groupResult
 	}
-	if len(r.groups) > 0 && len(r.joins) == 0 {
-		result = g
+	return result
+}
+
+// evaluateNode applies the conditions of a node, a node without conditions is false
+func evaluateNode(node *Node, d Data) bool {
+	if node == nil || len(node.Condition) == 0 {
+		return false
 	}
-	for _, join := range r.joins {
-		var joinResult bool
-		switch join.Operator {
+	result := node.Operator == AND || node.Operator == NOT
+	for _, condition := range node.Condition {
+		switch node.Operator {
 		case AND:
-			joinResult = join.Left.Result && join.Right.Result
-			break
+			result = result && condition.Validate(d)
+		case NOT:
+			result = result && !condition.Validate(d)
 		case OR:
-			joinResult = join.Left.Result || join.Right.Result
-			break
+			result = result || condition.Validate(d)
 		}
-		join.Result = joinResult
-		result = joinResult
 	}
-	if !result {
-		return defaultCallbackFn(nil)
-	}
-	if r.Handler != nil {
-		return r.Handler(d)
+	return result
+}
+
+// joinResults combines the results of the two sides of a group or join, other operators are false
+func joinResults(operator JoinOperator, left, right bool) bool {
+	switch operator {
+	case AND:
+		return left && right
+	case OR:
+		return left || right
 	}
-	return defaultCallbackFn(d)
+	return false
 }
 
 type Priority int
@@ -591,9 +261,18 @@ type PriorityRule struct {
 	Priority int
 }
 
+// Mode tells whether a group returns the response of the first rule that fires or of all of them
+type Mode int
+
+const (
+	FirstMatch Mode = iota
+	AllMatch
+)
+
 type Config struct {
 	Rules    []*PriorityRule
 	Priority Priority
+	Mode     Mode
 }
 
 type GroupRule struct {
@@ -632,13 +311,37 @@ func (r *GroupRule) ApplyLowestPriority(data Data, fn ...CallbackFn) any {
 	return r.apply(r.sortByPriority(), data, fn...)
 }
 
+// Apply returns the response of the first rule that fires, or with AllMatch the []any of the responses of every rule that fires
+// It returns nil when no rule fires
 func (r *GroupRule) Apply(data Data, fn ...CallbackFn) any {
+	if r.config.Mode == AllMatch {
+		// a nil []any would not be a nil any
+		if responses := r.ApplyAll(data, fn...); len(responses) > 0 {
+			return responses
+		}
+		return nil
+	}
 	if r.config.Priority == HighestPriority {
 		return r.ApplyHighestPriority(data, fn...)
 	}
 	return r.ApplyLowestPriority(data, fn...)
 }
 
+// ApplyAll returns the non nil responses of all rules in the priority order of the group
+func (r *GroupRule) ApplyAll(data Data, fn ...CallbackFn) []any {
+	direction := "ASC"
+	if r.config.Priority == HighestPriority {
+		direction = "DESC"
+	}
+	var responses []any
+	for _, rule := range r.sortByPriority(direction) {
+		if response := rule.Apply(data, fn...); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	return responses
+}
+
 func (r *GroupRule) apply(sortedRules []*Rule, data Data, fn ...CallbackFn) any {
 	for _, rule := range sortedRules {
 		response := rule.Apply(data, fn...)
@@ -653,18 +356,22 @@ func (r *GroupRule) SortByPriority(direction ...string) []*Rule {
 	return r.sortByPriority(direction...)
 }
 
+// sortByPriority returns the rules sorted by priority, the rules of the group are left in the order they were added
 func (r *GroupRule) sortByPriority(direction ...string) []*Rule {
 	dir := "ASC"
 	if len(direction) > 0 {
 		dir = direction[0]
 	}
+	r.mu.RLock()
+	rules := append(byPriority(nil), r.Rules...)
+	r.mu.RUnlock()
 	if dir == "DESC" {
-		sort.Sort(sort.Reverse(byPriority(r.Rules)))
+		sort.Stable(sort.Reverse(rules))
 	} else {
-		sort.Sort(byPriority(r.Rules))
+		sort.Stable(rules)
 	}
-	res := make([]*Rule, 0, len(r.Rules))
-	for _, q := range r.Rules {
+	res := make([]*Rule, 0, len(rules))
+	for _, q := range rules {
 		res = append(res, q.Rule)
 	}
 	return res
@@ -0,0 +1,97 @@
+package rule
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestGroupRuleAllMatch(t *testing.T) {
+	group := NewRuleGroup(Config{Priority: HighestPriority, Mode: AllMatch})
+	for i, expression := range []string{`age >= 18`, `age >= 21`, `country eq "US"`} {
+		rule, err := Parse(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := expression
+		rule.Handler = func(Data) any { return response }
+		group.AddRule(rule, i)
+	}
+
+	got := group.Apply(map[string]any{"age": 30, "country": "CA"})
+	if want := []any{`age >= 21`, `age >= 18`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply = %v, want %v", got, want)
+	}
+	if got := group.Apply(map[string]any{"age": 10}); got != nil {
+		t.Errorf("Apply without a match = %#v, want nil", got)
+	}
+}
+
+func TestApplyConcurrently(t *testing.T) {
+	rule := New("adult-or-vip")
+	adult := rule.And(NewCondition("age", GTE, 18))
+	local := rule.And(NewCondition("country", EQ, "CA"))
+	vip := rule.Or(NewCondition("vip", EQ, true))
+	rule.Join(rule.Group(adult, AND, local), OR, rule.Group(vip, AND, vip))
+
+	tests := []struct {
+		data Data
+		want bool
+	}{
+		{map[string]any{"age": 30, "country": "CA"}, true},
+		{map[string]any{"age": 30, "country": "US"}, false},
+		{map[string]any{"age": 10, "vip": true}, true},
+		{map[string]any{"age": 10}, false},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for _, test := range tests {
+					if got := rule.Apply(test.data) != nil; got != test.want {
+						t.Errorf("Apply(%v) fired %v, want %v", test.data, got, test.want)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, test := range tests {
+		if got := rule.Explain(test.data).Result; got != test.want {
+			t.Errorf("Explain(%v) = %v, want %v", test.data, got, test.want)
+		}
+	}
+}
+
+func TestSortByPriorityKeepsRules(t *testing.T) {
+	group := NewRuleGroup()
+	for _, priority := range []int{2, 3, 1} {
+		group.AddRule(New(strconv.Itoa(priority)), priority)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			group.SortByPriority("DESC")
+		}()
+		go func() {
+			defer wg.Done()
+			group.SortByPriority()
+		}()
+	}
+	wg.Wait()
+	sorted := group.SortByPriority("DESC")
+	if sorted[0].ID != "3" || sorted[1].ID != "2" || sorted[2].ID != "1" {
+		t.Errorf("sorted %s %s %s, want 3 2 1", sorted[0].ID, sorted[1].ID, sorted[2].ID)
+	}
+	for i, priority := range []int{2, 3, 1} {
+		if group.Rules[i].Priority != priority {
+			t.Fatalf("rule %d has priority %d after sorting, want the order they were added", i, group.Rules[i].Priority)
+		}
+	}
+}
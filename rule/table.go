@@ -0,0 +1,623 @@
+package rule
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// HitPolicy tells which rows of a decision table produce the decision, as in DMN
+type HitPolicy string
+
+const (
+	// HitFirst returns the first row that matches
+	HitFirst HitPolicy = "first"
+	// HitUnique returns the only row that matches, more than one is an error
+	HitUnique HitPolicy = "unique"
+	// HitCollect returns every row that matches in table order
+	HitCollect HitPolicy = "collect"
+	// HitPriority returns the matching row of highest priority, the first of them on a tie
+	HitPriority HitPolicy = "priority"
+)
+
+var (
+	ErrInvalidTable = errors.New("rule: invalid decision table")
+	ErrNotUnique    = errors.New("rule: more than one row matches")
+)
+
+// Column is an input of a decision table, Operator applies to cells holding a bare value and defaults to eq
+type Column struct {
+	Field    string            `json:"field"`
+	Operator ConditionOperator `json:"operator,omitempty"`
+}
+
+// Row is a line of a decision table, When holds one cell per input and Then the outputs it produces
+//
+// A cell is nil, "" or "-" to match anything, a test such as ">= 18", "in [\"US\",\"CA\"]" or "exists",
+// or a value applied with the operator of its column, strings that are neither are taken as they are
+type Row struct {
+	ID       string         `json:"id,omitempty"`
+	When     []any          `json:"when"`
+	Then     map[string]any `json:"then"`
+	Priority int            `json:"priority,omitempty"`
+}
+
+// Hit is a row that fired, Row is its index in the table
+type Hit struct {
+	Row    int            `json:"row"`
+	ID     string         `json:"id"`
+	Output map[string]any `json:"output"`
+}
+
+// DecisionTable maps inputs to outputs through rows of conditions, each row compiles to a Rule
+//
+//	country,                 weight lte, out:rate
+//	US,                      5,          4.5
+//	US,                      -,          9
+//	"in [""CA"", ""MX""]",   -,          12
+type DecisionTable struct {
+	Name      string
+	HitPolicy HitPolicy
+	Inputs    []Column
+	Outputs   []string
+	rows      []*tableRow
+}
+
+type tableRow struct {
+	Row
+	expr *Expr
+	sets []cellSet
+}
+
+func NewDecisionTable(name string, policy HitPolicy, inputs ...Column) *DecisionTable {
+	return &DecisionTable{Name: name, HitPolicy: policy, Inputs: inputs}
+}
+
+// AddRow compiles row and appends it to the table
+func (t *DecisionTable) AddRow(row Row) error {
+	if len(row.When) != len(t.Inputs) {
+		return fmt.Errorf("%w: row %d has %d cells for %d inputs", ErrInvalidTable, len(t.rows)+1, len(row.When), len(t.Inputs))
+	}
+	if len(t.Outputs) > 0 {
+		for name := range row.Then {
+			if !t.isOutput(name) {
+				return fmt.Errorf("%w: row %d sets unknown output %q", ErrInvalidTable, len(t.rows)+1, name)
+			}
+		}
+	}
+	compiled := &tableRow{Row: row, sets: make([]cellSet, len(row.When))}
+	var exprs []*Expr
+	for i, cell := range row.When {
+		expr, err := cellExpr(t.Inputs[i], cell)
+		if err != nil {
+			return fmt.Errorf("%w: row %d, %s: %w", ErrInvalidTable, len(t.rows)+1, t.Inputs[i].Field, err)
+		}
+		compiled.sets[i] = newCellSet(expr)
+		if expr != nil {
+			exprs = append(exprs, expr)
+		}
+	}
+	compiled.expr = All(exprs...)
+	t.rows = append(t.rows, compiled)
+	return nil
+}
+
+func (t *DecisionTable) isOutput(name string) bool {
+	for _, output := range t.Outputs {
+		if output == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Rows returns the rows of the table
+func (t *DecisionTable) Rows() []Row {
+	rows := make([]Row, len(t.rows))
+	for i, row := range t.rows {
+		rows[i] = row.Row
+	}
+	return rows
+}
+
+// rowID returns the id of row i, its own or the table name and its number
+func (t *DecisionTable) rowID(i int) string {
+	if id := t.rows[i].ID; id != "" {
+		return id
+	}
+	return t.Name + "#" + strconv.Itoa(i+1)
+}
+
+// Rules returns a rule per row whose Handler returns the outputs of the row
+func (t *DecisionTable) Rules() []*Rule {
+	rules := make([]*Rule, len(t.rows))
+	for i, row := range t.rows {
+		output := row.Then
+		rules[i] = New(t.rowID(i)).SetExpression(row.expr)
+		rules[i].Handler = func(Data) any {
+			return copyOutput(output)
+		}
+	}
+	return rules
+}
+
+// Evaluate returns the rows that fire for data under the hit policy of the table, none when no row matches
+// With HitUnique and more than one match the matching rows are returned with ErrNotUnique
+func (t *DecisionTable) Evaluate(data Data) ([]Hit, error) {
+	switch t.HitPolicy {
+	case "", HitFirst, HitUnique, HitCollect, HitPriority:
+	default:
+		return nil, fmt.Errorf("%w: unknown hit policy %q", ErrInvalidTable, t.HitPolicy)
+	}
+	var hits []Hit
+	best := -1
+	for i, row := range t.rows {
+		if !row.expr.Evaluate(data) {
+			continue
+		}
+		switch t.HitPolicy {
+		case HitFirst, "":
+			return []Hit{t.hit(i)}, nil
+		case HitPriority:
+			if best < 0 || row.Priority > t.rows[best].Priority {
+				best = i
+			}
+		default:
+			hits = append(hits, t.hit(i))
+		}
+	}
+	if best >= 0 {
+		return []Hit{t.hit(best)}, nil
+	}
+	if t.HitPolicy == HitUnique && len(hits) > 1 {
+		ids := make([]string, len(hits))
+		for i, hit := range hits {
+			ids[i] = hit.ID
+		}
+		return hits, fmt.Errorf("%w: %s", ErrNotUnique, strings.Join(ids, ", "))
+	}
+	return hits, nil
+}
+
+func (t *DecisionTable) hit(i int) Hit {
+	return Hit{Row: i, ID: t.rowID(i), Output: copyOutput(t.rows[i].Then)}
+}
+
+func copyOutput(output map[string]any) map[string]any {
+	copied := make(map[string]any, len(output))
+	for k, v := range output {
+		copied[k] = v
+	}
+	return copied
+}
+
+// cellExpr returns the condition of a cell on column, nil when the cell matches anything
+func cellExpr(column Column, cell any) (*Expr, error) {
+	operator := column.Operator
+	if operator == "" {
+		operator = EQ
+	}
+	text, ok := cell.(string)
+	if !ok {
+		if cell == nil {
+			return nil, nil
+		}
+		value := normalizeValue(cell)
+		if err := checkOperand(operator, value); err != nil {
+			return nil, err
+		}
+		return Match(NewCondition(column.Field, operator, value)), nil
+	}
+	text = strings.TrimSpace(text)
+	if text == "" || text == "-" {
+		return nil, nil
+	}
+	p := &exprParser{input: []rune(text)}
+	if expr, err := p.parseTest(column.Field); err == nil && p.atEnd() {
+		return expr, nil
+	}
+	p = &exprParser{input: []rune(text)}
+	value, err := p.parseValue()
+	if err != nil || !p.atEnd() {
+		value = text
+	}
+	value = normalizeValue(value)
+	if err := checkOperand(operator, value); err != nil {
+		return nil, err
+	}
+	return Match(NewCondition(column.Field, operator, value)), nil
+}
+
+// atEnd tells whether only spaces are left
+func (p *exprParser) atEnd() bool {
+	p.skipSpaces()
+	return p.pos == len(p.input)
+}
+
+// ParseTableCSV reads a decision table from csv, the first line names the columns:
+// an input is a field optionally followed by the operator of its bare values, such as "weight lte",
+// an output is prefixed with out:, and @id and @priority hold the id and the priority of the rows
+// Output cells are read as json values when they are valid json and as strings otherwise, empty ones are left out
+func ParseTableCSV(r io.Reader, name string, policy HitPolicy) (*DecisionTable, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTable, err)
+	}
+	table := NewDecisionTable(name, policy)
+	kinds := make([]byte, len(header)) // i input, o output, # id, p priority
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		switch {
+		case strings.HasPrefix(column, "out:"):
+			kinds[i] = 'o'
+			header[i] = strings.TrimSpace(strings.TrimPrefix(column, "out:"))
+			table.Outputs = append(table.Outputs, header[i])
+		case column == "@id":
+			kinds[i] = '#'
+		case column == "@priority":
+			kinds[i] = 'p'
+		default:
+			kinds[i] = 'i'
+			input, err := parseColumn(column)
+			if err != nil {
+				return nil, err
+			}
+			table.Inputs = append(table.Inputs, input)
+		}
+	}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTable, err)
+		}
+		row := Row{Then: map[string]any{}}
+		for i, cell := range record {
+			cell = strings.TrimSpace(cell)
+			switch kinds[i] {
+			case 'i':
+				row.When = append(row.When, cell)
+			case 'o':
+				if cell != "" {
+					row.Then[header[i]] = outputValue(cell)
+				}
+			case '#':
+				row.ID = cell
+			case 'p':
+				if cell == "" {
+					continue
+				}
+				if row.Priority, err = strconv.Atoi(cell); err != nil {
+					return nil, fmt.Errorf("%w: line %d: priority %q is not an integer", ErrInvalidTable, line, cell)
+				}
+			}
+		}
+		if err := table.AddRow(row); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+// parseColumn reads an input header such as age, age gte or age >=
+func parseColumn(header string) (Column, error) {
+	parts := strings.Fields(header)
+	switch len(parts) {
+	case 1:
+		return Column{Field: parts[0]}, nil
+	case 2:
+		operator := ConditionOperator(parts[1])
+		if symbol, ok := operatorSymbols[parts[1]]; ok {
+			operator = symbol
+		}
+		if _, ok := lookupOperator(operator); !ok {
+			return Column{}, fmt.Errorf("%w: column %q has an unknown operator", ErrInvalidTable, header)
+		}
+		return Column{Field: parts[0], Operator: operator}, nil
+	}
+	return Column{}, fmt.Errorf("%w: column %q is not a field and an operator", ErrInvalidTable, header)
+}
+
+func outputValue(cell string) any {
+	var value any
+	decoder := json.NewDecoder(strings.NewReader(cell))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return cell
+	}
+	return normalizeValue(value)
+}
+
+type tableJSON struct {
+	Name      string    `json:"name,omitempty"`
+	HitPolicy HitPolicy `json:"hit_policy"`
+	Inputs    []Column  `json:"inputs"`
+	Outputs   []string  `json:"outputs,omitempty"`
+	Rows      []Row     `json:"rows"`
+}
+
+func (t *DecisionTable) MarshalJSON() ([]byte, error) {
+	policy := t.HitPolicy
+	if policy == "" {
+		policy = HitFirst
+	}
+	return json.Marshal(tableJSON{Name: t.Name, HitPolicy: policy, Inputs: t.Inputs, Outputs: t.Outputs, Rows: t.Rows()})
+}
+
+// UnmarshalJSON reads a table written by MarshalJSON, cells are strings in the syntax of Row or json values
+func (t *DecisionTable) UnmarshalJSON(data []byte) error {
+	var table tableJSON
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&table); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTable, err)
+	}
+	switch table.HitPolicy {
+	case "":
+		table.HitPolicy = HitFirst
+	case HitFirst, HitUnique, HitCollect, HitPriority:
+	default:
+		return fmt.Errorf("%w: unknown hit policy %q", ErrInvalidTable, table.HitPolicy)
+	}
+	parsed := NewDecisionTable(table.Name, table.HitPolicy, table.Inputs...)
+	parsed.Outputs = table.Outputs
+	for _, row := range table.Rows {
+		for k, v := range row.Then {
+			row.Then[k] = normalizeValue(v)
+		}
+		if err := parsed.AddRow(row); err != nil {
+			return err
+		}
+	}
+	*t = *parsed
+	return nil
+}
+
+// IssueKind is the kind of problem Check finds in a decision table
+type IssueKind string
+
+const (
+	// Overlap is two rows matching some data together, an error under HitUnique
+	Overlap IssueKind = "overlap"
+	// Unreachable is a row matching only data an earlier or higher priority row already matches
+	Unreachable IssueKind = "unreachable"
+)
+
+// Issue reports that Row overlaps With or is shadowed by With, both indexes of the table
+type Issue struct {
+	Kind IssueKind `json:"kind"`
+	Row  int       `json:"row"`
+	With int       `json:"with"`
+}
+
+func (i Issue) String() string {
+	if i.Kind == Unreachable {
+		return fmt.Sprintf("row %d is unreachable behind row %d", i.Row+1, i.With+1)
+	}
+	return fmt.Sprintf("row %d overlaps row %d", i.Row+1, i.With+1)
+}
+
+// Check returns the overlapping and, under HitFirst and HitPriority, the unreachable rows of the table
+// Comparisons, ranges and lists of values are compared exactly, other operators are assumed to overlap anything
+// and to be covered only by the same test
+func (t *DecisionTable) Check() []Issue {
+	var issues []Issue
+	for j, row := range t.rows {
+		for i := 0; i < j; i++ {
+			earlier := t.rows[i]
+			if !row.intersects(earlier) {
+				continue
+			}
+			issues = append(issues, Issue{Kind: Overlap, Row: j, With: i})
+			shadows := t.HitPolicy == HitFirst || t.HitPolicy == ""
+			if t.HitPolicy == HitPriority {
+				shadows = earlier.Priority >= row.Priority
+			}
+			if shadows && row.within(earlier) {
+				issues = append(issues, Issue{Kind: Unreachable, Row: j, With: i})
+			}
+		}
+		if t.HitPolicy == HitPriority {
+			for i := j + 1; i < len(t.rows); i++ {
+				if later := t.rows[i]; later.Priority > row.Priority && row.within(later) {
+					issues = append(issues, Issue{Kind: Unreachable, Row: j, With: i})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+func (r *tableRow) intersects(other *tableRow) bool {
+	for i := range r.sets {
+		if !r.sets[i].intersects(other.sets[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *tableRow) within(other *tableRow) bool {
+	for i := range r.sets {
+		if !r.sets[i].within(other.sets[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+type setKind int
+
+const (
+	anySet setKind = iota
+	valueSet
+	exceptSet
+	rangeSet
+	opaqueSet
+)
+
+type bound struct {
+	value any
+	open  bool
+	set   bool
+}
+
+// cellSet is the set of values a cell matches: anything, a list of values, anything but a list of values,
+// a range, or an opaque test known by its text
+type cellSet struct {
+	kind      setKind
+	values    []any
+	low, high bound
+	key       string
+}
+
+func newCellSet(expr *Expr) cellSet {
+	if expr == nil {
+		return cellSet{kind: anySet}
+	}
+	c := expr.Condition
+	switch c.Operator {
+	case EQ:
+		return cellSet{kind: valueSet, values: []any{c.Value}}
+	case NEQ:
+		return cellSet{kind: exceptSet, values: []any{c.Value}}
+	case IN, NotIn:
+		values, _ := asList(c.Value)
+		if c.Operator == IN {
+			return cellSet{kind: valueSet, values: values}
+		}
+		return cellSet{kind: exceptSet, values: values}
+	case GT, GTE:
+		return cellSet{kind: rangeSet, low: bound{value: c.Value, open: c.Operator == GT, set: true}}
+	case LT, LTE:
+		return cellSet{kind: rangeSet, high: bound{value: c.Value, open: c.Operator == LT, set: true}}
+	case BETWEEN:
+		bounds, _ := asList(c.Value)
+		return cellSet{kind: rangeSet, low: bound{value: bounds[0], set: true}, high: bound{value: bounds[1], set: true}}
+	}
+	key, _ := json.Marshal(c)
+	return cellSet{kind: opaqueSet, key: string(key)}
+}
+
+// holds tells whether the range holds value
+func (s cellSet) holds(value any) bool {
+	if s.low.set {
+		c, ok := compareValues(value, s.low.value)
+		if !ok || c < 0 || c == 0 && s.low.open {
+			return false
+		}
+	}
+	if s.high.set {
+		c, ok := compareValues(value, s.high.value)
+		if !ok || c > 0 || c == 0 && s.high.open {
+			return false
+		}
+	}
+	return true
+}
+
+// matches tells whether value is in the set, opaque sets are assumed to hold it
+func (s cellSet) matches(value any) bool {
+	switch s.kind {
+	case valueSet:
+		return containsValue(s.values, value)
+	case exceptSet:
+		return !containsValue(s.values, value)
+	case rangeSet:
+		return s.holds(value)
+	}
+	return true
+}
+
+func (s cellSet) intersects(other cellSet) bool {
+	if s.kind == valueSet {
+		for _, value := range s.values {
+			if other.matches(value) {
+				return true
+			}
+		}
+		return false
+	}
+	if other.kind == valueSet {
+		return other.intersects(s)
+	}
+	if s.kind == rangeSet && other.kind == rangeSet {
+		low, high := s.low, s.high
+		if tighter(other.low, low, -1) {
+			low = other.low
+		}
+		if tighter(other.high, high, 1) {
+			high = other.high
+		}
+		if !low.set || !high.set {
+			return true
+		}
+		c, ok := compareValues(low.value, high.value)
+		return !ok || c < 0 || c == 0 && !low.open && !high.open
+	}
+	return true
+}
+
+// tighter tells whether a narrows the range more than b, sign is -1 for low bounds and 1 for high ones
+func tighter(a, b bound, sign int) bool {
+	if !a.set {
+		return false
+	}
+	if !b.set {
+		return true
+	}
+	c, ok := compareValues(a.value, b.value)
+	if !ok {
+		return false
+	}
+	return c*sign < 0 || c == 0 && a.open && !b.open
+}
+
+// within tells whether every value of s is in other, opaque tests are only within themselves and anything
+func (s cellSet) within(other cellSet) bool {
+	switch {
+	case other.kind == anySet:
+		return true
+	case s.kind == anySet || other.kind == opaqueSet:
+		return s.kind == opaqueSet && s.key == other.key
+	case s.kind == opaqueSet:
+		return false
+	case s.kind == valueSet:
+		for _, value := range s.values {
+			if !other.matches(value) {
+				return false
+			}
+		}
+		return true
+	case other.kind == valueSet:
+		return false
+	case s.kind == exceptSet:
+		if other.kind != exceptSet {
+			return false
+		}
+		for _, value := range other.values {
+			if !containsValue(s.values, value) {
+				return false
+			}
+		}
+		return true
+	case other.kind == exceptSet:
+		for _, value := range other.values {
+			if s.holds(value) {
+				return false
+			}
+		}
+		return true
+	}
+	return !tighter(other.low, s.low, -1) && !tighter(other.high, s.high, 1)
+}
@@ -0,0 +1,447 @@
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testCellSet(t *testing.T, cell any) cellSet {
+	t.Helper()
+	expr, err := cellExpr(Column{Field: "x"}, cell)
+	if err != nil {
+		t.Fatalf("cellExpr(%v): %v", cell, err)
+	}
+	return newCellSet(expr)
+}
+
+func TestCellSetIntersects(t *testing.T) {
+	tests := []struct {
+		a, b any
+		want bool
+	}{
+		{a: ">= 18", b: "< 18", want: false},
+		{a: "> 18", b: "<= 18", want: false},
+		{a: ">= 18", b: "<= 18", want: true},
+		{a: "> 18", b: "< 21", want: true},
+		{a: "between [1, 5]", b: "> 5", want: false},
+		{a: "between [1, 5]", b: ">= 5", want: true},
+		{a: "between [1, 5]", b: "between [5.5, 9]", want: false},
+		{a: "> 1", b: "> 100", want: true},
+		{a: "< 1.5", b: 1, want: true},
+		{a: "< 1", b: 1, want: false},
+		{a: `in ["US", "CA"]`, b: "US", want: true},
+		{a: `in ["US", "CA"]`, b: "MX", want: false},
+		{a: `in ["US", "CA"]`, b: `not_in ["US", "CA"]`, want: false},
+		{a: `neq "US"`, b: "US", want: false},
+		{a: `neq "US"`, b: `neq "CA"`, want: true},
+		{a: `neq 5`, b: "> 1", want: true},
+		{a: `matches "^a"`, b: "b", want: true},
+		{a: "-", b: "US", want: true},
+	}
+	for _, test := range tests {
+		a, b := testCellSet(t, test.a), testCellSet(t, test.b)
+		if got := a.intersects(b); got != test.want {
+			t.Errorf("%v intersects %v = %v, want %v", test.a, test.b, got, test.want)
+		}
+		if got := b.intersects(a); got != test.want {
+			t.Errorf("%v intersects %v = %v, want %v", test.b, test.a, got, test.want)
+		}
+	}
+}
+
+func TestCellSetWithin(t *testing.T) {
+	tests := []struct {
+		a, b any
+		want bool
+	}{
+		{a: "> 10", b: ">= 10", want: true},
+		{a: ">= 10", b: "> 10", want: false},
+		{a: ">= 10", b: ">= 10", want: true},
+		{a: "between [2, 3]", b: ">= 1", want: true},
+		{a: "between [2, 3]", b: "< 3", want: false},
+		{a: "between [2, 3]", b: "<= 3", want: true},
+		{a: 5, b: "between [1, 5]", want: true},
+		{a: "US", b: `in ["US", "CA"]`, want: true},
+		{a: `in ["US", "CA"]`, b: "US", want: false},
+		{a: `in ["US", "CA"]`, b: "-", want: true},
+		{a: "-", b: `neq 5`, want: false},
+		{a: "-", b: "-", want: true},
+		{a: `not_in ["US", "CA"]`, b: `neq "US"`, want: true},
+		{a: `neq "US"`, b: `not_in ["US", "CA"]`, want: false},
+		{a: `neq "US"`, b: `in ["CA"]`, want: false},
+		{a: "> 10", b: `neq 5`, want: true},
+		{a: "> 1", b: `neq 5`, want: false},
+		{a: `matches "^a"`, b: `matches "^a"`, want: true},
+		{a: `matches "^a"`, b: `matches "^b"`, want: false},
+		{a: "abc", b: `matches "^a"`, want: false},
+		{a: `matches "^a"`, b: "-", want: true},
+	}
+	for _, test := range tests {
+		if got := testCellSet(t, test.a).within(testCellSet(t, test.b)); got != test.want {
+			t.Errorf("%v within %v = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestTighter(t *testing.T) {
+	closed := bound{value: 5, set: true}
+	open := bound{value: 5, open: true, set: true}
+	tests := []struct {
+		a, b bound
+		sign int
+		want bool
+	}{
+		{a: open, b: closed, sign: -1, want: true},
+		{a: closed, b: open, sign: -1, want: false},
+		{a: closed, b: closed, sign: -1, want: false},
+		{a: open, b: closed, sign: 1, want: true},
+		{a: bound{value: 6, set: true}, b: open, sign: -1, want: true},
+		{a: bound{value: 6, set: true}, b: open, sign: 1, want: false},
+		{a: bound{}, b: closed, sign: -1, want: false},
+		{a: closed, b: bound{}, sign: 1, want: true},
+		{a: bound{value: "x", set: true}, b: closed, sign: -1, want: false},
+	}
+	for _, test := range tests {
+		if got := tighter(test.a, test.b, test.sign); got != test.want {
+			t.Errorf("tighter(%+v, %+v, %d) = %v, want %v", test.a, test.b, test.sign, got, test.want)
+		}
+	}
+}
+
+func newTestTable(t *testing.T, policy HitPolicy, rows ...Row) *DecisionTable {
+	t.Helper()
+	table := NewDecisionTable("shipping", policy, Column{Field: "country"}, Column{Field: "weight", Operator: LTE})
+	for _, row := range rows {
+		if err := table.AddRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return table
+}
+
+func TestDecisionTableCheck(t *testing.T) {
+	rows := []Row{
+		{When: []any{"US", ">= 18"}},
+		{When: []any{"US", ">= 21"}},
+		{When: []any{"-", "< 18"}},
+		{When: []any{"CA", nil}},
+	}
+	first := []Issue{{Kind: Overlap, Row: 1, With: 0}, {Kind: Unreachable, Row: 1, With: 0}, {Kind: Overlap, Row: 3, With: 2}}
+	if got := newTestTable(t, HitFirst, rows...).Check(); !reflect.DeepEqual(got, first) {
+		t.Errorf("Check under first = %v, want %v", got, first)
+	}
+	overlaps := []Issue{{Kind: Overlap, Row: 1, With: 0}, {Kind: Overlap, Row: 3, With: 2}}
+	for _, policy := range []HitPolicy{HitUnique, HitCollect} {
+		if got := newTestTable(t, policy, rows...).Check(); !reflect.DeepEqual(got, overlaps) {
+			t.Errorf("Check under %s = %v, want %v", policy, got, overlaps)
+		}
+	}
+	if got := first[1].String(); got != "row 2 is unreachable behind row 1" {
+		t.Errorf("Issue.String = %q", got)
+	}
+	if got := first[0].String(); got != "row 2 overlaps row 1" {
+		t.Errorf("Issue.String = %q", got)
+	}
+}
+
+func TestDecisionTableCheckPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []Row
+		want []Issue
+	}{
+		{
+			name: "a later row of higher priority is not shadowed",
+			rows: []Row{
+				{When: []any{"US", ">= 18"}, Priority: 1},
+				{When: []any{"US", ">= 21"}, Priority: 2},
+			},
+			want: []Issue{{Kind: Overlap, Row: 1, With: 0}},
+		},
+		{
+			name: "a later row of equal or lower priority is shadowed",
+			rows: []Row{
+				{When: []any{"US", ">= 18"}, Priority: 2},
+				{When: []any{"US", ">= 21"}, Priority: 2},
+				{When: []any{"US", ">= 30"}, Priority: 1},
+			},
+			want: []Issue{
+				{Kind: Overlap, Row: 1, With: 0}, {Kind: Unreachable, Row: 1, With: 0},
+				{Kind: Overlap, Row: 2, With: 0}, {Kind: Unreachable, Row: 2, With: 0},
+				{Kind: Overlap, Row: 2, With: 1}, {Kind: Unreachable, Row: 2, With: 1},
+			},
+		},
+		{
+			name: "an earlier row is shadowed by a later one of higher priority",
+			rows: []Row{
+				{When: []any{"US", "between [20, 30]"}},
+				{When: []any{"-", ">= 18"}, Priority: 5},
+			},
+			want: []Issue{{Kind: Unreachable, Row: 0, With: 1}, {Kind: Overlap, Row: 1, With: 0}},
+		},
+	}
+	for _, test := range tests {
+		if got := newTestTable(t, HitPriority, test.rows...).Check(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Check = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func hitIDs(hits []Hit) []string {
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+func TestDecisionTableHitPolicies(t *testing.T) {
+	rows := []Row{
+		{When: []any{"US", 5}, Then: map[string]any{"rate": 4.5}},
+		{When: []any{"US", "-"}, Then: map[string]any{"rate": 9}},
+		{ID: "north", When: []any{`in ["CA", "MX"]`, nil}, Then: map[string]any{"rate": 12}},
+	}
+	tests := []struct {
+		policy HitPolicy
+		data   map[string]any
+		want   []string
+	}{
+		{policy: HitFirst, data: map[string]any{"country": "US", "weight": 3}, want: []string{"shipping#1"}},
+		{policy: HitFirst, data: map[string]any{"country": "US", "weight": 10}, want: []string{"shipping#2"}},
+		{policy: HitFirst, data: map[string]any{"country": "MX", "weight": 1}, want: []string{"north"}},
+		{policy: HitFirst, data: map[string]any{"country": "FR", "weight": 1}, want: []string{}},
+		{policy: "", data: map[string]any{"country": "US", "weight": 3}, want: []string{"shipping#1"}},
+		{policy: HitCollect, data: map[string]any{"country": "US", "weight": 3}, want: []string{"shipping#1", "shipping#2"}},
+		{policy: HitCollect, data: map[string]any{"country": "US", "weight": 10}, want: []string{"shipping#2"}},
+		{policy: HitUnique, data: map[string]any{"country": "US", "weight": 10}, want: []string{"shipping#2"}},
+		{policy: HitUnique, data: map[string]any{"country": "CA"}, want: []string{"north"}},
+	}
+	for _, test := range tests {
+		hits, err := newTestTable(t, test.policy, rows...).Evaluate(test.data)
+		if err != nil {
+			t.Errorf("%s: Evaluate(%v): %v", test.policy, test.data, err)
+			continue
+		}
+		if got := hitIDs(hits); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Evaluate(%v) fired %v, want %v", test.policy, test.data, got, test.want)
+		}
+	}
+
+	table := newTestTable(t, HitUnique, rows...)
+	hits, err := table.Evaluate(map[string]any{"country": "US", "weight": 3})
+	if !errors.Is(err, ErrNotUnique) || !strings.Contains(err.Error(), "shipping#1, shipping#2") {
+		t.Errorf("Evaluate with two matches under unique returned %v, want ErrNotUnique naming both rows", err)
+	}
+	if got := hitIDs(hits); !reflect.DeepEqual(got, []string{"shipping#1", "shipping#2"}) {
+		t.Errorf("Evaluate with two matches under unique returned %v, want both rows", got)
+	}
+
+	hits, _ = table.Evaluate(map[string]any{"country": "MX"})
+	if hits[0].Row != 2 || hits[0].Output["rate"] != 12 {
+		t.Fatalf("Evaluate returned %+v, want row 2 with rate 12", hits[0])
+	}
+	hits[0].Output["rate"] = 0
+	if hits, _ := table.Evaluate(map[string]any{"country": "MX"}); hits[0].Output["rate"] != 12 {
+		t.Error("changing the output of a hit changed the table")
+	}
+
+	table.HitPolicy = "random"
+	if _, err := table.Evaluate(map[string]any{"country": "US"}); !errors.Is(err, ErrInvalidTable) {
+		t.Errorf("Evaluate with an unknown hit policy returned %v, want ErrInvalidTable", err)
+	}
+}
+
+func TestDecisionTablePriority(t *testing.T) {
+	table := newTestTable(t, HitPriority,
+		Row{ID: "default", When: []any{nil, nil}, Priority: 1},
+		Row{ID: "domestic", When: []any{"US", nil}, Priority: 3},
+		Row{ID: "americas", When: []any{`in ["US", "CA"]`, nil}, Priority: 3},
+		Row{ID: "light", When: []any{nil, 1}, Priority: 2},
+	)
+	tests := []struct {
+		data map[string]any
+		want string
+	}{
+		{data: map[string]any{"country": "US", "weight": 1}, want: "domestic"},
+		{data: map[string]any{"country": "CA", "weight": 1}, want: "americas"},
+		{data: map[string]any{"country": "FR", "weight": 1}, want: "light"},
+		{data: map[string]any{"country": "FR", "weight": 9}, want: "default"},
+	}
+	for _, test := range tests {
+		hits, err := table.Evaluate(test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hits) != 1 || hits[0].ID != test.want {
+			t.Errorf("Evaluate(%v) fired %v, want %s", test.data, hitIDs(hits), test.want)
+		}
+	}
+}
+
+func TestDecisionTableAddRowErrors(t *testing.T) {
+	table := NewDecisionTable("shipping", HitFirst, Column{Field: "weight", Operator: BETWEEN}, Column{Field: "code", Operator: MATCHES})
+	table.Outputs = []string{"rate"}
+	for _, row := range []Row{
+		{When: []any{"-"}},
+		{When: []any{"-", "-"}, Then: map[string]any{"carrier": "ups"}},
+		{When: []any{5, "-"}},
+		{When: []any{"-", "("}},
+		{When: []any{"-", 5}},
+	} {
+		if err := table.AddRow(row); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("AddRow(%v) returned %v, want ErrInvalidTable", row, err)
+		}
+	}
+	if len(table.Rows()) != 0 {
+		t.Errorf("rejected rows were added: %v", table.Rows())
+	}
+
+	// a cell that is not a valid test is an operand of the column operator, here the pattern between [1]
+	if err := table.AddRow(Row{When: []any{"[1, 5]", "between [1]"}}); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := table.Evaluate(map[string]any{"weight": 3, "code": "between 1"}); len(hits) != 1 {
+		t.Errorf("Evaluate returned %v, want the row matching the code with the pattern between [1]", hits)
+	}
+}
+
+const shippingCSV = `country, weight lte, @id, @priority, out:rate, out:carrier, out:options
+US, 5, us-light, 2, 4.5, """ups""", "{""signature"": true}"
+US, -, us, 1, 9, fedex,
+"in [""CA"", ""MX""]", "> 20", north, , 12.5, , "[1, 2]"
+-, "<= 1", , , 3, post,
+`
+
+func TestParseTableCSV(t *testing.T) {
+	table, err := ParseTableCSV(strings.NewReader(shippingCSV), "shipping", HitPriority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantInputs := []Column{{Field: "country"}, {Field: "weight", Operator: LTE}}
+	if !reflect.DeepEqual(table.Inputs, wantInputs) || !reflect.DeepEqual(table.Outputs, []string{"rate", "carrier", "options"}) {
+		t.Fatalf("ParseTableCSV read inputs %v and outputs %v", table.Inputs, table.Outputs)
+	}
+	wantRows := []Row{
+		{ID: "us-light", When: []any{"US", "5"}, Then: map[string]any{"rate": 4.5, "carrier": "ups", "options": map[string]any{"signature": true}}, Priority: 2},
+		{ID: "us", When: []any{"US", "-"}, Then: map[string]any{"rate": 9, "carrier": "fedex"}, Priority: 1},
+		{ID: "north", When: []any{`in ["CA", "MX"]`, "> 20"}, Then: map[string]any{"rate": 12.5, "options": []any{1, 2}}},
+		{When: []any{"-", "<= 1"}, Then: map[string]any{"rate": 3, "carrier": "post"}},
+	}
+	if got := table.Rows(); !reflect.DeepEqual(got, wantRows) {
+		t.Fatalf("ParseTableCSV read rows\n%#v\nwant\n%#v", got, wantRows)
+	}
+
+	data, err := json.Marshal(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &DecisionTable{}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatalf("Unmarshal(%s): %v", data, err)
+	}
+	if loaded.Name != table.Name || loaded.HitPolicy != table.HitPolicy || !reflect.DeepEqual(loaded.Inputs, table.Inputs) || !reflect.DeepEqual(loaded.Outputs, table.Outputs) {
+		t.Errorf("json round trip read %s %s %v %v", loaded.Name, loaded.HitPolicy, loaded.Inputs, loaded.Outputs)
+	}
+	if got := loaded.Rows(); !reflect.DeepEqual(got, wantRows) {
+		t.Errorf("json round trip read rows\n%#v\nwant\n%#v", got, wantRows)
+	}
+	for _, input := range []map[string]any{
+		{"country": "US", "weight": 3},
+		{"country": "US", "weight": 0.5},
+		{"country": "US", "weight": 8},
+		{"country": "MX", "weight": 25},
+		{"country": "MX", "weight": 10},
+		{"country": "FR", "weight": 1},
+		{"country": "FR", "weight": 2},
+	} {
+		want, err := table.Evaluate(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := loaded.Evaluate(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Evaluate(%v) after the json round trip = %v, want %v", input, got, want)
+		}
+	}
+	if hits, _ := table.Evaluate(map[string]any{"country": "US", "weight": 0.5}); len(hits) != 1 || hits[0].ID != "us-light" {
+		t.Errorf("Evaluate picked %v, want the row of highest priority us-light", hitIDs(hits))
+	}
+
+	for _, input := range []string{
+		``,
+		"country, weight foo, out:rate\nUS, 5, 4.5\n",
+		"country, weight lte extra, out:rate\nUS, 5, 4.5\n",
+		"country, @priority, out:rate\nUS, high, 4.5\n",
+		"country, out:rate\nUS, 4.5, 1\n",
+		"country between, out:rate\nUS, 4.5\n",
+	} {
+		if _, err := ParseTableCSV(strings.NewReader(input), "bad", HitFirst); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("ParseTableCSV(%q) returned %v, want ErrInvalidTable", input, err)
+		}
+	}
+}
+
+func TestDecisionTableJSON(t *testing.T) {
+	input := `{
+		"name": "discount",
+		"hit_policy": "collect",
+		"inputs": [{"field": "tier"}, {"field": "total", "operator": "gte"}],
+		"outputs": ["percent"],
+		"rows": [
+			{"id": "gold", "when": ["gold", 100], "then": {"percent": 10}},
+			{"when": [null, "between [500, 1000]"], "then": {"percent": 2.5}},
+			{"when": ["in [\"gold\", \"silver\"]", "-"], "then": {"percent": 1}}
+		]
+	}`
+	table := &DecisionTable{}
+	if err := json.Unmarshal([]byte(input), table); err != nil {
+		t.Fatal(err)
+	}
+	hits, err := table.Evaluate(map[string]any{"tier": "gold", "total": 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Hit{
+		{Row: 0, ID: "gold", Output: map[string]any{"percent": 10}},
+		{Row: 1, ID: "discount#2", Output: map[string]any{"percent": 2.5}},
+		{Row: 2, ID: "discount#3", Output: map[string]any{"percent": 1}},
+	}
+	if !reflect.DeepEqual(hits, want) {
+		t.Errorf("Evaluate = %+v, want %+v", hits, want)
+	}
+	rules := table.Rules()
+	if len(rules) != 3 || rules[0].ID != "gold" || rules[1].ID != "discount#2" {
+		t.Fatalf("Rules returned %d rules", len(rules))
+	}
+	if got := rules[0].Apply(map[string]any{"tier": "gold", "total": 150}); !reflect.DeepEqual(got, map[string]any{"percent": 10}) {
+		t.Errorf("the rule of row gold returned %v", got)
+	}
+	if got := rules[0].Apply(map[string]any{"tier": "gold", "total": 50}); got != nil {
+		t.Errorf("the rule of row gold fired below its total: %v", got)
+	}
+
+	if _, err := json.Marshal(&DecisionTable{}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(NewDecisionTable("empty", ""))
+	if !strings.Contains(string(data), `"hit_policy":"first"`) {
+		t.Errorf("a table without a hit policy was written as %s", data)
+	}
+	for _, input := range []string{
+		`{"hit_policy": "random", "inputs": [], "rows": []}`,
+		`{"hit_policy": "first", "inputs": [{"field": "tier"}], "rows": [], "extra": 1}`,
+		`{"inputs": [{"field": "tier"}], "rows": [{"when": ["gold", "silver"]}]}`,
+		`{"inputs": [{"field": "tier"}], "outputs": ["percent"], "rows": [{"when": ["gold"], "then": {"rate": 1}}]}`,
+		`[]`,
+	} {
+		if err := json.Unmarshal([]byte(input), &DecisionTable{}); !errors.Is(err, ErrInvalidTable) {
+			t.Errorf("Unmarshal(%s) returned %v, want ErrInvalidTable", input, err)
+		}
+	}
+}